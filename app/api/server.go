@@ -2,9 +2,11 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,12 +22,15 @@ type Server struct {
 	svc          *service.ReservationService
 	readThrough  *service.ReadThroughCache
 	postgres     *db.PostgresDB
+	replLag      *cluster.ReplLagMonitor
+	holdReaper   *service.HoldReaper
 	addr         string
 }
 
-// NewServer creates a new API server with optional PostgreSQL integration
-func NewServer(addr string, reservationTTL time.Duration, pgDSN string) (*Server, error) {
-	client, err := cluster.NewClient(nil)
+// NewServer creates a new API server with optional PostgreSQL integration.
+// Reads are routed per operation class according to policy.
+func NewServer(addr string, reservationTTL time.Duration, pgDSN string, clusterCfg *cluster.ClusterConfig, policy service.RoutingPolicy) (*Server, error) {
+	client, err := cluster.NewClient(clusterCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Redis cluster: %w", err)
 	}
@@ -47,6 +52,7 @@ func NewServer(addr string, reservationTTL time.Duration, pgDSN string) (*Server
 		svc = service.NewReservationService(client.Redis(), reservationTTL)
 		log.Println("[Server] Running in Redis-only mode")
 	}
+	svc.SetReadRouter(service.NewReadRouter(client.Redis(), client.ReplicaClient(), client.LatencyClient(), policy))
 
 	var rtCache *service.ReadThroughCache
 	if pg != nil {
@@ -71,6 +77,12 @@ func (s *Server) Start() error {
 
 	// Cluster info
 	mux.HandleFunc("/cluster/info", s.handleClusterInfo)
+	mux.HandleFunc("/cluster/repl-lag", s.handleReplLag)
+
+	// Client-side cluster metrics
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/metrics/redirects", s.handleRedirects)
+	mux.HandleFunc("/metrics/hotkeys", s.handleHotKeys)
 
 	// Event endpoints
 	mux.HandleFunc("/events", s.handleEvents)
@@ -97,6 +109,12 @@ func (s *Server) Start() error {
 
 // Close closes the server connections
 func (s *Server) Close() error {
+	if s.replLag != nil {
+		s.replLag.Stop()
+	}
+	if s.holdReaper != nil {
+		s.holdReaper.Stop()
+	}
 	if s.postgres != nil {
 		s.postgres.Close()
 	}
@@ -136,7 +154,66 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jsonResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+	if s.replLag == nil {
+		jsonResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+
+	// Lagging replicas still serve traffic, so degraded is reported with 200
+	resp := map[string]interface{}{"status": "ok"}
+	report, err := s.replLag.Report()
+	if err != nil {
+		resp["status"] = "degraded"
+		resp["repl_lag_error"] = err.Error()
+	} else if report != nil {
+		if lagging := report.Lagging(); len(lagging) > 0 {
+			resp["status"] = "degraded"
+			resp["lagging_replicas"] = lagging
+		}
+	}
+	jsonResponse(w, http.StatusOK, resp)
+}
+
+// Replication lag handler: the monitor's latest report
+func (s *Server) handleReplLag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if s.replLag == nil {
+		errorResponse(w, http.StatusNotFound, "replication lag monitor disabled")
+		return
+	}
+
+	report, err := s.replLag.Report()
+	if err != nil {
+		errorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	jsonResponse(w, http.StatusOK, report)
+}
+
+// EnableReplLagMonitor checks replica lag in the background; /health reports
+// degraded while a replica breaches a threshold
+func (s *Server) EnableReplLagMonitor(interval time.Duration, thresholds cluster.ReplLagThresholds, sinks ...cluster.AlertSink) {
+	s.replLag = cluster.NewReplLagMonitor(s.client, interval, thresholds, sinks...)
+	s.replLag.Start()
+}
+
+// EnableHoldReaper releases the seats of expired pending reservations every
+// interval. With notifications it also listens for expiring reservation
+// records on every master and releases their seats right away.
+func (s *Server) EnableHoldReaper(interval time.Duration, notifications bool) error {
+	s.holdReaper = service.NewHoldReaper(s.svc, interval)
+	if notifications {
+		if err := s.holdReaper.EnableNotifications(); err != nil {
+			s.holdReaper.Stop()
+			s.holdReaper = nil
+			return err
+		}
+	}
+	s.holdReaper.Start()
+	return nil
 }
 
 // Cluster info handler
@@ -155,6 +232,69 @@ func (s *Server) handleClusterInfo(w http.ResponseWriter, r *http.Request) {
 	jsonResponse(w, http.StatusOK, info)
 }
 
+// Metrics handler: counters collected by the cluster client
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errorResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	metrics := map[string]interface{}{
+		"redirects": s.client.Redirects().Snapshot(),
+	}
+	if s.holdReaper != nil {
+		metrics["holds"] = s.holdReaper.Stats()
+	}
+	jsonResponse(w, http.StatusOK, metrics)
+}
+
+// Redirect metrics handler: GET returns the counters, DELETE resets them
+func (s *Server) handleRedirects(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		jsonResponse(w, http.StatusOK, s.client.Redirects().Snapshot())
+	case http.MethodDelete:
+		s.client.Redirects().Reset()
+		jsonResponse(w, http.StatusOK, map[string]string{"status": "reset"})
+	default:
+		errorResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// Hot key handler: GET returns the sampled keys (?top=N, default 20),
+// DELETE resets the counters
+func (s *Server) handleHotKeys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		top := 20
+		if v := r.URL.Query().Get("top"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				errorResponse(w, http.StatusBadRequest, "invalid top")
+				return
+			}
+			top = n
+		}
+		jsonResponse(w, http.StatusOK, s.client.KeySampler().Snapshot(top))
+	case http.MethodDelete:
+		s.client.KeySampler().Reset()
+		jsonResponse(w, http.StatusOK, map[string]string{"status": "reset"})
+	default:
+		errorResponse(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// SetHotKeySampleRate samples the given fraction of Redis commands for
+// /metrics/hotkeys (0 disables sampling)
+func (s *Server) SetHotKeySampleRate(rate float64) {
+	s.client.KeySampler().SetRate(rate)
+}
+
+// SetRedirectLogging logs every MOVED/ASK redirect the cluster client follows
+func (s *Server) SetRedirectLogging(enabled bool) {
+	s.client.Redirects().SetLogging(enabled)
+}
+
 // Events handler (list/create)
 func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -343,6 +483,21 @@ func (s *Server) getSeats(w http.ResponseWriter, r *http.Request, eventID string
 		return
 	}
 
+	// Every seat with its status and owner
+	if r.URL.Query().Get("detail") == "true" {
+		seats, err := s.svc.GetSeats(eventID)
+		if err != nil {
+			errorResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		jsonResponse(w, http.StatusOK, map[string]interface{}{
+			"event_id": eventID,
+			"seats":    seats,
+			"count":    len(seats),
+		})
+		return
+	}
+
 	pattern := r.URL.Query().Get("pattern")
 
 	switch pattern {
@@ -391,6 +546,35 @@ type ReserveRequest struct {
 	Seats         []string `json:"seats"`
 	CustomerName  string   `json:"customer_name"`
 	CustomerEmail string   `json:"customer_email"`
+
+	// Without seats, hold the best Count seats matching Preferences. Seats
+	// are priced per event: max_price caps the event's price, there are no
+	// per-row price tiers to choose between.
+	Count       int                     `json:"count,omitempty"`
+	Preferences service.SeatPreferences `json:"preferences"`
+
+	// Retries with the same key get the first response (or use the
+	// Idempotency-Key header)
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+}
+
+// idempotencyKey returns the request's Idempotency-Key header, or the key
+// from its body
+func idempotencyKey(r *http.Request, bodyKey string) string {
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		return key
+	}
+	return bodyKey
+}
+
+// idempotencyError answers a request whose idempotency key was used for a
+// different request, and reports whether err was that
+func idempotencyError(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, service.ErrIdempotencyKeyReused) {
+		return false
+	}
+	errorResponse(w, http.StatusUnprocessableEntity, err.Error())
+	return true
 }
 
 func (s *Server) createReservation(w http.ResponseWriter, r *http.Request) {
@@ -400,8 +584,8 @@ func (s *Server) createReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.EventID == "" || req.UserID == "" || len(req.Seats) == 0 {
-		errorResponse(w, http.StatusBadRequest, "event_id, user_id, and seats are required")
+	if req.EventID == "" || req.UserID == "" || (len(req.Seats) == 0 && req.Count <= 0) {
+		errorResponse(w, http.StatusBadRequest, "event_id, user_id, and seats or count are required")
 		return
 	}
 
@@ -410,8 +594,26 @@ func (s *Server) createReservation(w http.ResponseWriter, r *http.Request) {
 		req.Seats[i] = strings.ToUpper(strings.TrimSpace(seat))
 	}
 
-	reservation, err := s.svc.ReserveSeats(req.EventID, req.UserID, req.Seats, req.CustomerName, req.CustomerEmail)
+	svc := s.svc.WithIdempotencyKey(idempotencyKey(r, req.IdempotencyKey))
+	var reservation *models.Reservation
+	var err error
+	if len(req.Seats) > 0 {
+		reservation, err = svc.ReserveSeats(req.EventID, req.UserID, req.Seats, req.CustomerName, req.CustomerEmail)
+	} else {
+		reservation, err = svc.ReserveBestAvailable(req.EventID, req.UserID, req.Count, req.Preferences, req.CustomerName, req.CustomerEmail)
+	}
 	if err != nil {
+		if idempotencyError(w, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidSeatRequest) {
+			errorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrNoSeatsMatch) {
+			errorResponse(w, http.StatusConflict, err.Error())
+			return
+		}
 		// Check if it's a seat unavailable error
 		if strings.Contains(err.Error(), "not available") {
 			errorResponse(w, http.StatusConflict, err.Error())
@@ -470,7 +672,8 @@ func (s *Server) getReservation(w http.ResponseWriter, r *http.Request, reservat
 
 // ConfirmRequest represents the request body for confirming a reservation
 type ConfirmRequest struct {
-	PaymentID string `json:"payment_id"`
+	PaymentID      string `json:"payment_id"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 func (s *Server) confirmReservation(w http.ResponseWriter, r *http.Request, reservationID string) {
@@ -482,12 +685,28 @@ func (s *Server) confirmReservation(w http.ResponseWriter, r *http.Request, rese
 	var req ConfirmRequest
 	json.NewDecoder(r.Body).Decode(&req) // Optional body
 
+	key := idempotencyKey(r, req.IdempotencyKey)
 	if req.PaymentID == "" {
 		req.PaymentID = fmt.Sprintf("pay_%d", time.Now().UnixNano())
+		if key != "" {
+			req.PaymentID = "pay_" + key // retries must describe the same payment
+		}
 	}
 
-	reservation, err := s.svc.ConfirmReservation(reservationID, req.PaymentID)
+	reservation, err := s.svc.WithIdempotencyKey(key).ConfirmReservation(reservationID, req.PaymentID)
 	if err != nil {
+		if idempotencyError(w, err) {
+			return
+		}
+		var conflict *service.SeatConflictError
+		if errors.As(err, &conflict) {
+			jsonResponse(w, http.StatusConflict, map[string]interface{}{
+				"error":          err.Error(),
+				"reservation_id": conflict.ReservationID,
+				"conflict":       conflict.SeatConflict,
+			})
+			return
+		}
 		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "expired") {
 			errorResponse(w, http.StatusNotFound, err.Error())
 			return
@@ -505,8 +724,11 @@ func (s *Server) cancelReservation(w http.ResponseWriter, r *http.Request, reser
 		return
 	}
 
-	err := s.svc.CancelReservation(reservationID)
+	err := s.svc.WithIdempotencyKey(idempotencyKey(r, "")).CancelReservation(reservationID)
 	if err != nil {
+		if idempotencyError(w, err) {
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			errorResponse(w, http.StatusNotFound, err.Error())
 			return
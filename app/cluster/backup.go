@@ -0,0 +1,789 @@
+package cluster
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"ticket-reservation/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Backup archive layout
+//
+// The archive is a sequence of gzip members (a valid multi-member gzip
+// stream). The first member holds the magic and a JSON header, every
+// following member one batch of keys closed by a checkpoint record, and the
+// last member the end record. Because each batch is its own member, an
+// interrupted backup is resumed by truncating the file to the end of the
+// last complete batch and appending.
+//
+//	header:     magic, uvarint len, JSON BackupHeader
+//	entry:      'K', uvarint len, key, varint pttl (ms, -1 = no expiry), uvarint len, DUMP payload
+//	checkpoint: 'C', uvarint entries, SHA-256 of the batch's entry records
+//	end:        'E', uvarint total keys, uvarint batches
+const (
+	backupMagic = "RCLBAK\x01"
+
+	recEntry      = 'K'
+	recCheckpoint = 'C'
+	recEnd        = 'E'
+)
+
+// BackupHeader describes an archive
+type BackupHeader struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Seeds     []string  `json:"seeds"`
+	Match     string    `json:"match,omitempty"`
+	Keys      int       `json:"explicit_keys,omitempty"`
+}
+
+// BackupOptions select the keys to back up
+type BackupOptions struct {
+	Match     string   // SCAN MATCH pattern ("" = every key)
+	Keys      []string // extra keys to include, fetched through the cluster client
+	BatchSize int      // SCAN COUNT and keys per checkpointed batch (default 500)
+}
+
+// BackupState is the persisted progress of a backup, used to resume it
+type BackupState struct {
+	Out      string                 `json:"out"`
+	Options  BackupOptions          `json:"options"`
+	Offset   int64                  `json:"offset"` // archive size after the last complete batch
+	Masters  map[string]*ScanCursor `json:"masters"`
+	KeysDone bool                   `json:"keys_done"` // Options.Keys written
+	Entries  int                    `json:"entries"`
+	Batches  int                    `json:"batches"`
+	Complete bool                   `json:"complete"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScanCursor is the SCAN position on one master
+type ScanCursor struct {
+	Cursor uint64 `json:"cursor"`
+	Done   bool   `json:"done"`
+}
+
+// NewBackupState starts a backup of the keys selected by opts into out
+func NewBackupState(out string, opts BackupOptions) *BackupState {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	return &BackupState{Out: out, Options: opts, Masters: make(map[string]*ScanCursor)}
+}
+
+// LoadBackupState reads progress saved by Save
+func LoadBackupState(path string) (*BackupState, error) {
+	var st BackupState
+	if err := loadJSONFile(path, &st); err != nil {
+		return nil, fmt.Errorf("failed to read backup progress: %w", err)
+	}
+	if st.Masters == nil {
+		st.Masters = make(map[string]*ScanCursor)
+	}
+	return &st, nil
+}
+
+// Save writes the progress atomically (temp file + rename)
+func (st *BackupState) Save(path string) error {
+	st.UpdatedAt = time.Now()
+	return saveJSONFile(path, st)
+}
+
+// BackupProgress is reported after every batch
+type BackupProgress struct {
+	Node    string // master scanned, "" for explicit keys
+	Keys    int    // keys in this batch
+	Entries int    // keys written so far
+	Batches int
+	Bytes   int64 // archive size so far
+}
+
+// Backup streams every key selected by st.Options into st.Out, saving st to
+// statePath after each batch. If st already has progress the archive is
+// truncated to the last complete batch and continued. Cancelling ctx stops
+// between batches.
+func (c *Client) Backup(ctx context.Context, st *BackupState, statePath string, progress func(BackupProgress)) error {
+	masters, err := c.backupMasters(st.Options.Match)
+	if err != nil {
+		return err
+	}
+
+	w, err := openArchive(st)
+	if err != nil {
+		return err
+	}
+	defer w.f.Close()
+
+	if st.Offset == 0 {
+		hdr := BackupHeader{
+			Version:   1,
+			CreatedAt: time.Now(),
+			Seeds:     c.cfg.Addrs,
+			Match:     st.Options.Match,
+			Keys:      len(st.Options.Keys),
+		}
+		if err := w.writeHeader(hdr); err != nil {
+			return err
+		}
+		if err := w.commit(st, statePath); err != nil {
+			return err
+		}
+	}
+
+	report := func(node string, n int) {
+		if progress != nil {
+			progress(BackupProgress{Node: node, Keys: n, Entries: st.Entries, Batches: st.Batches, Bytes: st.Offset})
+		}
+	}
+
+	for _, m := range masters {
+		cur := st.Masters[m.Address]
+		if cur == nil {
+			cur = &ScanCursor{}
+			st.Masters[m.Address] = cur
+		}
+		node := c.NodeClient(m.Address)
+		for !cur.Done {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			keys, next, err := node.Scan(ctx, cur.Cursor, st.Options.Match, int64(st.Options.BatchSize)).Result()
+			if err != nil {
+				return fmt.Errorf("scan %s: %w", m.Address, err)
+			}
+			entries, err := c.dumpKeys(ctx, node, keys)
+			if err != nil {
+				return fmt.Errorf("dump on %s: %w", m.Address, err)
+			}
+			if err := w.writeBatch(entries); err != nil {
+				return err
+			}
+			cur.Cursor, cur.Done = next, next == 0
+			st.Entries += len(entries)
+			if len(entries) > 0 {
+				st.Batches++
+			}
+			if err := w.commit(st, statePath); err != nil {
+				return err
+			}
+			report(m.Address, len(entries))
+		}
+	}
+
+	for !st.KeysDone {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		entries, err := c.dumpKeys(ctx, c.rdb, st.Options.Keys)
+		if err != nil {
+			return err
+		}
+		if err := w.writeBatch(entries); err != nil {
+			return err
+		}
+		st.KeysDone = true
+		st.Entries += len(entries)
+		if len(entries) > 0 {
+			st.Batches++
+		}
+		if err := w.commit(st, statePath); err != nil {
+			return err
+		}
+		report("", len(entries))
+	}
+
+	if err := w.writeEnd(st.Entries, st.Batches); err != nil {
+		return err
+	}
+	st.Complete = true
+	return w.commit(st, statePath)
+}
+
+// backupMasters returns the masters to scan, sorted by address. A pattern
+// whose hash tag is literal can only match keys of one slot, so only that
+// slot's owner is scanned.
+func (c *Client) backupMasters(match string) ([]models.ClusterNode, error) {
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return nil, err
+	}
+	slot, single := patternSlot(match)
+
+	var masters []models.ClusterNode
+	for _, n := range nodes {
+		if n.Role != "master" || n.SlotCount() == 0 {
+			continue
+		}
+		if single && !n.OwnsSlot(slot) {
+			continue
+		}
+		if n.IsFailing() {
+			return nil, fmt.Errorf("master %s is failing: its slots cannot be backed up", n.Address)
+		}
+		masters = append(masters, n)
+	}
+	if len(masters) == 0 {
+		return nil, fmt.Errorf("no master owns the slots to back up")
+	}
+	sort.Slice(masters, func(i, j int) bool { return masters[i].Address < masters[j].Address })
+	return masters, nil
+}
+
+// patternSlot returns the slot every key matching pattern hashes to, if the
+// pattern has a non-empty hash tag without glob characters before it ends
+func patternSlot(pattern string) (int, bool) {
+	start := strings.Index(pattern, "{")
+	if start == -1 {
+		return 0, false
+	}
+	end := strings.Index(pattern[start+1:], "}")
+	if end <= 0 || strings.ContainsAny(pattern[:start+1+end], `*?[\`) {
+		return 0, false
+	}
+	return KeySlot(pattern[:start+2+end]), true
+}
+
+// BackupEntry is one key in an archive
+type BackupEntry struct {
+	Key  string
+	PTTL int64 // milliseconds, -1 = no expiry
+	Dump []byte
+}
+
+// dumpKeys fetches DUMP and PTTL for keys in one pipeline. Keys that vanished
+// are skipped; keys that moved to another node meanwhile are fetched again
+// through the cluster client.
+func (c *Client) dumpKeys(ctx context.Context, rdb redis.Cmdable, keys []string) ([]BackupEntry, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	type pair struct {
+		dump *redis.StringCmd
+		pttl *redis.DurationCmd
+	}
+	cmds := make([]pair, len(keys))
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pair{pipe.Dump(ctx, key), pipe.PTTL(ctx, key)}
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil && !isRedirect(err) {
+		return nil, err
+	}
+
+	entries := make([]BackupEntry, 0, len(keys))
+	for i, key := range keys {
+		dump, err := cmds[i].dump.Result()
+		pttl := cmds[i].pttl.Val()
+		if _, direct := rdb.(*redis.Client); direct && isRedirect(err) {
+			dump, err = c.rdb.Dump(ctx, key).Result()
+			pttl = c.rdb.PTTL(ctx, key).Val()
+		}
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("DUMP %s: %w", key, err)
+		}
+		e := BackupEntry{Key: key, PTTL: -1, Dump: []byte(dump)}
+		if pttl > 0 {
+			e.PTTL = pttl.Milliseconds()
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// isRedirect reports whether err is a MOVED or ASK redirect
+func isRedirect(err error) bool {
+	return isMoved(err) || (err != nil && strings.HasPrefix(err.Error(), "ASK "))
+}
+
+// archiveWriter appends gzip members to a backup archive
+type archiveWriter struct {
+	f *os.File
+}
+
+// openArchive creates st.Out, or truncates it to st.Offset to resume
+func openArchive(st *BackupState) (*archiveWriter, error) {
+	f, err := os.OpenFile(st.Out, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	if err := f.Truncate(st.Offset); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to truncate archive for resume: %w", err)
+	}
+	if _, err := f.Seek(st.Offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &archiveWriter{f: f}, nil
+}
+
+// member compresses data into one gzip member at the end of the archive
+func (w *archiveWriter) member(data []byte) error {
+	zw := gzip.NewWriter(w.f)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (w *archiveWriter) writeHeader(hdr BackupHeader) error {
+	js, err := json.Marshal(hdr)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(backupMagic)
+	putUvarint(&buf, uint64(len(js)))
+	buf.Write(js)
+	return w.member(buf.Bytes())
+}
+
+func (w *archiveWriter) writeBatch(entries []BackupEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, e := range entries {
+		buf.WriteByte(recEntry)
+		putUvarint(&buf, uint64(len(e.Key)))
+		buf.WriteString(e.Key)
+		putVarint(&buf, e.PTTL)
+		putUvarint(&buf, uint64(len(e.Dump)))
+		buf.Write(e.Dump)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	buf.WriteByte(recCheckpoint)
+	putUvarint(&buf, uint64(len(entries)))
+	buf.Write(sum[:])
+	return w.member(buf.Bytes())
+}
+
+func (w *archiveWriter) writeEnd(entries, batches int) error {
+	var buf bytes.Buffer
+	buf.WriteByte(recEnd)
+	putUvarint(&buf, uint64(entries))
+	putUvarint(&buf, uint64(batches))
+	return w.member(buf.Bytes())
+}
+
+// commit syncs the archive and records its size as the resume point
+func (w *archiveWriter) commit(st *BackupState, statePath string) error {
+	if err := w.f.Sync(); err != nil {
+		return err
+	}
+	off, err := w.f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	st.Offset = off
+	if statePath == "" {
+		return nil
+	}
+	return st.Save(statePath)
+}
+
+func putUvarint(buf *bytes.Buffer, v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutUvarint(tmp[:], v)])
+}
+
+func putVarint(buf *bytes.Buffer, v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	buf.Write(tmp[:binary.PutVarint(tmp[:], v)])
+}
+
+// ArchiveReader reads a backup archive batch by batch, verifying checksums
+type ArchiveReader struct {
+	Header BackupHeader
+
+	f  *os.File
+	zr *gzip.Reader
+	r  *bufio.Reader
+
+	batches int
+	entries int
+	done    bool
+}
+
+// ErrArchiveTruncated means the archive has no end record, usually because
+// the backup was interrupted and not resumed
+var ErrArchiveTruncated = errors.New("archive is incomplete (backup interrupted? resume it with backup --resume)")
+
+// OpenArchive opens path and reads its header
+func OpenArchive(path string) (*ArchiveReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a backup archive: %w", path, err)
+	}
+	ar := &ArchiveReader{f: f, zr: zr, r: bufio.NewReader(zr)}
+
+	magic := make([]byte, len(backupMagic))
+	if _, err := io.ReadFull(ar.r, magic); err != nil || string(magic) != backupMagic {
+		ar.Close()
+		return nil, fmt.Errorf("%s is not a backup archive", path)
+	}
+	hdr, err := ar.readBytes()
+	if err != nil {
+		ar.Close()
+		return nil, fmt.Errorf("failed to read archive header: %w", err)
+	}
+	if err := json.Unmarshal(hdr, &ar.Header); err != nil {
+		ar.Close()
+		return nil, fmt.Errorf("failed to parse archive header: %w", err)
+	}
+	return ar, nil
+}
+
+// Close closes the archive file
+func (ar *ArchiveReader) Close() error {
+	ar.zr.Close()
+	return ar.f.Close()
+}
+
+// NextBatch returns the next batch of entries after verifying its checksum.
+// It returns io.EOF after the end record and ErrArchiveTruncated if the
+// archive stops without one.
+func (ar *ArchiveReader) NextBatch() ([]BackupEntry, error) {
+	if ar.done {
+		return nil, io.EOF
+	}
+
+	var entries []BackupEntry
+	sum := sha256.New()
+	for {
+		typ, err := ar.r.ReadByte()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrArchiveTruncated
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case recEntry:
+			e, raw, err := ar.readEntry()
+			if err != nil {
+				return nil, ar.corrupt(err)
+			}
+			sum.Write([]byte{recEntry})
+			sum.Write(raw)
+			entries = append(entries, e)
+
+		case recCheckpoint:
+			n, err := binary.ReadUvarint(ar.r)
+			if err != nil {
+				return nil, ar.corrupt(err)
+			}
+			want := make([]byte, sha256.Size)
+			if _, err := io.ReadFull(ar.r, want); err != nil {
+				return nil, ar.corrupt(err)
+			}
+			if int(n) != len(entries) || !bytes.Equal(sum.Sum(nil), want) {
+				return nil, fmt.Errorf("archive batch %d failed checksum verification", ar.batches+1)
+			}
+			ar.batches++
+			ar.entries += len(entries)
+			return entries, nil
+
+		case recEnd:
+			if len(entries) > 0 {
+				return nil, ar.corrupt(fmt.Errorf("end record inside a batch"))
+			}
+			keys, err1 := binary.ReadUvarint(ar.r)
+			batches, err2 := binary.ReadUvarint(ar.r)
+			if err1 != nil || err2 != nil {
+				return nil, ar.corrupt(fmt.Errorf("short end record"))
+			}
+			if int(keys) != ar.entries || int(batches) != ar.batches {
+				return nil, fmt.Errorf("archive end record expects %d keys in %d batches, read %d in %d",
+					keys, batches, ar.entries, ar.batches)
+			}
+			ar.done = true
+			return nil, io.EOF
+
+		default:
+			return nil, ar.corrupt(fmt.Errorf("unknown record type %q", typ))
+		}
+	}
+}
+
+func (ar *ArchiveReader) corrupt(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrArchiveTruncated
+	}
+	return fmt.Errorf("archive batch %d is corrupt: %w", ar.batches+1, err)
+}
+
+// readEntry reads one entry record and returns it with its raw bytes
+func (ar *ArchiveReader) readEntry() (BackupEntry, []byte, error) {
+	var raw bytes.Buffer
+	key, err := ar.readBytes()
+	if err != nil {
+		return BackupEntry{}, nil, err
+	}
+	pttl, err := binary.ReadVarint(ar.r)
+	if err != nil {
+		return BackupEntry{}, nil, err
+	}
+	dump, err := ar.readBytes()
+	if err != nil {
+		return BackupEntry{}, nil, err
+	}
+
+	putUvarint(&raw, uint64(len(key)))
+	raw.Write(key)
+	putVarint(&raw, pttl)
+	putUvarint(&raw, uint64(len(dump)))
+	raw.Write(dump)
+	return BackupEntry{Key: string(key), PTTL: pttl, Dump: dump}, raw.Bytes(), nil
+}
+
+func (ar *ArchiveReader) readBytes() ([]byte, error) {
+	n, err := binary.ReadUvarint(ar.r)
+	if err != nil {
+		return nil, err
+	}
+	if n > 512<<20 {
+		return nil, fmt.Errorf("record of %d bytes is too large", n)
+	}
+	b := make([]byte, n)
+	_, err = io.ReadFull(ar.r, b)
+	return b, err
+}
+
+// RestoreState is the persisted progress of a restore, used to resume it
+type RestoreState struct {
+	In       string `json:"in"`
+	Batches  int    `json:"batches"` // archive batches fully processed
+	Restored int    `json:"restored"`
+	Skipped  int    `json:"skipped"` // entries rejected by the filter
+	Complete bool   `json:"complete"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoadRestoreState reads progress saved by Save
+func LoadRestoreState(path string) (*RestoreState, error) {
+	var st RestoreState
+	if err := loadJSONFile(path, &st); err != nil {
+		return nil, fmt.Errorf("failed to read restore progress: %w", err)
+	}
+	return &st, nil
+}
+
+// Save writes the progress atomically (temp file + rename)
+func (st *RestoreState) Save(path string) error {
+	st.UpdatedAt = time.Now()
+	return saveJSONFile(path, st)
+}
+
+// RestoreProgress is reported after every batch
+type RestoreProgress struct {
+	Batch    int
+	Keys     int // keys restored from this batch
+	Restored int // keys restored so far
+	Skipped  int
+}
+
+// Restore replays st.In into the cluster with RESTORE ... REPLACE. The
+// cluster pipeline routes every key to the master owning its slot, so the
+// target topology does not need to match the source. Keys for which match
+// returns false are skipped (nil = restore all). Batches already processed
+// according to st are verified but not replayed; st is saved to statePath
+// after each batch. Cancelling ctx stops between batches.
+func (c *Client) Restore(ctx context.Context, st *RestoreState, statePath string, match func(key string) bool, progress func(RestoreProgress)) error {
+	ar, err := OpenArchive(st.In)
+	if err != nil {
+		return err
+	}
+	defer ar.Close()
+
+	for batch := 1; ; batch++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		entries, err := ar.NextBatch()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if batch <= st.Batches {
+			continue
+		}
+
+		var keep []BackupEntry
+		for _, e := range entries {
+			if match == nil || match(e.Key) {
+				keep = append(keep, e)
+			}
+		}
+		if err := c.restoreEntries(ctx, keep); err != nil {
+			return fmt.Errorf("batch %d: %w", batch, err)
+		}
+
+		st.Batches = batch
+		st.Restored += len(keep)
+		st.Skipped += len(entries) - len(keep)
+		if statePath != "" {
+			if err := st.Save(statePath); err != nil {
+				return err
+			}
+		}
+		if progress != nil {
+			progress(RestoreProgress{Batch: batch, Keys: len(keep), Restored: st.Restored, Skipped: st.Skipped})
+		}
+	}
+
+	st.Complete = true
+	if statePath != "" {
+		return st.Save(statePath)
+	}
+	return nil
+}
+
+// restoreEntries pipelines RESTORE REPLACE for entries, slot-routed
+func (c *Client) restoreEntries(ctx context.Context, entries []BackupEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	cmds, err := c.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, e := range entries {
+			var ttl time.Duration
+			if e.PTTL > 0 {
+				ttl = time.Duration(e.PTTL) * time.Millisecond
+			}
+			pipe.RestoreReplace(ctx, e.Key, ttl, string(e.Dump))
+		}
+		return nil
+	})
+	if err == nil {
+		return nil
+	}
+	for i, cmd := range cmds {
+		if cmd.Err() != nil {
+			return fmt.Errorf("RESTORE %s: %w", entries[i].Key, cmd.Err())
+		}
+	}
+	return err
+}
+
+// MatchPattern reports whether key matches a Redis glob pattern (*, ?,
+// [abc], [^a-z] and \ escapes), as used by SCAN MATCH and KEYS
+func MatchPattern(pattern, key string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 1 && pattern[1] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 1 {
+				return true
+			}
+			for i := 0; i <= len(key); i++ {
+				if MatchPattern(pattern[1:], key[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(key) == 0 {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		case '[':
+			if len(key) == 0 {
+				return false
+			}
+			end := strings.IndexByte(pattern[1:], ']')
+			if end < 0 {
+				return pattern == key
+			}
+			class := pattern[1 : 1+end]
+			negate := strings.HasPrefix(class, "^")
+			if negate {
+				class = class[1:]
+			}
+			matched := false
+			for i := 0; i < len(class); i++ {
+				if i+2 < len(class) && class[i+1] == '-' {
+					if class[i] <= key[0] && key[0] <= class[i+2] {
+						matched = true
+					}
+					i += 2
+				} else if class[i] == key[0] {
+					matched = true
+				}
+			}
+			if matched == negate {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[2+end:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(key) == 0 || pattern[0] != key[0] {
+				return false
+			}
+			key = key[1:]
+			pattern = pattern[1:]
+		}
+	}
+	return len(key) == 0
+}
+
+// loadJSONFile decodes the JSON file at path into v
+func loadJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// saveJSONFile writes v as indented JSON atomically (temp file + rename)
+func saveJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
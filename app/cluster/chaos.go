@@ -0,0 +1,492 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// DefaultRetransmitDelay is how late a dropped chunk arrives when the fault
+// does not set one, roughly TCP's minimum retransmission timeout
+const DefaultRetransmitDelay = 200 * time.Millisecond
+
+// FaultSpec describes the network faults injected on connections to a node.
+// Latency, drops and bandwidth shape the data the node sends back, so they
+// count against the client's read timeout like a slow network would.
+type FaultSpec struct {
+	// Added to every chunk of data received from the node
+	Latency Duration `json:"latency,omitempty"`
+	// Random extra latency, uniform in [0, Jitter)
+	Jitter Duration `json:"jitter,omitempty"`
+	// Fraction (0-1) of chunks "lost"; TCP delivers them after RetransmitDelay
+	DropRate        float64  `json:"drop_rate,omitempty"`
+	RetransmitDelay Duration `json:"retransmit_delay,omitempty"`
+	// Fraction (0-1) of reads and writes that reset the connection
+	ResetRate float64 `json:"reset_rate,omitempty"`
+	// Bytes per second in each direction (0 = unlimited)
+	Bandwidth int64 `json:"bandwidth,omitempty"`
+	// The node is unreachable: dials time out and data is silently discarded.
+	// Connections that lost data are reset once the partition heals.
+	Partition bool `json:"partition,omitempty"`
+}
+
+// IsZero reports whether the spec injects nothing
+func (f FaultSpec) IsZero() bool {
+	return f == FaultSpec{}
+}
+
+func (f FaultSpec) validate() error {
+	if f.DropRate < 0 || f.DropRate > 1 || f.ResetRate < 0 || f.ResetRate > 1 {
+		return fmt.Errorf("drop_rate and reset_rate must be between 0 and 1")
+	}
+	if f.Latency < 0 || f.Jitter < 0 || f.RetransmitDelay < 0 || f.Bandwidth < 0 {
+		return fmt.Errorf("latency, jitter, retransmit_delay and bandwidth cannot be negative")
+	}
+	return nil
+}
+
+// String summarizes the active faults, e.g. "latency=50ms drop=5%"
+func (f FaultSpec) String() string {
+	if f.IsZero() {
+		return "none"
+	}
+	var parts []string
+	if f.Partition {
+		parts = append(parts, "PARTITIONED")
+	}
+	if f.Latency > 0 || f.Jitter > 0 {
+		s := "latency=" + time.Duration(f.Latency).String()
+		if f.Jitter > 0 {
+			s += "±" + time.Duration(f.Jitter).String()
+		}
+		parts = append(parts, s)
+	}
+	if f.DropRate > 0 {
+		parts = append(parts, fmt.Sprintf("drop=%.1f%%", f.DropRate*100))
+	}
+	if f.ResetRate > 0 {
+		parts = append(parts, fmt.Sprintf("reset=%.1f%%", f.ResetRate*100))
+	}
+	if f.Bandwidth > 0 {
+		parts = append(parts, "bandwidth="+formatRate(f.Bandwidth))
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatRate(bps int64) string {
+	switch {
+	case bps >= 1<<20:
+		return fmt.Sprintf("%.1fMB/s", float64(bps)/(1<<20))
+	case bps >= 1<<10:
+		return fmt.Sprintf("%.1fKB/s", float64(bps)/(1<<10))
+	}
+	return fmt.Sprintf("%dB/s", bps)
+}
+
+// ChaosStep applies Fault to Nodes (all nodes if empty) from At until
+// At+For (until the end of the timeline if For is 0)
+type ChaosStep struct {
+	At    Duration  `json:"at"`
+	For   Duration  `json:"for,omitempty"`
+	Nodes []string  `json:"nodes,omitempty"`
+	Fault FaultSpec `json:"fault"`
+}
+
+// ChaosScenario is the content of a scenario file. Nodes are named by the
+// address the cluster announces ("172.30.0.11:6379"), the address dialed
+// after remapping ("127.0.0.1:7001") or just the port ("7001").
+//
+//	{
+//	  "default":  {"latency": "2ms"},
+//	  "nodes":    {"7001": {"latency": "40ms", "jitter": "20ms", "drop_rate": 0.02}},
+//	  "timeline": [{"at": "10s", "for": "5s", "nodes": ["7002"], "fault": {"partition": true}}],
+//	  "repeat":   "30s"
+//	}
+type ChaosScenario struct {
+	Name string `json:"name,omitempty"`
+	// Faults for nodes without an entry in Nodes
+	Default FaultSpec            `json:"default"`
+	Nodes   map[string]FaultSpec `json:"nodes,omitempty"`
+	// Timed faults; a later active step replaces earlier ones for its nodes
+	Timeline []ChaosStep `json:"timeline,omitempty"`
+	// Restart the timeline with this period (0 = run it once)
+	Repeat Duration `json:"repeat,omitempty"`
+}
+
+// LoadChaosScenario reads a scenario file
+func LoadChaosScenario(path string) (*ChaosScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chaos scenario: %w", err)
+	}
+	var s ChaosScenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse chaos scenario %s: %w", path, err)
+	}
+	if s.Name == "" {
+		s.Name = path
+	}
+	if err := s.Validate(); err != nil {
+		return nil, fmt.Errorf("chaos scenario %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+// Validate checks rates and durations
+func (s *ChaosScenario) Validate() error {
+	if err := s.Default.validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for node, f := range s.Nodes {
+		if err := f.validate(); err != nil {
+			return fmt.Errorf("node %s: %w", node, err)
+		}
+	}
+	for i, step := range s.Timeline {
+		if err := step.Fault.validate(); err != nil {
+			return fmt.Errorf("timeline[%d]: %w", i, err)
+		}
+		if step.At < 0 || step.For < 0 {
+			return fmt.Errorf("timeline[%d]: at and for cannot be negative", i)
+		}
+	}
+	return nil
+}
+
+// matchNode reports whether a scenario node name refers to one of addrs
+func matchNode(name string, addrs ...string) bool {
+	for _, addr := range addrs {
+		if name == addr {
+			return true
+		}
+		if _, port, err := net.SplitHostPort(addr); err == nil && name == port {
+			return true
+		}
+	}
+	return false
+}
+
+// ChaosNodeStats counts what was injected on one node's connections
+type ChaosNodeStats struct {
+	Addr       string `json:"addr"`
+	Conns      int64  `json:"conns"`
+	DialFails  int64  `json:"dial_failures"`
+	Resets     int64  `json:"resets"`
+	Drops      int64  `json:"drops"`
+	Blackholed int64  `json:"blackholed_bytes"`
+	BytesIn    int64  `json:"bytes_in"`
+	BytesOut   int64  `json:"bytes_out"`
+}
+
+type chaosCounters struct {
+	conns, dialFails, resets, drops, blackholed, bytesIn, bytesOut atomic.Int64
+}
+
+// Chaos injects a scenario's faults into connections. Use it in-process
+// through ClusterConfig.WithChaos, or in front of real nodes with ChaosProxy.
+type Chaos struct {
+	scenario *ChaosScenario
+	start    time.Time
+
+	rngMu sync.Mutex
+	rng   *rand.Rand
+
+	statsMu sync.Mutex
+	stats   map[string]*chaosCounters
+}
+
+// NewChaos starts the scenario's timeline now
+func NewChaos(s *ChaosScenario) *Chaos {
+	return &Chaos{
+		scenario: s,
+		start:    time.Now(),
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		stats:    make(map[string]*chaosCounters),
+	}
+}
+
+// Scenario returns the scenario being played
+func (c *Chaos) Scenario() *ChaosScenario {
+	return c.scenario
+}
+
+// Elapsed returns the time since the scenario started
+func (c *Chaos) Elapsed() time.Duration {
+	return time.Since(c.start)
+}
+
+// FaultFor returns the faults currently active for a node, given the
+// addresses it is known by (announced, dialed)
+func (c *Chaos) FaultFor(addrs ...string) FaultSpec {
+	s := c.scenario
+	fault := s.Default
+	for name, f := range s.Nodes {
+		if matchNode(name, addrs...) {
+			fault = f
+			break
+		}
+	}
+
+	t := c.Elapsed()
+	if s.Repeat > 0 {
+		t %= time.Duration(s.Repeat)
+	}
+	for _, step := range s.Timeline {
+		if t < time.Duration(step.At) || step.For > 0 && t >= time.Duration(step.At+step.For) {
+			continue
+		}
+		if len(step.Nodes) == 0 {
+			fault = step.Fault
+			continue
+		}
+		for _, name := range step.Nodes {
+			if matchNode(name, addrs...) {
+				fault = step.Fault
+				break
+			}
+		}
+	}
+	return fault
+}
+
+func (c *Chaos) counters(addr string) *chaosCounters {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	ct, ok := c.stats[addr]
+	if !ok {
+		ct = &chaosCounters{}
+		c.stats[addr] = ct
+	}
+	return ct
+}
+
+// Stats returns the per-node counters, sorted by address
+func (c *Chaos) Stats() []ChaosNodeStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	out := make([]ChaosNodeStats, 0, len(c.stats))
+	for addr, ct := range c.stats {
+		out = append(out, ChaosNodeStats{
+			Addr:       addr,
+			Conns:      ct.conns.Load(),
+			DialFails:  ct.dialFails.Load(),
+			Resets:     ct.resets.Load(),
+			Drops:      ct.drops.Load(),
+			Blackholed: ct.blackholed.Load(),
+			BytesIn:    ct.bytesIn.Load(),
+			BytesOut:   ct.bytesOut.Load(),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Addr < out[j].Addr })
+	return out
+}
+
+func (c *Chaos) chance(p float64) bool {
+	if p <= 0 {
+		return false
+	}
+	c.rngMu.Lock()
+	defer c.rngMu.Unlock()
+	return c.rng.Float64() < p
+}
+
+func (c *Chaos) jitter(max Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	c.rngMu.Lock()
+	defer c.rngMu.Unlock()
+	return time.Duration(c.rng.Int63n(int64(max)))
+}
+
+// DialFunc matches redis.Options.Dialer
+type DialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// Dialer wraps dial so connections to addr carry the faults for addr (or for
+// remap(addr), the address actually dialed). While a node is partitioned,
+// dials wait up to timeout for it to heal and then fail like an unanswered SYN.
+func (c *Chaos) Dialer(dial DialFunc, remap func(string) string, timeout time.Duration) DialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		names := []string{addr, remap(addr)}
+		ct := c.counters(addr)
+		if c.FaultFor(names...).Partition {
+			if err := c.waitHealed(ctx, names, timeout); err != nil {
+				ct.dialFails.Add(1)
+				return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+			}
+		}
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			ct.dialFails.Add(1)
+			return nil, err
+		}
+		return c.WrapConn(conn, names...), nil
+	}
+}
+
+// waitHealed blocks until the node is no longer partitioned, timeout passes
+// or ctx is done
+func (c *Chaos) waitHealed(ctx context.Context, names []string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for c.FaultFor(names...).Partition {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return os.ErrDeadlineExceeded
+		case <-tick.C:
+		}
+	}
+	return nil
+}
+
+// WrapConn returns conn with the node's faults injected on every read and
+// write. names are the node's addresses; stats are kept under the first.
+func (c *Chaos) WrapConn(conn net.Conn, names ...string) net.Conn {
+	ct := c.counters(names[0])
+	ct.conns.Add(1)
+	return &faultConn{Conn: conn, chaos: c, names: names, stats: ct, buf: make([]byte, 32<<10)}
+}
+
+// faultConn applies faults to the data flowing through a connection. Only
+// one goroutine reads and one writes at a time, as with any net.Conn user.
+type faultConn struct {
+	net.Conn
+	chaos *Chaos
+	names []string
+	stats *chaosCounters
+
+	deadlineMu   sync.Mutex
+	readDeadline time.Time
+
+	poisoned atomic.Bool // data was discarded by a partition
+
+	// Read side: a chunk received from the node waits until deliverAt
+	buf       []byte
+	pending   []byte
+	deliverAt time.Time
+	readFree  time.Time // when the simulated link is free for the next chunk
+
+	writeFree time.Time
+}
+
+func (fc *faultConn) SetDeadline(t time.Time) error {
+	fc.deadlineMu.Lock()
+	fc.readDeadline = t
+	fc.deadlineMu.Unlock()
+	return fc.Conn.SetDeadline(t)
+}
+
+func (fc *faultConn) SetReadDeadline(t time.Time) error {
+	fc.deadlineMu.Lock()
+	fc.readDeadline = t
+	fc.deadlineMu.Unlock()
+	return fc.Conn.SetReadDeadline(t)
+}
+
+func (fc *faultConn) reset(op string) error {
+	fc.stats.resets.Add(1)
+	fc.Conn.Close()
+	return &net.OpError{Op: op, Net: "tcp", Addr: fc.RemoteAddr(), Err: syscall.ECONNRESET}
+}
+
+// shape returns when a chunk of n bytes entering the link at free arrives,
+// and when the link is free again
+func (fc *faultConn) shape(f FaultSpec, n int, free time.Time, withLatency bool) (arrive, next time.Time) {
+	now := time.Now()
+	if free.Before(now) {
+		free = now
+	}
+	next = free
+	if f.Bandwidth > 0 {
+		next = free.Add(time.Duration(int64(n) * int64(time.Second) / f.Bandwidth))
+	}
+	arrive = next
+	if withLatency {
+		arrive = arrive.Add(time.Duration(f.Latency) + fc.chaos.jitter(f.Jitter))
+	}
+	if fc.chaos.chance(f.DropRate) {
+		fc.stats.drops.Add(1)
+		retransmit := time.Duration(f.RetransmitDelay)
+		if retransmit == 0 {
+			retransmit = DefaultRetransmitDelay
+		}
+		arrive = arrive.Add(retransmit)
+	}
+	return arrive, next
+}
+
+func (fc *faultConn) Write(b []byte) (int, error) {
+	f := fc.chaos.FaultFor(fc.names...)
+	switch {
+	case f.Partition:
+		fc.poisoned.Store(true)
+		fc.stats.blackholed.Add(int64(len(b)))
+		return len(b), nil
+	case fc.poisoned.Load():
+		return 0, fc.reset("write")
+	case fc.chaos.chance(f.ResetRate):
+		return 0, fc.reset("write")
+	}
+
+	// Latency is applied once, on the way back; requests only pay for
+	// bandwidth and retransmissions
+	arrive, next := fc.shape(f, len(b), fc.writeFree, false)
+	fc.writeFree = next
+	time.Sleep(time.Until(arrive))
+	n, err := fc.Conn.Write(b)
+	fc.stats.bytesOut.Add(int64(n))
+	return n, err
+}
+
+func (fc *faultConn) Read(b []byte) (int, error) {
+	for len(fc.pending) == 0 {
+		n, err := fc.Conn.Read(fc.buf)
+		if n == 0 {
+			return 0, err
+		}
+		f := fc.chaos.FaultFor(fc.names...)
+		switch {
+		case f.Partition:
+			fc.poisoned.Store(true)
+			fc.stats.blackholed.Add(int64(n))
+			continue
+		case fc.poisoned.Load():
+			return 0, fc.reset("read")
+		case fc.chaos.chance(f.ResetRate):
+			return 0, fc.reset("read")
+		}
+		fc.stats.bytesIn.Add(int64(n))
+		fc.pending = fc.buf[:n]
+		fc.deliverAt, fc.readFree = fc.shape(f, n, fc.readFree, true)
+	}
+
+	if wait := time.Until(fc.deliverAt); wait > 0 {
+		fc.deadlineMu.Lock()
+		deadline := fc.readDeadline
+		fc.deadlineMu.Unlock()
+		if !deadline.IsZero() && deadline.Before(fc.deliverAt) {
+			time.Sleep(time.Until(deadline))
+			return 0, &net.OpError{Op: "read", Net: "tcp", Addr: fc.RemoteAddr(), Err: os.ErrDeadlineExceeded}
+		}
+		time.Sleep(wait)
+	}
+	n := copy(b, fc.pending)
+	fc.pending = fc.pending[n:]
+	return n, nil
+}
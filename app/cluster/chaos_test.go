@@ -0,0 +1,192 @@
+package cluster_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+)
+
+func chaosClient(t *testing.T, fc *clustertest.Cluster, scenario *cluster.ChaosScenario) (*cluster.Client, *cluster.Chaos) {
+	t.Helper()
+	if err := scenario.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	chaos := cluster.NewChaos(scenario)
+	client, err := cluster.NewClient(fc.Config().WithChaos(chaos))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, chaos
+}
+
+// keyOn returns a key owned by node n
+func keyOn(t *testing.T, fc *clustertest.Cluster, n *clustertest.Node) string {
+	t.Helper()
+	for i := 0; i < 10000; i++ {
+		key := "chaos:" + strconv.Itoa(i)
+		if fc.NodeForKey(key) == n {
+			return key
+		}
+	}
+	t.Fatalf("no key found on %s", n.Addr())
+	return ""
+}
+
+func TestChaosLatency(t *testing.T) {
+	fc := clustertest.New(t, 2)
+	slow := fc.Nodes()[0]
+	client, _ := chaosClient(t, fc, &cluster.ChaosScenario{
+		Nodes: map[string]cluster.FaultSpec{
+			slow.Addr(): {Latency: cluster.Duration(60 * time.Millisecond)},
+		},
+	})
+	ctx := context.Background()
+	rdb := client.Redis()
+
+	slowKey, fastKey := keyOn(t, fc, slow), keyOn(t, fc, fc.Nodes()[1])
+	for _, key := range []string{slowKey, fastKey} {
+		if err := rdb.Set(ctx, key, "v", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now()
+	if err := rdb.Get(ctx, slowKey).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 60*time.Millisecond {
+		t.Fatalf("GET on the slow node took %v, want >= 60ms", d)
+	}
+	start = time.Now()
+	if err := rdb.Get(ctx, fastKey).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d >= 60*time.Millisecond {
+		t.Fatalf("GET on the healthy node took %v", d)
+	}
+}
+
+// Latency beyond the read timeout must surface as a timeout error
+func TestChaosReadTimeout(t *testing.T) {
+	fc := clustertest.New(t, 1)
+	cfg := fc.Config()
+	cfg.ReadTimeout = cluster.Duration(100 * time.Millisecond)
+	cfg.MaxRetries = -1
+	chaos := cluster.NewChaos(&cluster.ChaosScenario{
+		Timeline: []cluster.ChaosStep{
+			{At: cluster.Duration(200 * time.Millisecond), Fault: cluster.FaultSpec{Latency: cluster.Duration(time.Second)}},
+		},
+	})
+	client, err := cluster.NewClient(cfg.WithChaos(chaos))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	time.Sleep(250 * time.Millisecond)
+	err = client.Redis().Get(context.Background(), "k").Err()
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("GET over a 1s link with a 100ms read timeout: got %v, want a timeout", err)
+	}
+}
+
+func TestChaosPartition(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	cut := fc.Nodes()[2]
+	client, chaos := chaosClient(t, fc, &cluster.ChaosScenario{
+		Timeline: []cluster.ChaosStep{
+			{At: cluster.Duration(300 * time.Millisecond), For: cluster.Duration(time.Second), Nodes: []string{cut.Addr()}, Fault: cluster.FaultSpec{Partition: true}},
+		},
+	})
+	ctx := context.Background()
+	rdb := client.Redis()
+	cutKey, okKey := keyOn(t, fc, cut), keyOn(t, fc, fc.Nodes()[0])
+	for _, key := range []string{cutKey, okKey} {
+		if err := rdb.Set(ctx, key, "v", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	time.Sleep(350 * time.Millisecond)
+	if !chaos.FaultFor(cut.Addr()).Partition {
+		t.Fatal("partition step not active")
+	}
+	// Only the partitioned node is unreachable
+	if err := rdb.Get(ctx, okKey).Err(); err != nil {
+		t.Fatalf("GET on a reachable node: %v", err)
+	}
+	ctxShort, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	if err := rdb.Get(ctxShort, cutKey).Err(); err == nil {
+		t.Fatal("GET on the partitioned node succeeded")
+	}
+
+	// After the partition heals the client recovers on its own
+	var err error
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if err = rdb.Get(ctx, cutKey).Err(); err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("GET after the partition healed: %v", err)
+	}
+	if chaos.FaultFor(cut.Addr()).Partition {
+		t.Fatal("partition still active")
+	}
+}
+
+func TestChaosProxy(t *testing.T) {
+	fc := clustertest.New(t, 2)
+	direct, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer direct.Close()
+
+	chaos := cluster.NewChaos(&cluster.ChaosScenario{Default: cluster.FaultSpec{Latency: cluster.Duration(30 * time.Millisecond)}})
+	proxy, err := direct.StartChaosProxy(chaos, "127.0.0.1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxy.Close()
+	if len(proxy.Routes()) != 2 {
+		t.Fatalf("routes = %v", proxy.Routes())
+	}
+
+	cfg, err := fc.Config().WithAddrs(fc.Addrs()[0], proxy.RemapRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := cluster.NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	start := time.Now()
+	if err := client.Redis().Set(ctx, "proxied", "v", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Fatalf("SET through the proxy took %v, want >= 30ms", d)
+	}
+	var conns int64
+	for _, s := range chaos.Stats() {
+		conns += s.Conns
+	}
+	if conns == 0 {
+		t.Fatal("no connection went through the proxy")
+	}
+}
@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProxyRoute is one proxied node: clients connect to Listen, the proxy
+// connects to Node (the address the cluster announces)
+type ProxyRoute struct {
+	Node   string `json:"node"`
+	Listen string `json:"listen"`
+}
+
+// ChaosProxy is a TCP proxy in front of every cluster node that injects a
+// Chaos scenario's faults. Clients reach the nodes through it by remapping
+// the announced addresses to the proxy ports (see RemapRules).
+type ChaosProxy struct {
+	chaos  *Chaos
+	dial   DialFunc
+	routes []ProxyRoute
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// StartChaosProxy listens on listenHost:basePort, basePort+1, ... for each
+// node of the cluster, in address order
+func (c *Client) StartChaosProxy(chaos *Chaos, listenHost string, basePort int) (*ChaosProxy, error) {
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, n := range nodes {
+		if !n.NoAddr && n.Address != "" {
+			addrs = append(addrs, n.Address)
+		}
+	}
+	sortAddrs(addrs)
+
+	plain := c.cfg.WithChaos(nil)
+	p := &ChaosProxy{
+		chaos: chaos,
+		dial:  chaos.Dialer(plain.Dialer(), c.cfg.RemapAddress, time.Duration(c.cfg.DialTimeout)),
+		conns: make(map[net.Conn]struct{}),
+	}
+	for i, addr := range addrs {
+		listen := net.JoinHostPort(listenHost, strconv.Itoa(basePort+i))
+		ln, err := net.Listen("tcp", listen)
+		if err != nil {
+			p.Close()
+			return nil, fmt.Errorf("chaos proxy for %s: %w", addr, err)
+		}
+		route := ProxyRoute{Node: addr, Listen: ln.Addr().String()}
+		p.routes = append(p.routes, route)
+		p.listeners = append(p.listeners, ln)
+		p.wg.Add(1)
+		go p.accept(ln, route)
+	}
+	return p, nil
+}
+
+// sortAddrs sorts host:port addresses by host, then numerically by port
+func sortAddrs(addrs []string) {
+	key := func(a string) (string, int) {
+		host, port, _ := net.SplitHostPort(a)
+		n, _ := strconv.Atoi(port)
+		return host, n
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		h1, p1 := key(addrs[i])
+		h2, p2 := key(addrs[j])
+		return h1 < h2 || h1 == h2 && p1 < p2
+	})
+}
+
+// Routes returns the node -> listen address mapping
+func (p *ChaosProxy) Routes() []ProxyRoute {
+	return append([]ProxyRoute(nil), p.routes...)
+}
+
+// RemapRules returns the --remap value that sends clients through the proxy
+func (p *ChaosProxy) RemapRules() string {
+	rules := make([]string, len(p.routes))
+	for i, r := range p.routes {
+		rules[i] = r.Node + "=" + r.Listen
+	}
+	return strings.Join(rules, ",")
+}
+
+// Chaos returns the fault injector used by the proxy
+func (p *ChaosProxy) Chaos() *Chaos {
+	return p.chaos
+}
+
+// Close stops listening and drops every proxied connection
+func (p *ChaosProxy) Close() {
+	p.mu.Lock()
+	p.closed = true
+	for _, ln := range p.listeners {
+		ln.Close()
+	}
+	for conn := range p.conns {
+		conn.Close()
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+func (p *ChaosProxy) track(conn net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		conn.Close()
+		return false
+	}
+	p.conns[conn] = struct{}{}
+	return true
+}
+
+func (p *ChaosProxy) untrack(conn net.Conn) {
+	p.mu.Lock()
+	delete(p.conns, conn)
+	p.mu.Unlock()
+	conn.Close()
+}
+
+func (p *ChaosProxy) accept(ln net.Listener, route ProxyRoute) {
+	defer p.wg.Done()
+	for {
+		client, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		if !p.track(client) {
+			return
+		}
+		p.wg.Add(1)
+		go p.serve(client, route)
+	}
+}
+
+// serve pipes one client connection to its node. The client's connect has
+// already succeeded, so a partitioned node looks like a connection that
+// never answers and is dropped after the dial timeout.
+func (p *ChaosProxy) serve(client net.Conn, route ProxyRoute) {
+	defer p.wg.Done()
+	defer p.untrack(client)
+
+	upstream, err := p.dial(context.Background(), "tcp", route.Node)
+	if err != nil {
+		return
+	}
+	if !p.track(upstream) {
+		return
+	}
+	defer p.untrack(upstream)
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(upstream, client)
+		upstream.Close()
+		close(done)
+	}()
+	io.Copy(client, upstream)
+	client.Close()
+	<-done
+}
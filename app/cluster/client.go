@@ -3,9 +3,9 @@ package cluster
 import (
 	"context"
 	"fmt"
-	"net"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"ticket-reservation/models"
@@ -13,34 +13,21 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// addressMapper maps internal Docker IPs to localhost for host access
-var addressMapper = map[string]string{
-	"172.30.0.11": "127.0.0.1",
-	"172.30.0.12": "127.0.0.1",
-	"172.30.0.13": "127.0.0.1",
-	"172.30.0.14": "127.0.0.1",
-	"172.30.0.15": "127.0.0.1",
-	"172.30.0.16": "127.0.0.1",
-	"172.30.0.17": "127.0.0.1",
-	"172.30.0.18": "127.0.0.1",
-}
-
-// remapAddress converts internal Docker IP:port to localhost:port
-func remapAddress(addr string) string {
-	host, port, err := net.SplitHostPort(addr)
-	if err != nil {
-		return addr
-	}
-	if mapped, ok := addressMapper[host]; ok {
-		return net.JoinHostPort(mapped, port)
-	}
-	return addr
-}
-
 // Client wraps the Redis cluster client with additional functionality
 type Client struct {
-	rdb *redis.ClusterClient
-	ctx context.Context
+	rdb     *redis.ClusterClient
+	ctx     context.Context
+	cfg     *ClusterConfig
+	slots   *SlotTable
+	tracer  *RedirectTracer
+	sampler *KeySampler
+
+	routedMu sync.Mutex
+	replica  *redis.ClusterClient // ReadOnly + RouteRandomly
+	latency  *redis.ClusterClient // ReadOnly + RouteByLatency
+
+	nodeMu      sync.Mutex
+	nodeClients map[string]*redis.Client // direct per-node connections by announced address
 }
 
 // DefaultClusterAddrs returns the default cluster node addresses
@@ -55,40 +42,27 @@ func DefaultClusterAddrs() []string {
 	}
 }
 
-// NewClient creates a new Redis cluster client
-func NewClient(addrs []string) (*Client, error) {
-	if len(addrs) == 0 {
-		addrs = DefaultClusterAddrs()
+// NewClient creates a new Redis cluster client from cfg (nil = DefaultConfig)
+func NewClient(cfg *ClusterConfig) (*Client, error) {
+	if cfg == nil {
+		cfg = DefaultConfig()
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	rdb := redis.NewClusterClient(&redis.ClusterOptions{
-		Addrs:           addrs,
-		MaxRetries:      5,
-		MinRetryBackoff: 100 * time.Millisecond,
-		MaxRetryBackoff: 500 * time.Millisecond,
-		DialTimeout:     5 * time.Second,
-		ReadTimeout:     3 * time.Second,
-		WriteTimeout:    3 * time.Second,
-		PoolSize:        10,
-		MinIdleConns:    5,
-		// Route read commands to replicas for better distribution
-		RouteRandomly: true,
-		// Custom dialer to remap Docker internal IPs to localhost
-		Dialer: func(ctx context.Context, network, addr string) (net.Conn, error) {
-			mappedAddr := remapAddress(addr)
-			netDialer := &net.Dialer{
-				Timeout:   5 * time.Second,
-				KeepAlive: 5 * time.Minute,
-			}
-			return netDialer.DialContext(ctx, network, mappedAddr)
-		},
-	})
+	// Writes and strong reads always go to masters; replica and latency
+	// routed clients are created on demand (see ReplicaClient/LatencyClient)
+	slots := NewSlotTable()
+	tracer := NewRedirectTracer()
+	sampler := NewKeySampler()
+	rdb := newClusterClient(cfg, slots, tracer, sampler, func(opt *redis.ClusterOptions) {})
 
 	ctx := context.Background()
 
 	// Test connection with retries
 	var err error
-	for i := 0; i < 5; i++ {
+	for i := 0; i < cfg.ConnectRetries || i == 0; i++ {
 		err = rdb.Ping(ctx).Err()
 		if err == nil {
 			break
@@ -97,25 +71,155 @@ func NewClient(addrs []string) (*Client, error) {
 	}
 
 	if err != nil {
+		rdb.Close()
 		return nil, fmt.Errorf("failed to connect to Redis cluster: %w", err)
 	}
 
 	return &Client{
-		rdb: rdb,
-		ctx: ctx,
+		rdb:         rdb,
+		ctx:         ctx,
+		cfg:         cfg,
+		slots:       slots,
+		tracer:      tracer,
+		sampler:     sampler,
+		nodeClients: make(map[string]*redis.Client),
 	}, nil
 }
 
-// Close closes the Redis cluster connection
+// newClusterClient builds a cluster client from cfg; tune adjusts read routing
+func newClusterClient(cfg *ClusterConfig, slots *SlotTable, tracer *RedirectTracer, sampler *KeySampler, tune func(*redis.ClusterOptions)) *redis.ClusterClient {
+	opt := &redis.ClusterOptions{
+		Addrs:           cfg.Addrs,
+		MaxRetries:      cfg.MaxRetries,
+		MinRetryBackoff: time.Duration(cfg.MinRetryBackoff),
+		MaxRetryBackoff: time.Duration(cfg.MaxRetryBackoff),
+		DialTimeout:     time.Duration(cfg.DialTimeout),
+		ReadTimeout:     time.Duration(cfg.ReadTimeout),
+		WriteTimeout:    time.Duration(cfg.WriteTimeout),
+		PoolSize:        cfg.PoolSize,
+		MinIdleConns:    cfg.MinIdleConns,
+		// Custom dialer to remap announced node addresses (e.g. Docker IPs)
+		Dialer: cfg.Dialer(),
+	}
+	tune(opt)
+	rdb := redis.NewClusterClient(opt)
+
+	// Drop the cached slot table whenever a node redirects with MOVED, and
+	// count every redirect per node; sample keys for hot-key detection
+	rdb.OnNewNode(func(node *redis.Client) {
+		node.AddHook(movedHook{table: slots})
+		node.AddHook(tracer.hook(node.Options().Addr))
+		node.AddHook(sampler.hook(node.Options().Addr))
+	})
+	return rdb
+}
+
+// ReplicaClient returns a cluster client that sends read-only commands to a
+// random node of the shard (master or replica). Reads may be stale.
+func (c *Client) ReplicaClient() *redis.ClusterClient {
+	c.routedMu.Lock()
+	defer c.routedMu.Unlock()
+	if c.replica == nil {
+		c.replica = newClusterClient(c.cfg, c.slots, c.tracer, c.sampler, func(opt *redis.ClusterOptions) {
+			opt.ReadOnly = true
+			opt.RouteRandomly = true
+		})
+	}
+	return c.replica
+}
+
+// LatencyClient returns a cluster client that sends read-only commands to
+// the lowest-latency node of the shard. Reads may be stale.
+func (c *Client) LatencyClient() *redis.ClusterClient {
+	c.routedMu.Lock()
+	defer c.routedMu.Unlock()
+	if c.latency == nil {
+		c.latency = newClusterClient(c.cfg, c.slots, c.tracer, c.sampler, func(opt *redis.ClusterOptions) {
+			opt.ReadOnly = true
+			opt.RouteByLatency = true
+		})
+	}
+	return c.latency
+}
+
+// Close closes the Redis cluster connection and any direct node connections
 func (c *Client) Close() error {
+	c.routedMu.Lock()
+	for _, rc := range []*redis.ClusterClient{c.replica, c.latency} {
+		if rc != nil {
+			rc.Close()
+		}
+	}
+	c.routedMu.Unlock()
+
+	c.nodeMu.Lock()
+	for addr, nc := range c.nodeClients {
+		nc.Close()
+		delete(c.nodeClients, addr)
+	}
+	c.nodeMu.Unlock()
 	return c.rdb.Close()
 }
 
-// Redis returns the underlying Redis cluster client
+// NodeClient returns a direct (non-cluster) connection to a single node.
+// addr is the address the node announces; remap rules are applied when dialing.
+// Connections are cached and closed by Close.
+func (c *Client) NodeClient(addr string) *redis.Client {
+	c.nodeMu.Lock()
+	defer c.nodeMu.Unlock()
+
+	if nc, ok := c.nodeClients[addr]; ok {
+		return nc
+	}
+	nc := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  time.Duration(c.cfg.DialTimeout),
+		ReadTimeout:  time.Duration(c.cfg.ReadTimeout),
+		WriteTimeout: time.Duration(c.cfg.WriteTimeout),
+		PoolSize:     2,
+		Dialer:       c.cfg.Dialer(),
+	})
+	c.nodeClients[addr] = nc
+	return nc
+}
+
+// ResolveNode finds a node by full ID, unique ID prefix or address
+func (c *Client) ResolveNode(ref string) (models.ClusterNode, error) {
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return models.ClusterNode{}, err
+	}
+
+	var matches []models.ClusterNode
+	for _, n := range nodes {
+		switch {
+		case n.ID == ref, n.Address == ref, c.cfg.RemapAddress(n.Address) == ref:
+			return n, nil
+		case strings.HasPrefix(n.ID, ref):
+			matches = append(matches, n)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return models.ClusterNode{}, fmt.Errorf("no node matches %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		return models.ClusterNode{}, fmt.Errorf("node reference %q is ambiguous (%d matches)", ref, len(matches))
+	}
+}
+
+// Redis returns the underlying Redis cluster client (master-only routing)
 func (c *Client) Redis() *redis.ClusterClient {
 	return c.rdb
 }
 
+// Config returns the configuration the client was created with
+func (c *Client) Config() *ClusterConfig {
+	return c.cfg
+}
+
 // Context returns the context
 func (c *Client) Context() context.Context {
 	return c.ctx
@@ -166,97 +270,72 @@ func (c *Client) GetClusterInfo() (*models.ClusterInfo, error) {
 	nodes, err := c.GetClusterNodes()
 	if err == nil {
 		clusterInfo.Nodes = nodes
+		for _, node := range nodes {
+			if node.IsFailing() {
+				clusterInfo.FailingNodes = append(clusterInfo.FailingNodes, node)
+			}
+		}
+		c.mergeOwnMarkers(nodes)
+		clusterInfo.Migrations = FindMigrations(nodes)
 	}
 
 	return clusterInfo, nil
 }
 
-// GetClusterNodes retrieves information about all cluster nodes
-func (c *Client) GetClusterNodes() ([]models.ClusterNode, error) {
-	nodesStr, err := c.rdb.ClusterNodes(c.ctx).Result()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get cluster nodes: %w", err)
-	}
-
-	var nodes []models.ClusterNode
-	lines := strings.Split(nodesStr, "\n")
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		parts := strings.Fields(line)
-		if len(parts) < 8 {
+// mergeOwnMarkers asks every master for its own CLUSTER NODES entry and
+// merges its IMPORTING/MIGRATING markers into nodes, which come from one
+// node's view and so only carry that node's markers
+func (c *Client) mergeOwnMarkers(nodes []models.ClusterNode) {
+	for i := range nodes {
+		n := &nodes[i]
+		if n.Role != "master" || n.Fail {
 			continue
 		}
-
-		node := models.ClusterNode{
-			ID:      parts[0],
-			Address: strings.Split(parts[1], "@")[0],
-		}
-
-		// Parse flags
-		flags := parts[2]
-		if strings.Contains(flags, "master") {
-			node.Role = "master"
-		} else if strings.Contains(flags, "slave") {
-			node.Role = "replica"
+		text, err := c.NodeClient(n.Address).ClusterNodes(c.ctx).Result()
+		if err != nil {
+			continue // its markers stay unknown, as in the seed's view
 		}
-
-		// Master ID (for replicas)
-		if parts[3] != "-" {
-			node.MasterID = parts[3]
-		}
-
-		// Slots (for masters)
-		if len(parts) > 8 && node.Role == "master" {
-			node.Slots = strings.Join(parts[8:], " ")
+		for _, vn := range ParseClusterNodes(text) {
+			if vn.Myself && vn.ID == n.ID {
+				mergeMarkers(n, vn)
+			}
 		}
+	}
+}
 
-		nodes = append(nodes, node)
+// GetClusterNodes retrieves information about all cluster nodes
+func (c *Client) GetClusterNodes() ([]models.ClusterNode, error) {
+	nodesStr, err := c.rdb.ClusterNodes(c.ctx).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster nodes: %w", err)
 	}
 
+	nodes := ParseClusterNodes(nodesStr)
+	c.slots.Update(nodes)
 	return nodes, nil
 }
 
-// GetSlotForKey returns the hash slot for a given key
+// GetSlotForKey returns the hash slot for a given key (computed locally)
 func (c *Client) GetSlotForKey(key string) int {
-	return int(c.rdb.ClusterKeySlot(c.ctx, key).Val())
+	return KeySlot(key)
 }
 
-// GetNodeForSlot returns which node handles a specific slot
+// GetNodeForSlot returns which node handles a specific slot, using the cached
+// slot table. An unowned slot forces one refresh in case the table is outdated.
 func (c *Client) GetNodeForSlot(slot int) (string, error) {
-	nodes, err := c.GetClusterNodes()
+	table, err := c.Slots()
 	if err != nil {
 		return "", err
 	}
+	if node, ok := table.Owner(slot); ok {
+		return node.Address, nil
+	}
 
-	for _, node := range nodes {
-		if node.Role != "master" || node.Slots == "" {
-			continue
-		}
-
-		// Parse slot ranges
-		ranges := strings.Fields(node.Slots)
-		for _, r := range ranges {
-			if strings.Contains(r, "-") {
-				parts := strings.Split(r, "-")
-				if len(parts) == 2 {
-					start, _ := strconv.Atoi(parts[0])
-					end, _ := strconv.Atoi(parts[1])
-					if slot >= start && slot <= end {
-						return node.Address, nil
-					}
-				}
-			} else {
-				s, _ := strconv.Atoi(r)
-				if s == slot {
-					return node.Address, nil
-				}
-			}
-		}
+	if err := c.RefreshSlots(); err != nil {
+		return "", err
+	}
+	if node, ok := c.slots.Owner(slot); ok {
+		return node.Address, nil
 	}
 
 	return "", fmt.Errorf("no node found for slot %d", slot)
@@ -284,16 +363,49 @@ func (c *Client) PrintClusterStatus() error {
 	for _, node := range info.Nodes {
 		role := node.Role
 		if role == "replica" {
-			fmt.Printf("  [REPLICA] %s -> master %s\n", node.Address, node.MasterID[:8])
+			fmt.Printf("  [REPLICA] %s -> master %s%s\n", node.Address, ShortID(node.MasterID), nodeStateSuffix(node))
 		} else {
-			fmt.Printf("  [MASTER]  %s slots: %s\n", node.Address, node.Slots)
+			fmt.Printf("  [MASTER]  %s slots: %s%s\n", node.Address, node.Slots, nodeStateSuffix(node))
+		}
+	}
+
+	if len(info.FailingNodes) > 0 {
+		fmt.Println("\n--- Failing Nodes ---")
+		for _, node := range info.FailingNodes {
+			fmt.Printf("  %s %s (%s) flags=%s link=%s epoch=%d\n",
+				ShortID(node.ID), node.Address, node.Role, strings.Join(node.Flags, ","), node.LinkState, node.ConfigEpoch)
 		}
 	}
-	fmt.Println("========================================\n")
+
+	if len(info.Migrations) > 0 {
+		fmt.Println("\n--- Migrations In Flight ---")
+		for _, m := range info.Migrations {
+			fmt.Printf("  slot %5d: %s -> %s\n", m.Slot, ShortID(m.SourceID), ShortID(m.TargetID))
+		}
+	}
+	fmt.Println("========================================")
+	fmt.Println()
 
 	return nil
 }
 
+// nodeStateSuffix returns a marker for nodes that are not healthy
+func nodeStateSuffix(node models.ClusterNode) string {
+	switch {
+	case node.Fail:
+		return "  [FAIL]"
+	case node.PFail:
+		return "  [PFAIL]"
+	case node.Handshake:
+		return "  [HANDSHAKE]"
+	case node.NoAddr:
+		return "  [NOADDR]"
+	case node.LinkState == "disconnected" && !node.Myself:
+		return "  [DISCONNECTED]"
+	}
+	return ""
+}
+
 // ForEachMaster executes a function on each master node
 func (c *Client) ForEachMaster(fn func(client *redis.Client) error) error {
 	return c.rdb.ForEachMaster(c.ctx, func(ctx context.Context, client *redis.Client) error {
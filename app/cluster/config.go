@@ -0,0 +1,430 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables recognised by LoadConfig
+const (
+	EnvConfigFile      = "REDIS_CLUSTER_CONFIG"
+	EnvAddrs           = "REDIS_CLUSTER_ADDRS"
+	EnvRemap           = "REDIS_CLUSTER_REMAP"
+	EnvPoolSize        = "REDIS_POOL_SIZE"
+	EnvMinIdleConns    = "REDIS_MIN_IDLE_CONNS"
+	EnvDialTimeout     = "REDIS_DIAL_TIMEOUT"
+	EnvReadTimeout     = "REDIS_READ_TIMEOUT"
+	EnvWriteTimeout    = "REDIS_WRITE_TIMEOUT"
+	EnvMaxRetries      = "REDIS_MAX_RETRIES"
+	EnvMinRetryBackoff = "REDIS_MIN_RETRY_BACKOFF"
+	EnvMaxRetryBackoff = "REDIS_MAX_RETRY_BACKOFF"
+	EnvConnectRetries  = "REDIS_CONNECT_RETRIES"
+)
+
+// Duration is a time.Duration that reads and writes as a string ("500ms", "3s") in JSON
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a Go duration string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON accepts either a duration string or a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		*d = Duration(parsed)
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid duration %s", string(data))
+	}
+	*d = Duration(n)
+	return nil
+}
+
+// RemapRule rewrites a node address announced by the cluster into one reachable
+// from this host. Match is a CIDR ("172.30.0.0/24"), a host ("redis-1") or a
+// host:port; Target is a host (port is kept) or a host:port (port is replaced).
+type RemapRule struct {
+	Match  string `json:"match"`
+	Target string `json:"target"`
+}
+
+// ParseRemapRules parses "match=target,match=target" into remap rules
+func ParseRemapRules(s string) ([]RemapRule, error) {
+	var rules []RemapRule
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid remap rule %q (want match=target)", part)
+		}
+		rules = append(rules, RemapRule{Match: strings.TrimSpace(kv[0]), Target: strings.TrimSpace(kv[1])})
+	}
+	return rules, nil
+}
+
+// matches reports whether the rule applies to host:port
+func (r RemapRule) matches(host, port string) bool {
+	if _, network, err := net.ParseCIDR(r.Match); err == nil {
+		ip := net.ParseIP(host)
+		return ip != nil && network.Contains(ip)
+	}
+	if mHost, mPort, err := net.SplitHostPort(r.Match); err == nil {
+		return mHost == host && mPort == port
+	}
+	return r.Match == host
+}
+
+// apply returns the remapped address for host:port
+func (r RemapRule) apply(host, port string) string {
+	if _, _, err := net.SplitHostPort(r.Target); err == nil {
+		return r.Target
+	}
+	return net.JoinHostPort(r.Target, port)
+}
+
+// ClusterConfig holds everything needed to connect to a Redis cluster
+type ClusterConfig struct {
+	// Seed node addresses used to discover the cluster
+	Addrs []string `json:"addrs"`
+	// Address rewrites applied by the dialer (first match wins)
+	Remap []RemapRule `json:"remap,omitempty"`
+
+	// Connection pool
+	PoolSize     int `json:"pool_size"`
+	MinIdleConns int `json:"min_idle_conns"`
+
+	// Timeouts
+	DialTimeout  Duration `json:"dial_timeout"`
+	ReadTimeout  Duration `json:"read_timeout"`
+	WriteTimeout Duration `json:"write_timeout"`
+
+	// Command retry policy (MOVED/ASK/TRYAGAIN and network errors)
+	MaxRetries      int      `json:"max_retries"`
+	MinRetryBackoff Duration `json:"min_retry_backoff"`
+	MaxRetryBackoff Duration `json:"max_retry_backoff"`
+
+	// Number of PING attempts when connecting, with linear backoff between them
+	ConnectRetries int `json:"connect_retries"`
+
+	// Faults injected into every connection (see WithChaos)
+	chaos *Chaos
+}
+
+// DefaultConfig returns the configuration for the local docker-compose lab
+func DefaultConfig() *ClusterConfig {
+	return &ClusterConfig{
+		Addrs: DefaultClusterAddrs(),
+		// Nodes announce their Docker bridge IPs; reach them via the published ports
+		Remap: []RemapRule{
+			{Match: "172.30.0.0/24", Target: "127.0.0.1"},
+		},
+		PoolSize:        10,
+		MinIdleConns:    5,
+		DialTimeout:     Duration(5 * time.Second),
+		ReadTimeout:     Duration(3 * time.Second),
+		WriteTimeout:    Duration(3 * time.Second),
+		MaxRetries:      5,
+		MinRetryBackoff: Duration(100 * time.Millisecond),
+		MaxRetryBackoff: Duration(500 * time.Millisecond),
+		ConnectRetries:  5,
+	}
+}
+
+// LoadConfig builds a configuration from defaults, an optional JSON file and
+// environment variables, in that order of precedence (later wins). When path
+// is empty the REDIS_CLUSTER_CONFIG environment variable is consulted.
+func LoadConfig(path string) (*ClusterConfig, error) {
+	cfg := DefaultConfig()
+
+	if path == "" {
+		path = os.Getenv(EnvConfigFile)
+	}
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile overlays values from a JSON config file
+func (c *ClusterConfig) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read cluster config: %w", err)
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("failed to parse cluster config %s: %w", path, err)
+	}
+	return nil
+}
+
+// applyEnv overlays values from environment variables
+func (c *ClusterConfig) applyEnv() error {
+	if v := os.Getenv(EnvAddrs); v != "" {
+		c.Addrs = splitAddrs(v)
+	}
+	if v := os.Getenv(EnvRemap); v != "" {
+		rules, err := ParseRemapRules(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", EnvRemap, err)
+		}
+		c.Remap = rules
+	}
+
+	ints := map[string]*int{
+		EnvPoolSize:       &c.PoolSize,
+		EnvMinIdleConns:   &c.MinIdleConns,
+		EnvMaxRetries:     &c.MaxRetries,
+		EnvConnectRetries: &c.ConnectRetries,
+	}
+	for name, dst := range ints {
+		if v := os.Getenv(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: invalid integer %q", name, v)
+			}
+			*dst = n
+		}
+	}
+
+	durations := map[string]*Duration{
+		EnvDialTimeout:     &c.DialTimeout,
+		EnvReadTimeout:     &c.ReadTimeout,
+		EnvWriteTimeout:    &c.WriteTimeout,
+		EnvMinRetryBackoff: &c.MinRetryBackoff,
+		EnvMaxRetryBackoff: &c.MaxRetryBackoff,
+	}
+	for name, dst := range durations {
+		if v := os.Getenv(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("%s: invalid duration %q", name, v)
+			}
+			*dst = Duration(d)
+		}
+	}
+	return nil
+}
+
+// Flags holds the raw values of the global cluster flags until they are applied
+type Flags struct {
+	ConfigFile      string
+	Addrs           string
+	Remap           string
+	PoolSize        int
+	MinIdleConns    int
+	DialTimeout     time.Duration
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	MaxRetries      int
+	MinRetryBackoff time.Duration
+	MaxRetryBackoff time.Duration
+	ConnectRetries  int
+	ChaosScenario   string
+
+	fs *flag.FlagSet
+}
+
+// RegisterFlags adds the global cluster flags to fs. Only flags given on the
+// command line override the file and environment, whatever their value.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.ConfigFile, "config", "", "Cluster config file (JSON, or set "+EnvConfigFile+")")
+	fs.StringVar(&f.Addrs, "addrs", "", "Comma-separated seed node addresses")
+	fs.StringVar(&f.Remap, "remap", "", "Address remap rules: <cidr|host|host:port>=<host|host:port>,...")
+	fs.IntVar(&f.PoolSize, "pool-size", 0, "Connections per node")
+	fs.IntVar(&f.MinIdleConns, "min-idle-conns", 0, "Minimum idle connections per node")
+	fs.DurationVar(&f.DialTimeout, "dial-timeout", 0, "Dial timeout")
+	fs.DurationVar(&f.ReadTimeout, "read-timeout", 0, "Read timeout")
+	fs.DurationVar(&f.WriteTimeout, "write-timeout", 0, "Write timeout")
+	fs.IntVar(&f.MaxRetries, "max-retries", 0, "Max command retries (-1 = none)")
+	fs.DurationVar(&f.MinRetryBackoff, "min-retry-backoff", 0, "Minimum backoff between retries")
+	fs.DurationVar(&f.MaxRetryBackoff, "max-retry-backoff", 0, "Maximum backoff between retries")
+	fs.IntVar(&f.ConnectRetries, "connect-retries", 0, "PING attempts when connecting")
+	fs.StringVar(&f.ChaosScenario, "chaos", "", "Inject network faults from this scenario file into every connection")
+	return f
+}
+
+// Load builds the final configuration: defaults, then file, then environment, then flags
+func (f *Flags) Load() (*ClusterConfig, error) {
+	cfg, err := LoadConfig(f.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool)
+	if f.fs != nil {
+		f.fs.Visit(func(fl *flag.Flag) { set[fl.Name] = true })
+	}
+
+	if set["addrs"] {
+		cfg.Addrs = splitAddrs(f.Addrs)
+	}
+	if set["remap"] {
+		rules, err := ParseRemapRules(f.Remap)
+		if err != nil {
+			return nil, fmt.Errorf("--remap: %w", err)
+		}
+		cfg.Remap = rules
+	}
+
+	ints := map[string]struct {
+		src int
+		dst *int
+	}{
+		"pool-size":       {f.PoolSize, &cfg.PoolSize},
+		"min-idle-conns":  {f.MinIdleConns, &cfg.MinIdleConns},
+		"max-retries":     {f.MaxRetries, &cfg.MaxRetries},
+		"connect-retries": {f.ConnectRetries, &cfg.ConnectRetries},
+	}
+	for name, v := range ints {
+		if set[name] {
+			*v.dst = v.src
+		}
+	}
+
+	durations := map[string]struct {
+		src time.Duration
+		dst *Duration
+	}{
+		"dial-timeout":      {f.DialTimeout, &cfg.DialTimeout},
+		"read-timeout":      {f.ReadTimeout, &cfg.ReadTimeout},
+		"write-timeout":     {f.WriteTimeout, &cfg.WriteTimeout},
+		"min-retry-backoff": {f.MinRetryBackoff, &cfg.MinRetryBackoff},
+		"max-retry-backoff": {f.MaxRetryBackoff, &cfg.MaxRetryBackoff},
+	}
+	for name, v := range durations {
+		if set[name] {
+			*v.dst = Duration(v.src)
+		}
+	}
+	if f.ChaosScenario != "" {
+		scenario, err := LoadChaosScenario(f.ChaosScenario)
+		if err != nil {
+			return nil, err
+		}
+		cfg = cfg.WithChaos(NewChaos(scenario))
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the configuration for obvious mistakes
+func (c *ClusterConfig) Validate() error {
+	if len(c.Addrs) == 0 {
+		return fmt.Errorf("cluster config: at least one seed address is required")
+	}
+	for _, addr := range c.Addrs {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("cluster config: invalid seed address %q: %w", addr, err)
+		}
+	}
+	for _, r := range c.Remap {
+		if r.Match == "" || r.Target == "" {
+			return fmt.Errorf("cluster config: remap rule needs both match and target")
+		}
+	}
+	if c.MinRetryBackoff > c.MaxRetryBackoff {
+		return fmt.Errorf("cluster config: min_retry_backoff exceeds max_retry_backoff")
+	}
+	return nil
+}
+
+// RemapAddress converts an address announced by the cluster into one reachable
+// from this host using the first matching remap rule
+func (c *ClusterConfig) RemapAddress(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	for _, r := range c.Remap {
+		if r.matches(host, port) {
+			return r.apply(host, port)
+		}
+	}
+	return addr
+}
+
+// WithAddrs returns a copy of c for another cluster: seeded with a
+// comma-separated address list and, if remap is not empty, using those remap
+// rules instead of c's
+func (c *ClusterConfig) WithAddrs(addrs, remap string) (*ClusterConfig, error) {
+	cp := *c
+	cp.Addrs = splitAddrs(addrs)
+	if remap != "" {
+		rules, err := ParseRemapRules(remap)
+		if err != nil {
+			return nil, err
+		}
+		cp.Remap = rules
+	}
+	if err := cp.Validate(); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// WithChaos returns a copy of c whose connections go through chaos
+func (c *ClusterConfig) WithChaos(chaos *Chaos) *ClusterConfig {
+	cp := *c
+	cp.chaos = chaos
+	return &cp
+}
+
+// Chaos returns the fault injector set with WithChaos, or nil
+func (c *ClusterConfig) Chaos() *Chaos {
+	return c.chaos
+}
+
+// splitAddrs splits a comma-separated address list
+func splitAddrs(s string) []string {
+	var addrs []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+// Dialer returns a dial function that applies the remap rules before
+// connecting, and the chaos faults if any
+func (c *ClusterConfig) Dialer() func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		netDialer := &net.Dialer{
+			Timeout:   time.Duration(c.DialTimeout),
+			KeepAlive: 5 * time.Minute,
+		}
+		return netDialer.DialContext(ctx, network, c.RemapAddress(addr))
+	}
+	if c.chaos != nil {
+		return c.chaos.Dialer(dial, c.RemapAddress, time.Duration(c.DialTimeout))
+	}
+	return dial
+}
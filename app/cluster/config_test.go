@@ -0,0 +1,49 @@
+package cluster_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// loadFlags parses args as the global flags over the given environment
+func loadFlags(t *testing.T, env map[string]string, args ...string) *cluster.ClusterConfig {
+	t.Helper()
+	t.Setenv(cluster.EnvConfigFile, "")
+	t.Setenv(cluster.EnvAddrs, "127.0.0.1:7000")
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := cluster.RegisterFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := f.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// Flags given as zero or negative still override the environment
+func TestFlagsOverrideWithZero(t *testing.T) {
+	env := map[string]string{
+		cluster.EnvMaxRetries:   "5",
+		cluster.EnvMinIdleConns: "4",
+		cluster.EnvReadTimeout:  "5s",
+	}
+
+	cfg := loadFlags(t, env, "--max-retries", "-1", "--min-idle-conns", "0", "--read-timeout", "0")
+	if cfg.MaxRetries != -1 || cfg.MinIdleConns != 0 || cfg.ReadTimeout != 0 {
+		t.Fatalf("max retries %d, min idle %d, read timeout %v; want -1, 0, 0", cfg.MaxRetries, cfg.MinIdleConns, time.Duration(cfg.ReadTimeout))
+	}
+
+	// Flags left out keep the environment's values
+	cfg = loadFlags(t, env)
+	if cfg.MaxRetries != 5 || cfg.MinIdleConns != 4 || cfg.ReadTimeout != cluster.Duration(5*time.Second) {
+		t.Fatalf("max retries %d, min idle %d, read timeout %v; want 5, 4, 5s", cfg.MaxRetries, cfg.MinIdleConns, time.Duration(cfg.ReadTimeout))
+	}
+}
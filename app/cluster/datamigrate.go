@@ -0,0 +1,442 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DataMigrationOptions tune a cluster-to-cluster data migration
+type DataMigrationOptions struct {
+	Match        string        // only keys matching this pattern ("" = all)
+	BatchSize    int           // keys per SCAN page / DUMP pipeline (default 500)
+	SyncInterval time.Duration // how often changed keys are copied (default 100ms)
+}
+
+// DataMigrationStats are the counters of a running migration
+type DataMigrationStats struct {
+	Copied  int64 `json:"copied"`  // keys copied by the initial copy
+	Events  int64 `json:"events"`  // keyspace notifications received
+	Synced  int64 `json:"synced"`  // changed keys copied again
+	Deleted int64 `json:"deleted"` // keys deleted on the target because they vanished
+	Pending int   `json:"pending"` // changed keys waiting to be copied
+}
+
+// DataMigration copies every key from one cluster to another and keeps the
+// target in sync through keyspace notifications until cutover.
+//
+// Notifications are enabled on every source master (and restored on Stop) and
+// subscribed to before the initial copy starts, so writes made while copying
+// are replayed afterwards. Each changed key is re-read with DUMP; a key that
+// no longer exists is deleted on the target.
+type DataMigration struct {
+	source *Client
+	target *Client
+	opts   DataMigrationOptions
+
+	subs      []*redis.PubSub
+	notifyCfg map[string]string // master address -> original notify-keyspace-events
+	wg        sync.WaitGroup
+
+	mu    sync.Mutex
+	dirty map[string]struct{}
+
+	copied, events, synced, deleted atomic.Int64
+}
+
+// NewDataMigration prepares a migration from source to target
+func NewDataMigration(source, target *Client, opts DataMigrationOptions) *DataMigration {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = 100 * time.Millisecond
+	}
+	return &DataMigration{
+		source:    source,
+		target:    target,
+		opts:      opts,
+		notifyCfg: make(map[string]string),
+		dirty:     make(map[string]struct{}),
+	}
+}
+
+// Stats returns the current counters
+func (m *DataMigration) Stats() DataMigrationStats {
+	m.mu.Lock()
+	pending := len(m.dirty)
+	m.mu.Unlock()
+	return DataMigrationStats{
+		Copied:  m.copied.Load(),
+		Events:  m.events.Load(),
+		Synced:  m.synced.Load(),
+		Deleted: m.deleted.Load(),
+		Pending: pending,
+	}
+}
+
+// keyspacePrefix is the channel prefix of keyspace notifications for db 0,
+// the only database in cluster mode
+const keyspacePrefix = "__keyspace@0__:"
+
+// StartCapture enables keyspace notifications on every source master and
+// records changed keys until Stop. Call it before Copy.
+func (m *DataMigration) StartCapture(ctx context.Context) error {
+	masters, err := m.source.backupMasters(m.opts.Match)
+	if err != nil {
+		return err
+	}
+
+	match := m.opts.Match
+	if match == "" {
+		match = "*"
+	}
+	for _, master := range masters {
+		node := m.source.NodeClient(master.Address)
+		cfg, err := node.ConfigGet(ctx, "notify-keyspace-events").Result()
+		if err != nil {
+			m.Stop()
+			return fmt.Errorf("%s: CONFIG GET: %w", master.Address, err)
+		}
+		m.notifyCfg[master.Address] = cfg["notify-keyspace-events"]
+		// K: keyspace channel, A: every event class (set, del, expired, evicted, ...)
+		if err := node.ConfigSet(ctx, "notify-keyspace-events", "KA").Err(); err != nil {
+			m.Stop()
+			return fmt.Errorf("%s: enable keyspace notifications: %w", master.Address, err)
+		}
+
+		// Notifications are local to the node that changed the key
+		sub := node.PSubscribe(ctx, keyspacePrefix+match)
+		if _, err := sub.Receive(ctx); err != nil {
+			sub.Close()
+			m.Stop()
+			return fmt.Errorf("%s: subscribe: %w", master.Address, err)
+		}
+		m.subs = append(m.subs, sub)
+		m.wg.Add(1)
+		go m.capture(sub)
+	}
+	return nil
+}
+
+// capture marks the key of every notification received on sub as changed
+func (m *DataMigration) capture(sub *redis.PubSub) {
+	defer m.wg.Done()
+	for msg := range sub.Channel() {
+		key := strings.TrimPrefix(msg.Channel, keyspacePrefix)
+		m.events.Add(1)
+		m.mu.Lock()
+		m.dirty[key] = struct{}{}
+		m.mu.Unlock()
+	}
+}
+
+// Stop unsubscribes and restores the source masters' notification settings
+func (m *DataMigration) Stop() {
+	for _, sub := range m.subs {
+		sub.Close()
+	}
+	m.wg.Wait()
+	m.subs = nil
+
+	ctx := context.Background()
+	for addr, cfg := range m.notifyCfg {
+		if err := m.source.NodeClient(addr).ConfigSet(ctx, "notify-keyspace-events", cfg).Err(); err != nil {
+			log.Printf("[MigrateData] failed to restore notify-keyspace-events on %s: %v", addr, err)
+		}
+	}
+	m.notifyCfg = make(map[string]string)
+}
+
+// CopyProgress is reported after every SCAN page of the initial copy
+type CopyProgress struct {
+	Node   string
+	Keys   int   // keys copied from this page
+	Copied int64 // keys copied so far
+}
+
+// Copy copies every matching key from the source masters to the target with
+// DUMP/PTTL and RESTORE REPLACE routed by slot
+func (m *DataMigration) Copy(ctx context.Context, progress func(CopyProgress)) error {
+	masters, err := m.source.backupMasters(m.opts.Match)
+	if err != nil {
+		return err
+	}
+
+	for _, master := range masters {
+		node := m.source.NodeClient(master.Address)
+		var cursor uint64
+		for {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			keys, next, err := node.Scan(ctx, cursor, m.opts.Match, int64(m.opts.BatchSize)).Result()
+			if err != nil {
+				return fmt.Errorf("scan %s: %w", master.Address, err)
+			}
+			entries, err := m.source.dumpKeys(ctx, node, keys)
+			if err != nil {
+				return fmt.Errorf("dump on %s: %w", master.Address, err)
+			}
+			if err := m.target.restoreEntries(ctx, entries); err != nil {
+				return err
+			}
+			m.copied.Add(int64(len(entries)))
+			if progress != nil {
+				progress(CopyProgress{Node: master.Address, Keys: len(entries), Copied: m.copied.Load()})
+			}
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// Sync copies changed keys every SyncInterval until ctx is cancelled
+func (m *DataMigration) Sync(ctx context.Context) error {
+	ticker := time.NewTicker(m.opts.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := m.Flush(ctx); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// Flush copies every key changed since the last flush and returns how many
+// keys it processed. Keys that failed are marked changed again.
+func (m *DataMigration) Flush(ctx context.Context) (int, error) {
+	m.mu.Lock()
+	dirty := m.dirty
+	m.dirty = make(map[string]struct{})
+	m.mu.Unlock()
+
+	keys := make([]string, 0, len(dirty))
+	for k := range dirty {
+		keys = append(keys, k)
+	}
+
+	for start := 0; start < len(keys); start += m.opts.BatchSize {
+		end := start + m.opts.BatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := m.syncKeys(ctx, keys[start:end]); err != nil {
+			m.mu.Lock()
+			for _, k := range keys[start:] {
+				m.dirty[k] = struct{}{}
+			}
+			m.mu.Unlock()
+			return start, err
+		}
+	}
+	return len(keys), nil
+}
+
+// syncKeys makes the target's copy of keys match the source
+func (m *DataMigration) syncKeys(ctx context.Context, keys []string) error {
+	entries, err := m.source.dumpKeys(ctx, m.source.rdb, keys)
+	if err != nil {
+		return err
+	}
+	if err := m.target.restoreEntries(ctx, entries); err != nil {
+		return err
+	}
+	m.synced.Add(int64(len(entries)))
+
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		present[e.Key] = true
+	}
+	var gone []string
+	for _, k := range keys {
+		if !present[k] {
+			gone = append(gone, k)
+		}
+	}
+	if len(gone) == 0 {
+		return nil
+	}
+	_, err = m.target.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, k := range gone {
+			pipe.Del(ctx, k)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("delete vanished keys: %w", err)
+	}
+	m.deleted.Add(int64(len(gone)))
+	return nil
+}
+
+// MigrationVerification compares source and target after a migration
+type MigrationVerification struct {
+	SourceKeys int64    `json:"source_keys"`
+	TargetKeys int64    `json:"target_keys"`
+	Sampled    int      `json:"sampled"`
+	Method     string   `json:"method"` // DEBUG DIGEST-VALUE, or DUMP if DEBUG is disabled
+	Mismatches []string `json:"mismatches,omitempty"`
+}
+
+// OK reports whether the key counts match and every sample is identical
+func (v *MigrationVerification) OK() bool {
+	return v.SourceKeys == v.TargetKeys && len(v.Mismatches) == 0
+}
+
+// Verify compares the number of matching keys on both clusters and the
+// values of up to samples random source keys. Values are compared with DEBUG
+// DIGEST-VALUE; when DEBUG is disabled (enable-debug-command no) the DUMP
+// payloads are compared instead.
+func (m *DataMigration) Verify(ctx context.Context, samples int) (*MigrationVerification, error) {
+	v := &MigrationVerification{Method: "DEBUG DIGEST-VALUE"}
+	var err error
+	if v.SourceKeys, err = countKeys(ctx, m.source, m.opts.Match); err != nil {
+		return nil, fmt.Errorf("count source keys: %w", err)
+	}
+	if v.TargetKeys, err = countKeys(ctx, m.target, m.opts.Match); err != nil {
+		return nil, fmt.Errorf("count target keys: %w", err)
+	}
+
+	keys, err := m.sampleKeys(ctx, samples)
+	if err != nil {
+		return nil, err
+	}
+	v.Sampled = len(keys)
+
+	for _, key := range keys {
+		src, dst, err := valueDigests(ctx, m.source.rdb, m.target.rdb, key, v.Method)
+		if err != nil && v.Method != "DUMP" && strings.Contains(err.Error(), "DEBUG") {
+			v.Method = "DUMP"
+			src, dst, err = valueDigests(ctx, m.source.rdb, m.target.rdb, key, v.Method)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("compare %s: %w", key, err)
+		}
+		if src != dst {
+			v.Mismatches = append(v.Mismatches, key)
+		}
+	}
+	return v, nil
+}
+
+// valueDigests returns the value digest (or DUMP payload) of key on both
+// clusters; a missing key yields ""
+func valueDigests(ctx context.Context, source, target *redis.ClusterClient, key, method string) (string, string, error) {
+	get := func(rdb *redis.ClusterClient) (string, error) {
+		var res string
+		var err error
+		if method == "DUMP" {
+			res, err = rdb.Dump(ctx, key).Result()
+		} else {
+			var digest interface{}
+			digest, err = rdb.Do(ctx, "DEBUG", "DIGEST-VALUE", key).Result()
+			if vals, ok := digest.([]interface{}); ok && len(vals) == 1 {
+				res = fmt.Sprint(vals[0])
+			}
+		}
+		if err == redis.Nil {
+			return "", nil
+		}
+		return res, err
+	}
+	src, err := get(source)
+	if err != nil {
+		return "", "", err
+	}
+	dst, err := get(target)
+	return src, dst, err
+}
+
+// sampleKeys picks up to n random keys from the source masters. With a match
+// pattern the sample is drawn from the first SCAN pages instead of RANDOMKEY.
+func (m *DataMigration) sampleKeys(ctx context.Context, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	masters, err := m.source.backupMasters(m.opts.Match)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, n)
+	var keys []string
+	add := func(batch []string) {
+		for _, key := range batch {
+			if !seen[key] && len(keys) < n {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	if m.opts.Match != "" {
+		per := int64(n/len(masters) + 1)
+		for _, master := range masters {
+			batch, _, err := m.source.NodeClient(master.Address).Scan(ctx, 0, m.opts.Match, per).Result()
+			if err != nil {
+				return nil, err
+			}
+			add(batch)
+		}
+		return keys, nil
+	}
+
+	for attempt := 0; len(keys) < n && attempt < 3*n; attempt++ {
+		node := m.source.NodeClient(masters[rand.Intn(len(masters))].Address)
+		key, err := node.RandomKey(ctx).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		add([]string{key})
+	}
+	return keys, nil
+}
+
+// countKeys counts the keys on every master matching pattern: DBSIZE for all
+// keys, a full SCAN otherwise
+func countKeys(ctx context.Context, c *Client, match string) (int64, error) {
+	masters, err := c.backupMasters(match)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, master := range masters {
+		node := c.NodeClient(master.Address)
+		if match == "" {
+			n, err := node.DBSize(ctx).Result()
+			if err != nil {
+				return 0, err
+			}
+			total += n
+			continue
+		}
+		iter := node.Scan(ctx, 0, match, 1000).Iterator()
+		for iter.Next(ctx) {
+			total++
+		}
+		if err := iter.Err(); err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,435 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"ticket-reservation/models"
+)
+
+// CheckStatus is the outcome of a doctor check
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "PASS"
+	CheckWarn CheckStatus = "WARN"
+	CheckFail CheckStatus = "FAIL"
+)
+
+// severity orders statuses so the worst one can be picked
+func (s CheckStatus) severity() int {
+	switch s {
+	case CheckFail:
+		return 2
+	case CheckWarn:
+		return 1
+	}
+	return 0
+}
+
+// CheckResult is one doctor check
+type CheckResult struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+	Details []string    `json:"details,omitempty"`
+}
+
+// DoctorOptions tunes the doctor checks
+type DoctorOptions struct {
+	MinReplicas int // replicas each master with slots should have (default 1)
+}
+
+// DoctorReport is the result of Doctor
+type DoctorReport struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	Nodes     int           `json:"nodes"`
+	Results   []CheckResult `json:"results"`
+}
+
+// Worst returns the most severe status in the report
+func (r *DoctorReport) Worst() CheckStatus {
+	worst := CheckPass
+	for _, res := range r.Results {
+		if res.Status.severity() > worst.severity() {
+			worst = res.Status
+		}
+	}
+	return worst
+}
+
+// Count returns how many checks ended with status
+func (r *DoctorReport) Count(status CheckStatus) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// doctorNode is one node's own CLUSTER NODES output plus its config
+type doctorNode struct {
+	node    models.ClusterNode
+	view    []models.ClusterNode
+	self    models.ClusterNode // the node's own entry in view
+	owners  [TotalSlots]string
+	config  map[string]string
+	err     error
+	confErr error
+}
+
+// Doctor connects to every node and checks the cluster for consistency and
+// operational risks. It only returns an error if the cluster cannot be
+// reached at all; everything else is reported as check results.
+func (c *Client) Doctor(ctx context.Context, opts DoctorOptions) (*DoctorReport, error) {
+	if opts.MinReplicas <= 0 {
+		opts.MinReplicas = 1
+	}
+
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DoctorReport{CheckedAt: time.Now(), Nodes: len(nodes)}
+	dns := make([]*doctorNode, 0, len(nodes))
+	for _, n := range nodes {
+		dn := &doctorNode{node: n}
+		dn.view, dn.err = c.viewOf(ctx, n.Address)
+		if dn.err == nil {
+			for _, v := range dn.view {
+				if v.Myself {
+					dn.self = v
+				}
+				if v.Role != "master" {
+					continue
+				}
+				for _, r := range v.SlotRanges {
+					for s := r.Start; s <= r.End && s < TotalSlots; s++ {
+						dn.owners[s] = v.ID
+					}
+				}
+			}
+			dn.config, dn.confErr = c.nodeConfig(ctx, n.Address, "maxmemory-policy", "maxmemory", "appendonly", "save")
+		}
+		dns = append(dns, dn)
+	}
+
+	report.Results = append(report.Results,
+		checkReachability(dns),
+		checkSlotViews(dns),
+		checkCoverage(nodes, dns),
+		checkConfigEpochs(nodes),
+		checkReplicaCount(nodes, opts.MinReplicas),
+		checkReplicaHosts(nodes),
+		checkEvictionPolicy(dns),
+		checkPersistence(dns),
+	)
+	return report, nil
+}
+
+// nodeConfig reads CONFIG GET parameters from a single node
+func (c *Client) nodeConfig(ctx context.Context, addr string, params ...string) (map[string]string, error) {
+	config := make(map[string]string)
+	nc := c.NodeClient(addr)
+	for _, p := range params {
+		values, err := nc.ConfigGet(ctx, p).Result()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range values {
+			config[k] = v
+		}
+	}
+	return config, nil
+}
+
+func checkReachability(dns []*doctorNode) CheckResult {
+	res := CheckResult{Name: "Node reachability", Status: CheckPass}
+	for _, dn := range dns {
+		if dn.err != nil {
+			res.Details = append(res.Details, fmt.Sprintf("%s %s: %v", dn.node.Address, ShortID(dn.node.ID), dn.err))
+		}
+	}
+	if len(res.Details) > 0 {
+		res.Status = CheckFail
+		res.Message = fmt.Sprintf("%d of %d nodes unreachable", len(res.Details), len(dns))
+		return res
+	}
+	res.Message = fmt.Sprintf("all %d nodes answered", len(dns))
+	return res
+}
+
+func checkSlotViews(dns []*doctorNode) CheckResult {
+	res := CheckResult{Name: "Slot ownership agreement", Status: CheckPass}
+	var ref *doctorNode
+	for _, dn := range dns {
+		if dn.err != nil {
+			continue
+		}
+		if ref == nil {
+			ref = dn
+			continue
+		}
+		diff := 0
+		for s := 0; s < TotalSlots; s++ {
+			if dn.owners[s] != ref.owners[s] {
+				diff++
+			}
+		}
+		if diff > 0 {
+			res.Details = append(res.Details, fmt.Sprintf("%s disagrees with %s on %d slots", dn.node.Address, ref.node.Address, diff))
+		}
+	}
+	if ref == nil {
+		res.Status = CheckFail
+		res.Message = "no node view available"
+		return res
+	}
+	if len(res.Details) > 0 {
+		res.Status = CheckFail
+		res.Message = "nodes have different slot maps (run fix-migrations)"
+		return res
+	}
+	res.Message = "every node reports the same slot owners"
+	return res
+}
+
+func checkCoverage(nodes []models.ClusterNode, dns []*doctorNode) CheckResult {
+	res := CheckResult{Name: "Slot coverage", Status: CheckPass}
+	var covered [TotalSlots]bool
+	for _, n := range nodes {
+		if n.Role != "master" {
+			continue
+		}
+		for _, r := range n.SlotRanges {
+			for s := r.Start; s <= r.End && s < TotalSlots; s++ {
+				covered[s] = true
+			}
+		}
+	}
+	var missing []int
+	for s, ok := range covered {
+		if !ok {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) > 0 {
+		res.Status = CheckFail
+		res.Message = fmt.Sprintf("%d slots unassigned", len(missing))
+		for _, r := range CompactSlots(missing) {
+			res.Details = append(res.Details, "unassigned: "+r.String())
+		}
+		return res
+	}
+
+	// Migration markers are only reported by the node that holds them
+	selves := make([]models.ClusterNode, 0, len(dns))
+	for _, dn := range dns {
+		if dn.err == nil {
+			selves = append(selves, dn.self)
+		}
+	}
+	res.Message = fmt.Sprintf("all %d slots assigned", TotalSlots)
+	if migrations := FindMigrations(selves); len(migrations) > 0 {
+		res.Status = CheckWarn
+		res.Message += fmt.Sprintf(", %d migrations open", len(migrations))
+		for _, m := range migrations {
+			res.Details = append(res.Details, fmt.Sprintf("slot %d: %s -> %s", m.Slot, ShortID(m.SourceID), ShortID(m.TargetID)))
+		}
+	}
+	return res
+}
+
+func checkConfigEpochs(nodes []models.ClusterNode) CheckResult {
+	res := CheckResult{Name: "Config epochs", Status: CheckPass}
+	byEpoch := make(map[int64][]string)
+	for _, n := range nodes {
+		if n.Role == "master" && n.SlotCount() > 0 {
+			byEpoch[n.ConfigEpoch] = append(byEpoch[n.ConfigEpoch], n.Address)
+		}
+	}
+	for epoch, addrs := range byEpoch {
+		if len(addrs) > 1 {
+			sort.Strings(addrs)
+			res.Details = append(res.Details, fmt.Sprintf("epoch %d shared by %s", epoch, strings.Join(addrs, ", ")))
+		}
+	}
+	if len(res.Details) > 0 {
+		res.Status = CheckFail
+		res.Message = "masters share a config epoch"
+		return res
+	}
+	res.Message = "every master has a unique config epoch"
+	return res
+}
+
+func checkReplicaCount(nodes []models.ClusterNode, min int) CheckResult {
+	res := CheckResult{Name: fmt.Sprintf("Replicas per master (>= %d)", min), Status: CheckPass}
+	healthy := make(map[string]int)
+	for _, n := range nodes {
+		if n.Role == "replica" && !n.IsFailing() {
+			healthy[n.MasterID]++
+		}
+	}
+	for _, n := range nodes {
+		if n.Role != "master" || n.SlotCount() == 0 {
+			continue
+		}
+		count := healthy[n.ID]
+		if count >= min {
+			continue
+		}
+		res.Details = append(res.Details, fmt.Sprintf("%s %s has %d healthy replicas", n.Address, ShortID(n.ID), count))
+		if count == 0 {
+			res.Status = CheckFail
+		} else if res.Status != CheckFail {
+			res.Status = CheckWarn
+		}
+	}
+	switch res.Status {
+	case CheckFail:
+		res.Message = "some masters have no healthy replica"
+	case CheckWarn:
+		res.Message = "some masters have fewer replicas than required"
+	default:
+		res.Message = "every master has enough replicas"
+	}
+	return res
+}
+
+func checkReplicaHosts(nodes []models.ClusterNode) CheckResult {
+	res := CheckResult{Name: "Replica placement", Status: CheckPass}
+	byID := make(map[string]models.ClusterNode)
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+	replicas := make(map[string]int)  // master ID -> replicas
+	elsewhere := make(map[string]int) // master ID -> replicas on another host
+	for _, n := range nodes {
+		if n.Role != "replica" {
+			continue
+		}
+		master, ok := byID[n.MasterID]
+		if !ok {
+			continue
+		}
+		replicas[master.ID]++
+		if nodeHost(n) == nodeHost(master) {
+			res.Details = append(res.Details, fmt.Sprintf("%s replicates %s on the same host", n.Address, master.Address))
+			raise(&res, CheckWarn)
+		} else {
+			elsewhere[master.ID]++
+		}
+	}
+	for id, count := range replicas {
+		if count > 0 && elsewhere[id] == 0 && byID[id].SlotCount() > 0 {
+			raise(&res, CheckFail)
+		}
+	}
+
+	switch res.Status {
+	case CheckFail:
+		res.Message = "some masters have no replica on another host"
+	case CheckWarn:
+		res.Message = "some replicas share a host with their master"
+	default:
+		res.Message = "every replica runs on a different host than its master"
+	}
+	return res
+}
+
+// nodeHost returns the announced hostname or, failing that, the IP
+func nodeHost(n models.ClusterNode) string {
+	if n.Hostname != "" {
+		return n.Hostname
+	}
+	host, _, err := net.SplitHostPort(n.Address)
+	if err != nil {
+		return n.Address
+	}
+	return host
+}
+
+func checkEvictionPolicy(dns []*doctorNode) CheckResult {
+	res := CheckResult{Name: "maxmemory-policy", Status: CheckPass}
+	for _, dn := range dns {
+		if dn.err != nil || dn.node.Role != "master" {
+			continue
+		}
+		if dn.confErr != nil {
+			res.Details = append(res.Details, fmt.Sprintf("%s: CONFIG GET failed: %v", dn.node.Address, dn.confErr))
+			raise(&res, CheckWarn)
+			continue
+		}
+		policy := dn.config["maxmemory-policy"]
+		unlimited := dn.config["maxmemory"] == "0"
+		switch {
+		case strings.HasPrefix(policy, "allkeys-") && unlimited:
+			res.Details = append(res.Details, fmt.Sprintf("%s: %s (no maxmemory yet, evicts seat data once one is set)", dn.node.Address, policy))
+			raise(&res, CheckWarn)
+		case strings.HasPrefix(policy, "allkeys-"):
+			// Would evict seat hashes and confirmed reservations
+			res.Details = append(res.Details, fmt.Sprintf("%s: %s can evict seat data", dn.node.Address, policy))
+			raise(&res, CheckFail)
+		case strings.HasPrefix(policy, "volatile-"):
+			res.Details = append(res.Details, fmt.Sprintf("%s: %s can evict pending holds early", dn.node.Address, policy))
+			raise(&res, CheckWarn)
+		}
+	}
+	switch res.Status {
+	case CheckFail:
+		res.Message = "an allkeys-* policy may silently drop seat data"
+	case CheckWarn:
+		res.Message = "eviction policy could not be verified or affects keys with TTLs"
+	default:
+		res.Message = "no master evicts keys without TTL"
+	}
+	return res
+}
+
+func checkPersistence(dns []*doctorNode) CheckResult {
+	res := CheckResult{Name: "Persistence", Status: CheckPass}
+	for _, dn := range dns {
+		if dn.err != nil {
+			continue
+		}
+		if dn.confErr != nil {
+			res.Details = append(res.Details, fmt.Sprintf("%s: CONFIG GET failed: %v", dn.node.Address, dn.confErr))
+			raise(&res, CheckWarn)
+			continue
+		}
+		aof := dn.config["appendonly"] == "yes"
+		rdb := strings.TrimSpace(dn.config["save"]) != ""
+		switch {
+		case !aof && !rdb:
+			res.Details = append(res.Details, fmt.Sprintf("%s: no AOF and no RDB snapshots", dn.node.Address))
+			raise(&res, CheckFail)
+		case !aof:
+			res.Details = append(res.Details, fmt.Sprintf("%s: RDB only (save %q), recent writes lost on restart", dn.node.Address, dn.config["save"]))
+			raise(&res, CheckWarn)
+		}
+	}
+	switch res.Status {
+	case CheckFail:
+		res.Message = "some nodes persist nothing"
+	case CheckWarn:
+		res.Message = "some nodes rely on RDB snapshots only"
+	default:
+		res.Message = "AOF enabled on every node"
+	}
+	return res
+}
+
+// raise sets the result status if status is more severe
+func raise(res *CheckResult, status CheckStatus) {
+	if status.severity() > res.Status.severity() {
+		res.Status = status
+	}
+}
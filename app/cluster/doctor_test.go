@@ -0,0 +1,41 @@
+package cluster_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+)
+
+// The coverage check lists a migration that only its two ends report
+func TestDoctorReportsOpenMigration(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	m := beginMigrationAwayFromSeed(t, fc)
+
+	report, err := client.Doctor(context.Background(), cluster.DoctorOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range report.Results {
+		if res.Name != "Slot coverage" {
+			continue
+		}
+		if res.Status != cluster.CheckWarn || !strings.Contains(res.Message, "1 migrations open") {
+			t.Fatalf("coverage = %s: %s, want a warning for one open migration", res.Status, res.Message)
+		}
+		want := "slot " + strconv.Itoa(m.Slot) + ": " + cluster.ShortID(m.SourceID) + " -> " + cluster.ShortID(m.TargetID)
+		if len(res.Details) != 1 || res.Details[0] != want {
+			t.Fatalf("coverage details = %q, want [%q]", res.Details, want)
+		}
+		return
+	}
+	t.Fatal("no slot coverage check in the report")
+}
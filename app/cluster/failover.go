@@ -0,0 +1,267 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"ticket-reservation/models"
+)
+
+// FailoverMode selects the CLUSTER FAILOVER variant
+type FailoverMode string
+
+const (
+	FailoverDefault  FailoverMode = ""         // coordinated: master pauses clients, replica catches up
+	FailoverForce    FailoverMode = "FORCE"    // no handshake with the master (it may be down)
+	FailoverTakeover FailoverMode = "TAKEOVER" // no agreement from other masters either
+)
+
+// FailoverResult describes a completed manual failover
+type FailoverResult struct {
+	ReplicaID   string        `json:"replica_id"`
+	Replica     string        `json:"replica"`
+	OldMasterID string        `json:"old_master_id"`
+	OldMaster   string        `json:"old_master"`
+	Mode        FailoverMode  `json:"mode,omitempty"`
+	Promoted    time.Duration `json:"promoted_after"`  // replica reports itself master
+	Converged   time.Duration `json:"converged_after"` // every reachable node agrees
+}
+
+// Failover sends CLUSTER FAILOVER [FORCE|TAKEOVER] to replica and waits until
+// every reachable node sees it as the master of its former master's slots
+func (c *Client) Failover(ctx context.Context, replicaRef string, mode FailoverMode, timeout time.Duration) (*FailoverResult, error) {
+	replica, err := c.ResolveNode(replicaRef)
+	if err != nil {
+		return nil, err
+	}
+	if replica.Role != "replica" {
+		return nil, fmt.Errorf("%s is a %s; choose a replica to promote", replica.Address, replica.Role)
+	}
+	master, ok := c.slots.Node(replica.MasterID)
+	if !ok {
+		return nil, fmt.Errorf("master %s of %s is unknown", ShortID(replica.MasterID), replica.Address)
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	result := &FailoverResult{
+		ReplicaID:   replica.ID,
+		Replica:     replica.Address,
+		OldMasterID: master.ID,
+		OldMaster:   master.Address,
+		Mode:        mode,
+	}
+
+	args := []interface{}{"CLUSTER", "FAILOVER"}
+	if mode != FailoverDefault {
+		args = append(args, string(mode))
+	}
+	start := time.Now()
+	if err := c.NodeClient(replica.Address).Do(ctx, args...).Err(); err != nil {
+		return nil, fmt.Errorf("CLUSTER FAILOVER on %s: %w", replica.Address, err)
+	}
+
+	if err := waitFor(ctx, timeout, "replica promotion", func() (bool, error) {
+		view, err := c.viewOf(ctx, replica.Address)
+		if err != nil {
+			return false, err
+		}
+		for _, v := range view {
+			if v.Myself {
+				return v.Role == "master", nil
+			}
+		}
+		return false, nil
+	}); err != nil {
+		return result, err
+	}
+	result.Promoted = time.Since(start)
+
+	err = c.waitForConvergence(ctx, timeout, []string{replica.ID}, func(view []models.ClusterNode) bool {
+		for _, v := range view {
+			if v.ID == replica.ID && v.Role != "master" {
+				return false
+			}
+			if v.ID == master.ID && !v.IsFailing() && v.Role == "master" && v.SlotCount() > 0 {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Converged = time.Since(start)
+	c.slots.Invalidate()
+	return result, nil
+}
+
+// ProbeStats summarizes what clients experienced while a probe ran
+type ProbeStats struct {
+	Duration      time.Duration  `json:"duration"`
+	Writes        int64          `json:"writes"`
+	WriteErrors   int64          `json:"write_errors"`
+	Reads         int64          `json:"reads"`
+	ReadErrors    int64          `json:"read_errors"`
+	StaleReads    int64          `json:"stale_reads"` // read returned an older value than the last ack
+	Moved         int64          `json:"moved"`       // MOVED redirects followed by the client
+	Unavailable   time.Duration  `json:"unavailable"` // total time between a failed write and the next success
+	LongestOutage time.Duration  `json:"longest_outage"`
+	LostWrites    int            `json:"lost_writes"` // keys whose final value is older than their last ack
+	LostKeys      []string       `json:"lost_keys,omitempty"`
+	Errors        map[string]int `json:"errors,omitempty"` // error message -> count
+}
+
+// WriteProbe continuously writes increasing sequence numbers to a set of keys
+// and reads them back through the cluster client, tracking acknowledged
+// values so lost writes can be detected afterwards
+type WriteProbe struct {
+	client   *Client
+	keys     []string
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu          sync.Mutex
+	stats       ProbeStats
+	lastAck     map[string]int64
+	started     time.Time
+	outageStart time.Time
+	movedStart  int64
+}
+
+// ProbeKeysForNode returns n probe keys whose slots are owned by node, so the
+// probe exercises exactly the shard that is failing over
+func ProbeKeysForNode(node models.ClusterNode, n int) []string {
+	var keys []string
+	for i := 0; len(keys) < n && i < 1000000; i++ {
+		key := fmt.Sprintf("probe:failover:%d", i)
+		if node.OwnsSlot(KeySlot(key)) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// NewWriteProbe creates a probe over keys, writing every interval
+func NewWriteProbe(client *Client, keys []string, interval time.Duration) *WriteProbe {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WriteProbe{
+		client:   client,
+		keys:     keys,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+		lastAck:  make(map[string]int64),
+		stats:    ProbeStats{Errors: make(map[string]int)},
+	}
+}
+
+// Start begins writing and reading in the background
+func (p *WriteProbe) Start() {
+	p.started = time.Now()
+	p.movedStart = p.client.slots.MovedCount()
+	p.wg.Add(1)
+	go p.run()
+}
+
+func (p *WriteProbe) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	rdb := p.client.Redis()
+	var seq int64
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		seq++
+		key := p.keys[int(seq)%len(p.keys)]
+		ctx, cancel := context.WithTimeout(p.ctx, time.Second)
+		err := rdb.Set(ctx, key, seq, 0).Err()
+		now := time.Now()
+
+		p.mu.Lock()
+		p.stats.Writes++
+		if err != nil {
+			p.stats.WriteErrors++
+			p.stats.Errors[err.Error()]++
+			if p.outageStart.IsZero() {
+				p.outageStart = now
+			}
+		} else {
+			p.lastAck[key] = seq
+			p.endOutage(now)
+		}
+		expected := p.lastAck[key]
+		p.mu.Unlock()
+
+		val, err := rdb.Get(ctx, key).Int64()
+		cancel()
+
+		p.mu.Lock()
+		p.stats.Reads++
+		if err != nil {
+			p.stats.ReadErrors++
+			p.stats.Errors[err.Error()]++
+		} else if val < expected {
+			p.stats.StaleReads++
+		}
+		p.mu.Unlock()
+	}
+}
+
+// endOutage closes an open unavailability window; callers hold p.mu
+func (p *WriteProbe) endOutage(now time.Time) {
+	if p.outageStart.IsZero() {
+		return
+	}
+	outage := now.Sub(p.outageStart)
+	p.stats.Unavailable += outage
+	if outage > p.stats.LongestOutage {
+		p.stats.LongestOutage = outage
+	}
+	p.outageStart = time.Time{}
+}
+
+// Stop stops the probe, re-reads every key and returns the final statistics.
+// A key whose stored value is lower than its last acknowledged write lost data.
+func (p *WriteProbe) Stop() ProbeStats {
+	p.cancel()
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.endOutage(now)
+	p.stats.Duration = now.Sub(p.started)
+	p.stats.Moved = p.client.slots.MovedCount() - p.movedStart
+
+	rdb := p.client.Redis()
+	for key, acked := range p.lastAck {
+		val, err := rdb.Get(context.Background(), key).Result()
+		got, _ := strconv.ParseInt(val, 10, 64)
+		if err != nil || got < acked {
+			p.stats.LostWrites++
+			p.stats.LostKeys = append(p.stats.LostKeys, key)
+		}
+	}
+	return p.stats
+}
+
+// Cleanup deletes the probe keys
+func (p *WriteProbe) Cleanup() {
+	for _, key := range p.keys {
+		p.client.Redis().Del(context.Background(), key)
+	}
+}
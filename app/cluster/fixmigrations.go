@@ -0,0 +1,315 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"ticket-reservation/models"
+)
+
+// MigrationIssueKind classifies a stuck slot
+type MigrationIssueKind string
+
+const (
+	// IssueKeysOnSource: nothing reached the target yet, roll back to the source
+	IssueKeysOnSource MigrationIssueKind = "keys_on_source"
+	// IssueKeysOnBoth: the reshard died mid-slot, finish moving the keys
+	IssueKeysOnBoth MigrationIssueKind = "keys_on_both"
+	// IssueKeysOnTarget: every key already moved, only ownership is missing
+	IssueKeysOnTarget MigrationIssueKind = "keys_on_target"
+	// IssueNoKeys: the slot is empty on both sides, hand it to the target
+	IssueNoKeys MigrationIssueKind = "no_keys"
+	// IssueOwnerMismatch: masters disagree about who owns the slot
+	IssueOwnerMismatch MigrationIssueKind = "owner_mismatch"
+)
+
+// MigrationIssue is one stuck slot and the remediation chosen for it
+type MigrationIssue struct {
+	Slot       int                `json:"slot"`
+	Kind       MigrationIssueKind `json:"kind"`
+	SourceID   string             `json:"source_id,omitempty"`
+	TargetID   string             `json:"target_id,omitempty"`
+	SourceKeys int64              `json:"source_keys"`
+	TargetKeys int64              `json:"target_keys"`
+	Owners     map[string]string  `json:"owners,omitempty"` // master ID -> owner in that master's view
+	OwnerID    string             `json:"owner_id,omitempty"`
+	Action     string             `json:"action"`
+	Manual     bool               `json:"manual,omitempty"` // cannot be fixed automatically
+}
+
+// MigrationFixResult records what was done for an issue
+type MigrationFixResult struct {
+	MigrationIssue
+	Applied   bool   `json:"applied"`
+	KeysMoved int    `json:"keys_moved,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// MigrationReport is the JSON report written by fix-migrations
+type MigrationReport struct {
+	CheckedAt   time.Time            `json:"checked_at"`
+	Masters     int                  `json:"masters"`
+	Unreachable []string             `json:"unreachable,omitempty"`
+	Issues      []MigrationIssue     `json:"issues"`
+	Fixes       []MigrationFixResult `json:"fixes,omitempty"`
+}
+
+// masterView is one master's own CLUSTER NODES output
+type masterView struct {
+	self   models.ClusterNode
+	nodes  []models.ClusterNode
+	owners [TotalSlots]string
+}
+
+// FindStuckMigrations asks every master for its own view of the cluster and
+// returns open IMPORTING/MIGRATING slots plus slots whose owner differs
+// between views. Each issue carries the remediation FixMigration will apply.
+func (c *Client) FindStuckMigrations(ctx context.Context) (*MigrationReport, error) {
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &MigrationReport{CheckedAt: time.Now()}
+	var views []masterView
+	for _, n := range nodes {
+		if n.Role != "master" || n.Fail {
+			continue
+		}
+		report.Masters++
+		text, err := c.NodeClient(n.Address).ClusterNodes(ctx).Result()
+		if err != nil {
+			report.Unreachable = append(report.Unreachable, n.Address)
+			continue
+		}
+		view := masterView{nodes: ParseClusterNodes(text)}
+		for _, vn := range view.nodes {
+			if vn.Myself {
+				view.self = vn
+			}
+			if vn.Role != "master" {
+				continue
+			}
+			for _, r := range vn.SlotRanges {
+				for s := r.Start; s <= r.End && s < TotalSlots; s++ {
+					view.owners[s] = vn.ID
+				}
+			}
+		}
+		views = append(views, view)
+	}
+	if len(views) == 0 {
+		return nil, fmt.Errorf("no master could be queried")
+	}
+
+	byID := make(map[string]models.ClusterNode)
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	// Migration markers are only reported by the node that holds them, so
+	// merge each master's "myself" entry
+	selves := make([]models.ClusterNode, 0, len(views))
+	for _, v := range views {
+		selves = append(selves, v.self)
+	}
+	migrating := make(map[int]bool)
+	for _, m := range FindMigrations(selves) {
+		migrating[m.Slot] = true
+		issue, err := c.classifyMigration(ctx, m, byID)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	for slot := 0; slot < TotalSlots; slot++ {
+		if migrating[slot] {
+			continue
+		}
+		owners := make(map[string]string)
+		distinct := make(map[string]bool)
+		for _, v := range views {
+			owners[v.self.ID] = v.owners[slot]
+			distinct[v.owners[slot]] = true
+		}
+		if len(distinct) < 2 {
+			continue
+		}
+		issue, err := c.classifyOwnerMismatch(ctx, slot, owners, byID)
+		if err != nil {
+			return nil, err
+		}
+		report.Issues = append(report.Issues, issue)
+	}
+
+	sort.Slice(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Slot < report.Issues[j].Slot
+	})
+	return report, nil
+}
+
+// classifyMigration decides how to close an open migration based on where
+// the slot's keys currently live
+func (c *Client) classifyMigration(ctx context.Context, m models.SlotMigration, byID map[string]models.ClusterNode) (MigrationIssue, error) {
+	issue := MigrationIssue{Slot: m.Slot, SourceID: m.SourceID, TargetID: m.TargetID}
+	source, okS := byID[m.SourceID]
+	target, okT := byID[m.TargetID]
+	if !okS || !okT {
+		issue.Kind = IssueOwnerMismatch
+		issue.Manual = true
+		issue.Action = "source or target is no longer in the cluster; reassign the slot manually"
+		return issue, nil
+	}
+
+	var err error
+	if issue.SourceKeys, err = c.CountKeysInSlot(ctx, source, m.Slot); err != nil {
+		return issue, fmt.Errorf("failed to count keys of slot %d on %s: %w", m.Slot, source.Address, err)
+	}
+	if issue.TargetKeys, err = c.CountKeysInSlot(ctx, target, m.Slot); err != nil {
+		return issue, fmt.Errorf("failed to count keys of slot %d on %s: %w", m.Slot, target.Address, err)
+	}
+
+	switch {
+	case issue.SourceKeys > 0 && issue.TargetKeys == 0:
+		issue.Kind = IssueKeysOnSource
+		issue.OwnerID = source.ID
+		issue.Action = "SETSLOT STABLE on source and target (roll back)"
+	case issue.SourceKeys > 0:
+		issue.Kind = IssueKeysOnBoth
+		issue.OwnerID = target.ID
+		issue.Action = fmt.Sprintf("MIGRATE %d remaining keys, then SETSLOT NODE target on all masters", issue.SourceKeys)
+	case issue.TargetKeys > 0:
+		issue.Kind = IssueKeysOnTarget
+		issue.OwnerID = target.ID
+		issue.Action = "SETSLOT NODE target on all masters"
+	default:
+		issue.Kind = IssueNoKeys
+		issue.OwnerID = target.ID
+		issue.Action = "SETSLOT NODE target on all masters"
+	}
+	return issue, nil
+}
+
+// classifyOwnerMismatch picks the rightful owner of a slot the masters
+// disagree about: the only master holding keys for it, otherwise the owner
+// most views agree on (highest config epoch breaks ties)
+func (c *Client) classifyOwnerMismatch(ctx context.Context, slot int, owners map[string]string, byID map[string]models.ClusterNode) (MigrationIssue, error) {
+	issue := MigrationIssue{Slot: slot, Kind: IssueOwnerMismatch, Owners: owners}
+
+	votes := make(map[string]int)
+	var withKeys []string
+	for _, owner := range owners {
+		if owner != "" {
+			votes[owner]++
+		}
+	}
+	for id := range votes {
+		n, ok := byID[id]
+		if !ok {
+			continue
+		}
+		keys, err := c.CountKeysInSlot(ctx, n, slot)
+		if err != nil {
+			return issue, fmt.Errorf("failed to count keys of slot %d on %s: %w", slot, n.Address, err)
+		}
+		if keys > 0 {
+			withKeys = append(withKeys, id)
+		}
+	}
+
+	switch len(withKeys) {
+	case 0:
+		for id, v := range votes {
+			if _, ok := byID[id]; !ok {
+				continue
+			}
+			best := issue.OwnerID
+			if best == "" || v > votes[best] || (v == votes[best] && byID[id].ConfigEpoch > byID[best].ConfigEpoch) {
+				issue.OwnerID = id
+			}
+		}
+	case 1:
+		issue.OwnerID = withKeys[0]
+	default:
+		issue.Manual = true
+		issue.Action = fmt.Sprintf("%d masters hold keys for the slot; merge them manually", len(withKeys))
+		return issue, nil
+	}
+
+	if issue.OwnerID == "" {
+		issue.Manual = true
+		issue.Action = "no known master claims the slot; assign it manually"
+		return issue, nil
+	}
+	issue.Action = fmt.Sprintf("SETSLOT NODE %s on all masters", ShortID(issue.OwnerID))
+	return issue, nil
+}
+
+// FixMigration applies the remediation chosen for issue
+func (c *Client) FixMigration(ctx context.Context, issue MigrationIssue, opts MigrateOptions) MigrationFixResult {
+	result := MigrationFixResult{MigrationIssue: issue}
+	if issue.Manual {
+		result.Error = "manual intervention required"
+		return result
+	}
+
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	byID := make(map[string]models.ClusterNode)
+	var masters []models.ClusterNode
+	for _, n := range nodes {
+		byID[n.ID] = n
+		if n.Role == "master" {
+			masters = append(masters, n)
+		}
+	}
+
+	switch issue.Kind {
+	case IssueKeysOnSource:
+		for _, id := range []string{issue.SourceID, issue.TargetID} {
+			if err := c.SetSlotStable(ctx, byID[id], issue.Slot); err != nil {
+				result.Error = fmt.Sprintf("SETSLOT %d STABLE on %s: %v", issue.Slot, byID[id].Address, err)
+				return result
+			}
+		}
+	case IssueKeysOnBoth:
+		moved, err := c.MoveSlot(ctx, issue.Slot, byID[issue.SourceID], byID[issue.TargetID], masters, opts)
+		result.KeysMoved = moved
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	default:
+		if err := c.assignSlot(ctx, issue.Slot, byID[issue.OwnerID], masters); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+	}
+
+	result.Applied = true
+	c.slots.Invalidate()
+	return result
+}
+
+// assignSlot runs SETSLOT NODE owner on the owner first, then every other
+// master; this also clears any IMPORTING/MIGRATING state for the slot
+func (c *Client) assignSlot(ctx context.Context, slot int, owner models.ClusterNode, masters []models.ClusterNode) error {
+	if err := c.SetSlotNode(ctx, owner, slot, owner.ID); err != nil {
+		return fmt.Errorf("SETSLOT %d NODE on %s: %w", slot, owner.Address, err)
+	}
+	for _, m := range masters {
+		if m.ID == owner.ID || m.IsFailing() {
+			continue
+		}
+		if err := c.SetSlotNode(ctx, m, slot, owner.ID); err != nil {
+			return fmt.Errorf("SETSLOT %d NODE on %s: %w", slot, m.Address, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,483 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// maxSampledKeys bounds the keys a KeySampler tracks; beyond it keys seen
+// only once are dropped
+const maxSampledKeys = 100000
+
+// KeyHits is the sampled traffic of one key
+type KeyHits struct {
+	Key  string `json:"key"`
+	Node string `json:"node"` // node that last answered for the key
+	Hits int64  `json:"hits"` // sampled commands; divide by the rate for an estimate
+}
+
+// KeySamples is a snapshot of a KeySampler
+type KeySamples struct {
+	Since    time.Time        `json:"since"`
+	Rate     float64          `json:"rate"`
+	Sampled  int64            `json:"sampled"` // sampled key accesses
+	Keys     []KeyHits        `json:"keys"`    // most hits first
+	ByNode   map[string]int64 `json:"by_node"`
+	Commands map[string]int64 `json:"commands"`
+}
+
+// KeySampler counts the keys of a random sample of the commands the cluster
+// clients send. Like the RedirectTracer it is installed as a hook on every
+// node connection; it costs nothing until a sample rate is set.
+type KeySampler struct {
+	rate atomic.Uint64 // math.Float64bits of the sample rate, 0 = off
+
+	mu       sync.Mutex
+	since    time.Time
+	sampled  int64
+	keys     map[string]*KeyHits
+	byNode   map[string]int64
+	commands map[string]int64
+}
+
+// NewKeySampler creates a disabled sampler
+func NewKeySampler() *KeySampler {
+	s := &KeySampler{}
+	s.Reset()
+	return s
+}
+
+// SetRate samples the given fraction of commands (0 disables, 1 samples all)
+func (s *KeySampler) SetRate(rate float64) {
+	s.rate.Store(math.Float64bits(math.Max(0, math.Min(1, rate))))
+}
+
+// Rate returns the current sample rate
+func (s *KeySampler) Rate() float64 {
+	return math.Float64frombits(s.rate.Load())
+}
+
+// Reset clears all counters
+func (s *KeySampler) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.since = time.Now()
+	s.sampled = 0
+	s.keys = make(map[string]*KeyHits)
+	s.byNode = make(map[string]int64)
+	s.commands = make(map[string]int64)
+}
+
+// Snapshot returns a copy of the counters with up to top keys (0 = all)
+func (s *KeySampler) Snapshot(top int) KeySamples {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := KeySamples{
+		Since:    s.since,
+		Rate:     s.Rate(),
+		Sampled:  s.sampled,
+		Keys:     make([]KeyHits, 0, len(s.keys)),
+		ByNode:   make(map[string]int64, len(s.byNode)),
+		Commands: make(map[string]int64, len(s.commands)),
+	}
+	for _, h := range s.keys {
+		out.Keys = append(out.Keys, *h)
+	}
+	sort.Slice(out.Keys, func(i, j int) bool {
+		a, b := out.Keys[i], out.Keys[j]
+		return a.Hits > b.Hits || a.Hits == b.Hits && a.Key < b.Key
+	})
+	if top > 0 && len(out.Keys) > top {
+		out.Keys = out.Keys[:top]
+	}
+	for k, v := range s.byNode {
+		out.ByNode[k] = v
+	}
+	for k, v := range s.commands {
+		out.Commands[k] = v
+	}
+	return out
+}
+
+// observe records one command answered by node if it falls in the sample
+func (s *KeySampler) observe(node string, cmd redis.Cmder) {
+	rate := s.Rate()
+	if rate == 0 || rate < 1 && rand.Float64() >= rate {
+		return
+	}
+	// The redirected attempt is counted on the node that finally answers
+	if _, _, _, redirected := parseRedirect(cmd.Err()); redirected {
+		return
+	}
+	keys := commandKeys(cmd)
+	if len(keys) == 0 {
+		return
+	}
+
+	name := strings.ToUpper(cmd.Name())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		h := s.keys[key]
+		if h == nil {
+			if len(s.keys) >= maxSampledKeys {
+				s.pruneLocked()
+			}
+			h = &KeyHits{Key: key}
+			s.keys[key] = h
+		}
+		h.Node = node
+		h.Hits++
+		s.sampled++
+		s.byNode[node]++
+		s.commands[name]++
+	}
+}
+
+// pruneLocked drops the keys seen only once to make room for new ones
+func (s *KeySampler) pruneLocked() {
+	for key, h := range s.keys {
+		if h.Hits <= 1 {
+			delete(s.keys, key)
+		}
+	}
+}
+
+// commandKeys returns the key arguments of the common data commands; admin
+// commands without keys return nil
+func commandKeys(cmd redis.Cmder) []string {
+	args := cmd.Args()
+	strs := func(from, step int) []string {
+		var keys []string
+		for i := from; i < len(args); i += step {
+			if k, ok := args[i].(string); ok {
+				keys = append(keys, k)
+			}
+		}
+		return keys
+	}
+	switch strings.ToLower(cmd.Name()) {
+	case "ping", "echo", "hello", "auth", "client", "select", "readonly", "readwrite",
+		"multi", "exec", "discard", "unwatch", "info", "config", "dbsize", "flushall",
+		"flushdb", "time", "cluster", "debug", "scan", "script", "function", "command",
+		"publish", "subscribe", "psubscribe", "ssubscribe", "wait", "keys", "randomkey":
+		return nil
+	case "mget", "del", "unlink", "exists", "touch", "watch":
+		return strs(1, 1)
+	case "mset", "msetnx":
+		return strs(1, 2)
+	case "eval", "evalsha", "eval_ro", "evalsha_ro", "fcall", "fcall_ro":
+		if len(args) < 3 {
+			return nil
+		}
+		n, err := strconv.Atoi(fmt.Sprint(args[2]))
+		if err != nil || n <= 0 || 3+n > len(args) {
+			return nil
+		}
+		args = args[:3+n]
+		return strs(3, 1)
+	case "memory", "object":
+		if len(args) > 2 {
+			return strs(2, len(args))
+		}
+		return nil
+	}
+	if len(args) > 1 {
+		return strs(1, len(args))
+	}
+	return nil
+}
+
+// hook returns the go-redis hook for the connection to node
+func (s *KeySampler) hook(node string) redis.Hook {
+	return samplerHook{sampler: s, node: node}
+}
+
+// samplerHook feeds every command a node answers into a KeySampler
+type samplerHook struct {
+	sampler *KeySampler
+	node    string
+}
+
+func (h samplerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h samplerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		h.sampler.observe(h.node, cmd)
+		return err
+	}
+}
+
+func (h samplerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			h.sampler.observe(h.node, cmd)
+		}
+		return err
+	}
+}
+
+// KeySampler returns the sampler shared by the client's cluster connections
+func (c *Client) KeySampler() *KeySampler {
+	return c.sampler
+}
+
+// HotKeyOptions controls DetectHotKeys
+type HotKeyOptions struct {
+	Window      time.Duration // how long client traffic is sampled
+	SampleRate  float64       // fraction of commands sampled, 0 = 1
+	Match       string        // SCAN MATCH pattern for OBJECT FREQ sampling, "" = every key
+	KeysPerNode int           // keys checked with OBJECT FREQ per LFU master, 0 = none
+	Top         int           // keys and slots to keep
+}
+
+// HotKey is one key ranked by access rate
+type HotKey struct {
+	Key     string  `json:"key"`
+	Node    string  `json:"node"`
+	Slot    int     `json:"slot"`
+	Rate    float64 `json:"rate"`            // estimated client ops/sec from sampled traffic
+	Share   float64 `json:"share"`           // percent of the node's ops/sec
+	LFUFreq int64   `json:"lfu_freq"`        // OBJECT FREQ counter, -1 when not measured
+	Event   string  `json:"event,omitempty"` // event ID when the key carries an {event:ID} hash tag
+}
+
+// HotSlot aggregates the hot keys of one slot
+type HotSlot struct {
+	Slot  int     `json:"slot"`
+	Node  string  `json:"node"`
+	Keys  int     `json:"keys"`
+	Rate  float64 `json:"rate"`
+	Share float64 `json:"share"`
+}
+
+// NodeOps is one master's command rate during the window
+type NodeOps struct {
+	Addr       string  `json:"addr"`
+	OpsPerSec  float64 `json:"ops_per_sec"` // all clients, from total_commands_processed
+	ClientRate float64 `json:"client_rate"` // this process, from sampled traffic
+	Policy     string  `json:"policy"`      // maxmemory-policy
+	LFU        bool    `json:"lfu"`         // OBJECT FREQ is available
+	Error      string  `json:"error,omitempty"`
+}
+
+// HotKeyReport is the result of DetectHotKeys
+type HotKeyReport struct {
+	Time       time.Time `json:"time"`
+	Window     Duration  `json:"window"`
+	SampleRate float64   `json:"sample_rate"`
+	Sampled    int64     `json:"sampled"`
+	Nodes      []NodeOps `json:"nodes"`
+	Keys       []HotKey  `json:"keys"`
+	Slots      []HotSlot `json:"slots"`
+}
+
+// DetectHotKeys samples the client traffic of this process for opts.Window
+// (ending early if ctx is cancelled), measures every master's ops/sec over
+// the same window and, on masters with an LFU maxmemory-policy, reads
+// OBJECT FREQ of up to opts.KeysPerNode keys. Keys are ranked by estimated
+// client rate, then by LFU counter.
+func (c *Client) DetectHotKeys(ctx context.Context, opts HotKeyOptions) (*HotKeyReport, error) {
+	if opts.SampleRate <= 0 {
+		opts.SampleRate = 1
+	}
+	if opts.Top <= 0 {
+		opts.Top = 20
+	}
+	if opts.Match == "" {
+		opts.Match = "*"
+	}
+
+	before, err := c.masterCommandCounts()
+	if err != nil {
+		return nil, err
+	}
+	prevRate := c.sampler.Rate()
+	c.sampler.Reset()
+	c.sampler.SetRate(opts.SampleRate)
+	start := time.Now()
+	select {
+	case <-ctx.Done():
+	case <-time.After(opts.Window):
+	}
+	samples := c.sampler.Snapshot(0)
+	c.sampler.SetRate(prevRate)
+	elapsed := time.Since(start).Seconds()
+	after, err := c.masterCommandCounts()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &HotKeyReport{
+		Time:       time.Now(),
+		Window:     Duration(time.Since(start).Round(time.Millisecond)),
+		SampleRate: opts.SampleRate,
+		Sampled:    samples.Sampled,
+	}
+	nodeOps := make(map[string]float64)
+	for addr, a := range after {
+		n := NodeOps{Addr: addr, Policy: a.policy, LFU: strings.Contains(a.policy, "lfu"), Error: a.err}
+		if b, ok := before[addr]; ok && a.err == "" && b.err == "" && elapsed > 0 {
+			n.OpsPerSec = float64(a.commands-b.commands) / elapsed
+		}
+		n.ClientRate = float64(samples.ByNode[addr]) / opts.SampleRate / elapsed
+		nodeOps[addr] = n.OpsPerSec
+		report.Nodes = append(report.Nodes, n)
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Addr < report.Nodes[j].Addr })
+
+	keys := make(map[string]*HotKey)
+	for _, h := range samples.Keys {
+		keys[h.Key] = &HotKey{Key: h.Key, Node: h.Node, Rate: float64(h.Hits) / opts.SampleRate / elapsed, LFUFreq: -1}
+	}
+	if opts.KeysPerNode > 0 {
+		for _, n := range report.Nodes {
+			if !n.LFU {
+				continue
+			}
+			freqs, err := c.lfuFrequencies(ctx, n.Addr, opts.Match, opts.KeysPerNode)
+			if err != nil {
+				return nil, err
+			}
+			for key, freq := range freqs {
+				k := keys[key]
+				if k == nil {
+					k = &HotKey{Key: key, Node: n.Addr}
+					keys[key] = k
+				}
+				k.LFUFreq = freq
+			}
+		}
+	}
+
+	slots := make(map[int]*HotSlot)
+	for _, k := range keys {
+		k.Slot = KeySlot(k.Key)
+		if ops := nodeOps[k.Node]; ops > 0 {
+			k.Share = math.Min(100, k.Rate/ops*100)
+		}
+		if tag := HashTag(k.Key); tag != k.Key && strings.HasPrefix(tag, "event:") {
+			k.Event = strings.TrimPrefix(tag, "event:")
+		}
+		report.Keys = append(report.Keys, *k)
+
+		s := slots[k.Slot]
+		if s == nil {
+			s = &HotSlot{Slot: k.Slot, Node: k.Node}
+			slots[k.Slot] = s
+		}
+		s.Keys++
+		s.Rate += k.Rate
+	}
+	sort.Slice(report.Keys, func(i, j int) bool {
+		a, b := report.Keys[i], report.Keys[j]
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		if a.LFUFreq != b.LFUFreq {
+			return a.LFUFreq > b.LFUFreq
+		}
+		return a.Key < b.Key
+	})
+	if len(report.Keys) > opts.Top {
+		report.Keys = report.Keys[:opts.Top]
+	}
+
+	for _, s := range slots {
+		if s.Rate == 0 {
+			continue
+		}
+		if ops := nodeOps[s.Node]; ops > 0 {
+			s.Share = math.Min(100, s.Rate/ops*100)
+		}
+		report.Slots = append(report.Slots, *s)
+	}
+	sort.Slice(report.Slots, func(i, j int) bool { return report.Slots[i].Rate > report.Slots[j].Rate })
+	if len(report.Slots) > opts.Top {
+		report.Slots = report.Slots[:opts.Top]
+	}
+	return report, nil
+}
+
+type commandCount struct {
+	commands int64
+	policy   string
+	err      string
+}
+
+// masterCommandCounts reads total_commands_processed and the
+// maxmemory-policy of every master
+func (c *Client) masterCommandCounts() (map[string]commandCount, error) {
+	var mu sync.Mutex
+	counts := make(map[string]commandCount)
+	err := c.ForEachMaster(func(node *redis.Client) error {
+		addr := node.Options().Addr
+		var cc commandCount
+		raw, err := node.Info(c.ctx, "stats").Result()
+		if err != nil {
+			cc.err = err.Error()
+		} else {
+			cc.commands = infoInt(ParseInfo(raw)["stats"], "total_commands_processed")
+		}
+		if policy, err := node.ConfigGet(c.ctx, "maxmemory-policy").Result(); err == nil {
+			cc.policy = policy["maxmemory-policy"]
+		}
+		mu.Lock()
+		counts[addr] = cc
+		mu.Unlock()
+		return nil
+	})
+	return counts, err
+}
+
+// lfuFrequencies reads OBJECT FREQ for up to limit keys of one master. The
+// counter is logarithmic: 255 is the hottest a key can get.
+func (c *Client) lfuFrequencies(ctx context.Context, addr, match string, limit int) (map[string]int64, error) {
+	nc := c.NodeClient(addr)
+	var keys []string
+	iter := nc.Scan(ctx, 0, match, memoryBatch).Iterator()
+	for len(keys) < limit && iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", addr, err)
+	}
+
+	freqs := make(map[string]int64, len(keys))
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > memoryBatch {
+			batch = batch[:memoryBatch]
+		}
+		keys = keys[len(batch):]
+
+		pipe := nc.Pipeline()
+		cmds := make([]*redis.Cmd, len(batch))
+		for j, key := range batch {
+			cmds[j] = pipe.Do(ctx, "OBJECT", "FREQ", key)
+		}
+		if _, err := pipe.Exec(ctx); err != nil && !keyGone(err) {
+			return nil, fmt.Errorf("OBJECT FREQ on %s: %w", addr, err)
+		}
+		for j, cmd := range cmds {
+			if freq, err := cmd.Int64(); err == nil {
+				freqs[batch[j]] = freq
+			}
+		}
+	}
+	return freqs, nil
+}
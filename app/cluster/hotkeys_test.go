@@ -0,0 +1,75 @@
+package cluster_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+)
+
+func TestDetectHotKeys(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	rdb := client.Redis()
+	const hot, cold = "{event:hot}:seats", "reservation:cold"
+	for _, key := range []string{hot, cold} {
+		if err := rdb.HSet(ctx, key, "A1", "available").Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.ForEachMaster(func(c *redis.Client) error {
+		return c.ConfigSet(ctx, "maxmemory-policy", "allkeys-lfu").Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	loadCtx, stop := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; loadCtx.Err() == nil; i++ {
+			rdb.HGet(ctx, hot, "A1")
+			if i%10 == 0 {
+				rdb.HGet(ctx, cold, "A1")
+			}
+		}
+	}()
+	report, err := client.DetectHotKeys(ctx, cluster.HotKeyOptions{
+		Window:      300 * time.Millisecond,
+		KeysPerNode: 100,
+	})
+	stop()
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Keys) != 2 {
+		t.Fatalf("keys = %+v, want the hot and the cold key", report.Keys)
+	}
+	top := report.Keys[0]
+	if top.Key != hot || top.Event != "hot" || top.Node != fc.NodeForKey(hot).Addr() {
+		t.Fatalf("hottest key = %+v", top)
+	}
+	if top.Rate <= report.Keys[1].Rate*5 || top.LFUFreq <= 0 || top.Share <= 0 {
+		t.Fatalf("hot key %+v not clearly ahead of %+v", top, report.Keys[1])
+	}
+	if report.Slots[0].Slot != cluster.KeySlot(hot) {
+		t.Fatalf("hottest slot = %+v", report.Slots[0])
+	}
+	if client.KeySampler().Rate() != 0 {
+		t.Fatal("sampler left enabled after the window")
+	}
+}
@@ -0,0 +1,285 @@
+package cluster
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ParseInfo splits INFO output into sections ("server", "memory", ...) of
+// field/value pairs. Section names are lower-cased.
+func ParseInfo(raw string) map[string]map[string]string {
+	sections := make(map[string]map[string]string)
+	current := make(map[string]string)
+	sections[""] = current
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			current = make(map[string]string)
+			sections[name] = current
+			continue
+		}
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			current[k] = v
+		}
+	}
+	return sections
+}
+
+// ServerInfo is the INFO server section
+type ServerInfo struct {
+	Version       string `json:"version"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+}
+
+// ClientsInfo is the INFO clients section
+type ClientsInfo struct {
+	Connected int64 `json:"connected"`
+	Blocked   int64 `json:"blocked"`
+}
+
+// MemoryInfo is the INFO memory section
+type MemoryInfo struct {
+	Used          int64   `json:"used"`
+	Peak          int64   `json:"peak"`
+	MaxMemory     int64   `json:"maxmemory"` // 0 = unlimited
+	Policy        string  `json:"policy"`
+	Fragmentation float64 `json:"fragmentation_ratio"`
+}
+
+// UsedPercent returns used memory as a percentage of maxmemory, or -1 when
+// maxmemory is unlimited
+func (m MemoryInfo) UsedPercent() float64 {
+	if m.MaxMemory <= 0 {
+		return -1
+	}
+	return float64(m.Used) * 100 / float64(m.MaxMemory)
+}
+
+// StatsInfo is the INFO stats section
+type StatsInfo struct {
+	OpsPerSec      int64   `json:"ops_per_sec"`
+	TotalCommands  int64   `json:"total_commands"`
+	KeyspaceHits   int64   `json:"keyspace_hits"`
+	KeyspaceMisses int64   `json:"keyspace_misses"`
+	EvictedKeys    int64   `json:"evicted_keys"`
+	ExpiredKeys    int64   `json:"expired_keys"`
+	InputKbps      float64 `json:"input_kbps"`
+	OutputKbps     float64 `json:"output_kbps"`
+}
+
+// HitRate returns keyspace hits as a percentage of lookups, or -1 before the
+// first lookup
+func (s StatsInfo) HitRate() float64 {
+	total := s.KeyspaceHits + s.KeyspaceMisses
+	if total == 0 {
+		return -1
+	}
+	return float64(s.KeyspaceHits) * 100 / float64(total)
+}
+
+// ReplicaInfo is one slaveN line of a master's INFO replication
+type ReplicaInfo struct {
+	Addr     string `json:"addr"`
+	State    string `json:"state"`
+	Offset   int64  `json:"offset"`
+	LagSecs  int64  `json:"lag_seconds"` // seconds since the last ACK
+	LagBytes int64  `json:"lag_bytes"`   // master_repl_offset minus the replica's offset
+}
+
+// ReplicationInfo is the INFO replication section
+type ReplicationInfo struct {
+	Role             string        `json:"role"` // "master" or "slave"
+	MasterReplOffset int64         `json:"master_repl_offset"`
+	Replicas         []ReplicaInfo `json:"replicas,omitempty"` // masters only
+
+	// Replicas only
+	MasterAddr       string `json:"master_addr,omitempty"`
+	MasterLinkStatus string `json:"master_link_status,omitempty"`
+	MasterLastIO     int64  `json:"master_last_io_seconds,omitempty"`
+	SlaveReplOffset  int64  `json:"slave_repl_offset,omitempty"`
+}
+
+// IsReplica reports whether the node replicates from a master
+func (r ReplicationInfo) IsReplica() bool {
+	return r.Role == "slave"
+}
+
+// KeyspaceInfo sums the keys of every db in INFO keyspace
+type KeyspaceInfo struct {
+	Keys    int64 `json:"keys"`
+	Expires int64 `json:"expires"`
+}
+
+// NodeInfo is the typed INFO of a single node
+type NodeInfo struct {
+	Addr        string          `json:"addr"`
+	Time        time.Time       `json:"time"`
+	Error       string          `json:"error,omitempty"`
+	Server      ServerInfo      `json:"server"`
+	Clients     ClientsInfo     `json:"clients"`
+	Memory      MemoryInfo      `json:"memory"`
+	Stats       StatsInfo       `json:"stats"`
+	Replication ReplicationInfo `json:"replication"`
+	Keyspace    KeyspaceInfo    `json:"keyspace"`
+
+	// LagBytes is how far this replica is behind its master's offset, as
+	// reported by the master; -1 for masters or when the master is unknown
+	LagBytes int64 `json:"lag_bytes"`
+}
+
+// ParseNodeInfo parses the output of a plain INFO command
+func ParseNodeInfo(addr, raw string) *NodeInfo {
+	s := ParseInfo(raw)
+	info := &NodeInfo{Addr: addr, Time: time.Now(), LagBytes: -1}
+
+	server := s["server"]
+	info.Server = ServerInfo{
+		Version:       server["redis_version"],
+		UptimeSeconds: infoInt(server, "uptime_in_seconds"),
+	}
+
+	clients := s["clients"]
+	info.Clients = ClientsInfo{
+		Connected: infoInt(clients, "connected_clients"),
+		Blocked:   infoInt(clients, "blocked_clients"),
+	}
+
+	memory := s["memory"]
+	info.Memory = MemoryInfo{
+		Used:          infoInt(memory, "used_memory"),
+		Peak:          infoInt(memory, "used_memory_peak"),
+		MaxMemory:     infoInt(memory, "maxmemory"),
+		Policy:        memory["maxmemory_policy"],
+		Fragmentation: infoFloat(memory, "mem_fragmentation_ratio"),
+	}
+
+	stats := s["stats"]
+	info.Stats = StatsInfo{
+		OpsPerSec:      infoInt(stats, "instantaneous_ops_per_sec"),
+		TotalCommands:  infoInt(stats, "total_commands_processed"),
+		KeyspaceHits:   infoInt(stats, "keyspace_hits"),
+		KeyspaceMisses: infoInt(stats, "keyspace_misses"),
+		EvictedKeys:    infoInt(stats, "evicted_keys"),
+		ExpiredKeys:    infoInt(stats, "expired_keys"),
+		InputKbps:      infoFloat(stats, "instantaneous_input_kbps"),
+		OutputKbps:     infoFloat(stats, "instantaneous_output_kbps"),
+	}
+
+	info.Replication = parseReplication(s["replication"])
+
+	for k, v := range s["keyspace"] {
+		if !strings.HasPrefix(k, "db") {
+			continue
+		}
+		fields := infoFields(v)
+		info.Keyspace.Keys += infoInt(fields, "keys")
+		info.Keyspace.Expires += infoInt(fields, "expires")
+	}
+	return info
+}
+
+// parseReplication parses the INFO replication section
+func parseReplication(section map[string]string) ReplicationInfo {
+	r := ReplicationInfo{
+		Role:             section["role"],
+		MasterReplOffset: infoInt(section, "master_repl_offset"),
+	}
+	if r.IsReplica() {
+		r.MasterAddr = net.JoinHostPort(section["master_host"], section["master_port"])
+		r.MasterLinkStatus = section["master_link_status"]
+		r.MasterLastIO = infoInt(section, "master_last_io_seconds_ago")
+		r.SlaveReplOffset = infoInt(section, "slave_repl_offset")
+	}
+
+	for k, v := range section {
+		if !strings.HasPrefix(k, "slave") {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimPrefix(k, "slave")); err != nil {
+			continue // slave_repl_offset, slave_priority, ...
+		}
+		fields := infoFields(v)
+		replica := ReplicaInfo{
+			Addr:    net.JoinHostPort(fields["ip"], fields["port"]),
+			State:   fields["state"],
+			Offset:  infoInt(fields, "offset"),
+			LagSecs: infoInt(fields, "lag"),
+		}
+		replica.LagBytes = r.MasterReplOffset - replica.Offset
+		r.Replicas = append(r.Replicas, replica)
+	}
+	sort.Slice(r.Replicas, func(i, j int) bool { return r.Replicas[i].Addr < r.Replicas[j].Addr })
+	return r
+}
+
+// infoFields splits "ip=1.2.3.4,port=6379,state=online" into a map
+func infoFields(v string) map[string]string {
+	fields := make(map[string]string)
+	for _, kv := range strings.Split(v, ",") {
+		if k, val, ok := strings.Cut(kv, "="); ok {
+			fields[k] = val
+		}
+	}
+	return fields
+}
+
+func infoInt(m map[string]string, key string) int64 {
+	n, _ := strconv.ParseInt(m[key], 10, 64)
+	return n
+}
+
+func infoFloat(m map[string]string, key string) float64 {
+	f, _ := strconv.ParseFloat(m[key], 64)
+	return f
+}
+
+// NodeInfos reads INFO from every master and replica in parallel. A node
+// that fails to answer is returned with Error set instead of failing the
+// whole call. Replicas get LagBytes from their master's view of them.
+func (c *Client) NodeInfos(ctx context.Context) ([]*NodeInfo, error) {
+	var mu sync.Mutex
+	var infos []*NodeInfo
+	err := c.rdb.ForEachShard(ctx, func(ctx context.Context, node *redis.Client) error {
+		addr := node.Options().Addr
+		raw, err := node.Info(ctx).Result()
+		info := &NodeInfo{Addr: addr, Time: time.Now(), LagBytes: -1}
+		if err != nil {
+			info.Error = err.Error()
+		} else {
+			info = ParseNodeInfo(addr, raw)
+		}
+		mu.Lock()
+		infos = append(infos, info)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byAddr := make(map[string]*NodeInfo, len(infos))
+	for _, info := range infos {
+		byAddr[info.Addr] = info
+	}
+	for _, info := range infos {
+		for _, r := range info.Replication.Replicas {
+			if replica, ok := byAddr[r.Addr]; ok {
+				replica.LagBytes = r.LagBytes
+			}
+		}
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Addr < infos[j].Addr })
+	return infos, nil
+}
@@ -0,0 +1,390 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// KeyClass groups keys matching a glob pattern under a name in a memory
+// report
+type KeyClass struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// MemoryReportOptions controls what MemoryReport samples and when it warns
+type MemoryReportOptions struct {
+	Match       string     // SCAN MATCH pattern, "" = every key
+	KeysPerNode int        // keys sampled per master, 0 = all
+	Samples     int        // MEMORY USAGE SAMPLES for nested values, 0 = server default
+	Top         int        // largest keys, slots and hash tags to keep
+	Classes     []KeyClass // prefix groups, first match wins
+	TagShare    float64    // warn when one hash tag holds more than this percent of a master's sampled bytes
+	TagMinBytes int64      // ... and at least this many bytes, so tiny clusters stay quiet
+	BigKey      int64      // warn about single keys larger than this many bytes, 0 = off
+}
+
+// KeyMemory is the measured size of one key
+type KeyMemory struct {
+	Key      string `json:"key"`
+	Node     string `json:"node"`
+	Slot     int    `json:"slot"`
+	Type     string `json:"type"`
+	Encoding string `json:"encoding"`
+	Bytes    int64  `json:"bytes"`
+	Elements int64  `json:"elements"` // fields, members, entries or string length
+	Class    string `json:"class"`
+}
+
+// MemoryGroup aggregates the sampled keys of one key class
+type MemoryGroup struct {
+	Name      string           `json:"name"`
+	Keys      int64            `json:"keys"`
+	Bytes     int64            `json:"bytes"`
+	Elements  int64            `json:"elements"`
+	Largest   string           `json:"largest"`
+	MaxBytes  int64            `json:"max_bytes"`
+	Encodings map[string]int64 `json:"encodings"` // encoding -> key count
+}
+
+// SlotMemory aggregates the sampled keys of one slot
+type SlotMemory struct {
+	Slot  int    `json:"slot"`
+	Node  string `json:"node"`
+	Keys  int64  `json:"keys"`
+	Bytes int64  `json:"bytes"`
+}
+
+// TagMemory aggregates the sampled keys sharing a hash tag on one master
+type TagMemory struct {
+	Tag   string  `json:"tag"`
+	Node  string  `json:"node"`
+	Keys  int64   `json:"keys"`
+	Bytes int64   `json:"bytes"`
+	Share float64 `json:"share"` // percent of the master's sampled bytes
+}
+
+// NodeMemory is one master's sampled total next to what the server reports
+type NodeMemory struct {
+	Addr       string `json:"addr"`
+	Keys       int64  `json:"keys"` // DBSIZE
+	Sampled    int64  `json:"sampled"`
+	Bytes      int64  `json:"bytes"`     // sum of MEMORY USAGE over the sample
+	Estimated  int64  `json:"estimated"` // Bytes scaled up to Keys
+	UsedMemory int64  `json:"used_memory"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MemoryReport is the result of MemoryReport
+type MemoryReport struct {
+	Time     time.Time     `json:"time"`
+	Match    string        `json:"match"`
+	Sampled  int64         `json:"sampled"`
+	Bytes    int64         `json:"bytes"`
+	Nodes    []NodeMemory  `json:"nodes"`
+	Classes  []MemoryGroup `json:"classes"`
+	Slots    []SlotMemory  `json:"slots"`
+	Tags     []TagMemory   `json:"tags"`
+	TopKeys  []KeyMemory   `json:"top_keys"`
+	Warnings []string      `json:"warnings,omitempty"`
+}
+
+// memoryBatch is how many keys are sized per pipeline round trip
+const memoryBatch = 100
+
+// MemoryReport samples keys on every master with SCAN, sizes them with
+// MEMORY USAGE, OBJECT ENCODING and the type's length command, and
+// aggregates the result by key class, slot, hash tag and node
+func (c *Client) MemoryReport(ctx context.Context, opts MemoryReportOptions) (*MemoryReport, error) {
+	if opts.Match == "" {
+		opts.Match = "*"
+	}
+	if opts.Top <= 0 {
+		opts.Top = 20
+	}
+
+	var mu sync.Mutex
+	var nodes []NodeMemory
+	var keys []KeyMemory
+	err := c.ForEachMaster(func(node *redis.Client) error {
+		nm, sampled := sampleNodeMemory(ctx, node, opts)
+		for i := range sampled {
+			sampled[i].Class = classifyKey(sampled[i].Key, opts.Classes)
+		}
+		mu.Lock()
+		nodes = append(nodes, nm)
+		keys = append(keys, sampled...)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Addr < nodes[j].Addr })
+
+	report := &MemoryReport{Time: time.Now(), Match: opts.Match, Nodes: nodes}
+	report.aggregate(keys, opts)
+	return report, nil
+}
+
+// sampleNodeMemory sizes up to opts.KeysPerNode keys of one master. Keys that
+// disappear or move while being sampled are skipped.
+func sampleNodeMemory(ctx context.Context, node *redis.Client, opts MemoryReportOptions) (NodeMemory, []KeyMemory) {
+	nm := NodeMemory{Addr: node.Options().Addr}
+	if raw, err := node.Info(ctx, "memory").Result(); err == nil {
+		nm.UsedMemory = ParseNodeInfo(nm.Addr, raw).Memory.Used
+	}
+	size, err := node.DBSize(ctx).Result()
+	if err != nil {
+		nm.Error = err.Error()
+		return nm, nil
+	}
+	nm.Keys = size
+
+	var out []KeyMemory
+	var batch []string
+	scanned := 0
+	flush := func() error {
+		sized, err := sizeKeys(ctx, node, batch, opts.Samples)
+		batch = batch[:0]
+		for _, km := range sized {
+			km.Node = nm.Addr
+			nm.Sampled++
+			nm.Bytes += km.Bytes
+			out = append(out, km)
+		}
+		return err
+	}
+
+	iter := node.Scan(ctx, 0, opts.Match, memoryBatch).Iterator()
+	for iter.Next(ctx) {
+		if opts.KeysPerNode > 0 && scanned >= opts.KeysPerNode {
+			break
+		}
+		scanned++
+		batch = append(batch, iter.Val())
+		if len(batch) == memoryBatch {
+			if err := flush(); err != nil {
+				nm.Error = err.Error()
+				return nm, out
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		nm.Error = err.Error()
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil && nm.Error == "" {
+			nm.Error = err.Error()
+		}
+	}
+	if nm.Sampled > 0 {
+		nm.Estimated = nm.Bytes * nm.Keys / nm.Sampled
+	}
+	return nm, out
+}
+
+// sizeKeys measures keys in two pipelines: type, encoding and memory first,
+// then the length command matching each type
+func sizeKeys(ctx context.Context, node *redis.Client, keys []string, samples int) ([]KeyMemory, error) {
+	pipe := node.Pipeline()
+	types := make([]*redis.StatusCmd, len(keys))
+	encodings := make([]*redis.StringCmd, len(keys))
+	usage := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		types[i] = pipe.Type(ctx, key)
+		encodings[i] = pipe.ObjectEncoding(ctx, key)
+		if samples > 0 {
+			usage[i] = pipe.MemoryUsage(ctx, key, samples)
+		} else {
+			usage[i] = pipe.MemoryUsage(ctx, key)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !keyGone(err) {
+		return nil, fmt.Errorf("failed to size keys: %w", err)
+	}
+
+	var out []KeyMemory
+	pipe = node.Pipeline()
+	var lengths []*redis.IntCmd
+	for i, key := range keys {
+		if usage[i].Err() != nil || types[i].Val() == "none" {
+			continue
+		}
+		km := KeyMemory{
+			Key:      key,
+			Slot:     KeySlot(key),
+			Type:     types[i].Val(),
+			Encoding: encodings[i].Val(),
+			Bytes:    usage[i].Val(),
+		}
+		out = append(out, km)
+		lengths = append(lengths, lengthCmd(ctx, pipe, km.Type, key))
+	}
+	if len(out) == 0 {
+		return out, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !keyGone(err) {
+		return out, fmt.Errorf("failed to count elements: %w", err)
+	}
+	for i, cmd := range lengths {
+		if cmd != nil {
+			out[i].Elements = cmd.Val()
+		}
+	}
+	return out, nil
+}
+
+// keyGone reports whether a pipeline error only means a key expired or
+// moved to another node while it was being sampled
+func keyGone(err error) bool {
+	if errors.Is(err, redis.Nil) {
+		return true
+	}
+	msg := err.Error()
+	return strings.HasPrefix(msg, "MOVED ") || strings.HasPrefix(msg, "ASK ") || strings.HasPrefix(msg, "TRYAGAIN")
+}
+
+// lengthCmd queues the element count command for a key type; nil for types
+// without one
+func lengthCmd(ctx context.Context, pipe redis.Pipeliner, typ, key string) *redis.IntCmd {
+	switch typ {
+	case "string":
+		return pipe.StrLen(ctx, key)
+	case "hash":
+		return pipe.HLen(ctx, key)
+	case "set":
+		return pipe.SCard(ctx, key)
+	case "zset":
+		return pipe.ZCard(ctx, key)
+	case "list":
+		return pipe.LLen(ctx, key)
+	case "stream":
+		return pipe.XLen(ctx, key)
+	}
+	return nil
+}
+
+// classifyKey returns the name of the first class matching key. Other keys
+// are grouped by their first ':'-separated segment.
+func classifyKey(key string, classes []KeyClass) string {
+	for _, class := range classes {
+		if MatchPattern(class.Pattern, key) {
+			return class.Name
+		}
+	}
+	if i := strings.IndexByte(key, ':'); i > 0 {
+		return key[:i+1] + "*"
+	}
+	return "(other)"
+}
+
+// aggregate fills the per class, slot and tag tables, the top keys and the
+// warnings from the sampled keys
+func (r *MemoryReport) aggregate(keys []KeyMemory, opts MemoryReportOptions) {
+	classes := make(map[string]*MemoryGroup)
+	slots := make(map[int]*SlotMemory)
+	type tagKey struct{ tag, node string }
+	tags := make(map[tagKey]*TagMemory)
+	nodeBytes := make(map[string]int64)
+
+	for _, km := range keys {
+		r.Sampled++
+		r.Bytes += km.Bytes
+		nodeBytes[km.Node] += km.Bytes
+
+		g := classes[km.Class]
+		if g == nil {
+			g = &MemoryGroup{Name: km.Class, Encodings: make(map[string]int64)}
+			classes[km.Class] = g
+		}
+		g.Keys++
+		g.Bytes += km.Bytes
+		g.Elements += km.Elements
+		g.Encodings[km.Encoding]++
+		if km.Bytes > g.MaxBytes {
+			g.Largest, g.MaxBytes = km.Key, km.Bytes
+		}
+
+		s := slots[km.Slot]
+		if s == nil {
+			s = &SlotMemory{Slot: km.Slot, Node: km.Node}
+			slots[km.Slot] = s
+		}
+		s.Keys++
+		s.Bytes += km.Bytes
+
+		if tag := HashTag(km.Key); tag != km.Key {
+			k := tagKey{tag, km.Node}
+			t := tags[k]
+			if t == nil {
+				t = &TagMemory{Tag: tag, Node: km.Node}
+				tags[k] = t
+			}
+			t.Keys++
+			t.Bytes += km.Bytes
+		}
+	}
+
+	for _, g := range classes {
+		r.Classes = append(r.Classes, *g)
+	}
+	sort.Slice(r.Classes, func(i, j int) bool { return r.Classes[i].Bytes > r.Classes[j].Bytes })
+
+	for _, s := range slots {
+		r.Slots = append(r.Slots, *s)
+	}
+	sort.Slice(r.Slots, func(i, j int) bool { return r.Slots[i].Bytes > r.Slots[j].Bytes })
+
+	for _, t := range tags {
+		if total := nodeBytes[t.Node]; total > 0 {
+			t.Share = float64(t.Bytes) / float64(total) * 100
+		}
+		r.Tags = append(r.Tags, *t)
+	}
+	sort.Slice(r.Tags, func(i, j int) bool { return r.Tags[i].Bytes > r.Tags[j].Bytes })
+
+	r.TopKeys = append([]KeyMemory(nil), keys...)
+	sort.Slice(r.TopKeys, func(i, j int) bool { return r.TopKeys[i].Bytes > r.TopKeys[j].Bytes })
+
+	// Warnings look at every tag and key, not only the ones kept below
+	if opts.TagShare > 0 {
+		for _, t := range r.Tags {
+			if t.Share > opts.TagShare && t.Bytes >= opts.TagMinBytes {
+				r.Warnings = append(r.Warnings, fmt.Sprintf("hash tag {%s} holds %.1f%% (%d bytes in %d keys) of the sampled memory on %s",
+					t.Tag, t.Share, t.Bytes, t.Keys, t.Node))
+			}
+		}
+	}
+	if opts.BigKey > 0 {
+		for _, km := range r.TopKeys {
+			if km.Bytes <= opts.BigKey {
+				break
+			}
+			r.Warnings = append(r.Warnings, fmt.Sprintf("big key %s on %s: %d bytes, %d elements (%s)",
+				km.Key, km.Node, km.Bytes, km.Elements, km.Encoding))
+		}
+	}
+	for _, n := range r.Nodes {
+		if n.Error != "" {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("%s only partly sampled: %s", n.Addr, n.Error))
+		}
+	}
+
+	if len(r.Slots) > opts.Top {
+		r.Slots = r.Slots[:opts.Top]
+	}
+	if len(r.Tags) > opts.Top {
+		r.Tags = r.Tags[:opts.Top]
+	}
+	if len(r.TopKeys) > opts.Top {
+		r.TopKeys = r.TopKeys[:opts.Top]
+	}
+}
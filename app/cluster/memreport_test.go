@@ -0,0 +1,64 @@
+package cluster_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+)
+
+func TestMemoryReport(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	rdb := client.Redis()
+	seats := make(map[string]interface{})
+	for i := 0; i < 300; i++ {
+		seats["S"+strconv.Itoa(i)] = "available"
+	}
+	if err := rdb.HSet(ctx, "{event:big}:seats", seats).Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := rdb.Set(ctx, "reservation:"+strconv.Itoa(i), "{}", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := client.MemoryReport(ctx, cluster.MemoryReportOptions{
+		Top: 5,
+		Classes: []cluster.KeyClass{
+			{Name: "event seats", Pattern: "{event:*}:seats"},
+			{Name: "reservation", Pattern: "reservation:*"},
+		},
+		TagShare: 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Sampled != 21 || len(report.Nodes) != 3 {
+		t.Fatalf("sampled %d keys on %d nodes, want 21 on 3", report.Sampled, len(report.Nodes))
+	}
+	if len(report.TopKeys) != 5 {
+		t.Fatalf("top keys = %d, want 5", len(report.TopKeys))
+	}
+	big := report.TopKeys[0]
+	if big.Key != "{event:big}:seats" || big.Type != "hash" || big.Encoding != "hashtable" || big.Elements != 300 {
+		t.Fatalf("largest key = %+v", big)
+	}
+	if report.Classes[0].Name != "event seats" || report.Classes[1].Name != "reservation" || report.Classes[1].Keys != 20 {
+		t.Fatalf("classes = %+v", report.Classes)
+	}
+	if len(report.Warnings) != 1 || !strings.Contains(report.Warnings[0], "{event:big}") {
+		t.Fatalf("warnings = %v, want one for {event:big}", report.Warnings)
+	}
+}
@@ -0,0 +1,488 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-reservation/models"
+)
+
+// NodeOpOptions controls add/remove/replicate operations
+type NodeOpOptions struct {
+	Timeout  time.Duration     // how long to wait for gossip convergence (default 30s)
+	Migrate  MigrateOptions    // used when draining slots before removal
+	Progress func(step string) // called before each step and after each health check
+
+	removing string // ID of the node being removed, which may be failing
+}
+
+func (o NodeOpOptions) withDefaults() NodeOpOptions {
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Second
+	}
+	if o.Progress == nil {
+		o.Progress = func(string) {}
+	}
+	return o
+}
+
+// VerifyHealth checks the cluster is in a state where the next topology step
+// is safe: cluster_state ok, every slot served, no failing nodes and no open
+// migrations
+func (c *Client) VerifyHealth() error {
+	return c.verifyHealth("")
+}
+
+// verifyHealth is VerifyHealth, except that the node with ID exempt may be
+// failing. Slots it still serves are caught by the slot checks.
+func (c *Client) verifyHealth(exempt string) error {
+	info, err := c.GetClusterInfo()
+	if err != nil {
+		return err
+	}
+	var problems []string
+	if info.State != "ok" {
+		problems = append(problems, "cluster_state is "+info.State)
+	}
+	if info.SlotsOK != TotalSlots {
+		problems = append(problems, fmt.Sprintf("%d/%d slots ok", info.SlotsOK, TotalSlots))
+	}
+	for _, n := range info.FailingNodes {
+		if n.ID == exempt {
+			continue
+		}
+		problems = append(problems, fmt.Sprintf("%s %s is failing", n.Address, ShortID(n.ID)))
+	}
+	if len(info.Migrations) > 0 {
+		problems = append(problems, fmt.Sprintf("%d open slot migrations", len(info.Migrations)))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("cluster unhealthy: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// waitFor polls cond every 500ms until it returns true or timeout elapses
+func waitFor(ctx context.Context, timeout time.Duration, what string, cond func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	var lastErr error
+	for {
+		ok, err := cond()
+		if ok {
+			return nil
+		}
+		lastErr = err
+		if time.Now().After(deadline) {
+			if lastErr != nil {
+				return fmt.Errorf("timed out waiting for %s: %w", what, lastErr)
+			}
+			return fmt.Errorf("timed out waiting for %s", what)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// viewOf returns the CLUSTER NODES output of a single node
+func (c *Client) viewOf(ctx context.Context, addr string) ([]models.ClusterNode, error) {
+	text, err := c.NodeClient(addr).ClusterNodes(ctx).Result()
+	if err != nil {
+		return nil, err
+	}
+	return ParseClusterNodes(text), nil
+}
+
+// waitForConvergence waits until every healthy node lists each of ids as a
+// fully joined node (no handshake) and check, if set, holds in every view
+func (c *Client) waitForConvergence(ctx context.Context, timeout time.Duration, ids []string, check func(view []models.ClusterNode) bool) error {
+	return waitFor(ctx, timeout, "gossip convergence", func() (bool, error) {
+		nodes, err := c.GetClusterNodes()
+		if err != nil {
+			return false, err
+		}
+		for _, n := range nodes {
+			if n.IsFailing() || n.Handshake || n.NoAddr {
+				continue
+			}
+			view, err := c.viewOf(ctx, n.Address)
+			if err != nil {
+				return false, fmt.Errorf("%s: %w", n.Address, err)
+			}
+			known := make(map[string]models.ClusterNode, len(view))
+			for _, v := range view {
+				known[v.ID] = v
+			}
+			for _, id := range ids {
+				if v, ok := known[id]; !ok || v.Handshake {
+					return false, fmt.Errorf("%s does not know %s yet", n.Address, ShortID(id))
+				}
+			}
+			if check != nil && !check(view) {
+				return false, fmt.Errorf("%s has not seen the change yet", n.Address)
+			}
+		}
+		return true, nil
+	})
+}
+
+// AddNode joins the empty node at addr (its announced address) to the
+// cluster as a master without slots and waits until every node knows it
+func (c *Client) AddNode(ctx context.Context, addr string, opts NodeOpOptions) (models.ClusterNode, error) {
+	opts = opts.withDefaults()
+	nc := c.NodeClient(addr)
+
+	opts.Progress("Checking " + addr + " is reachable and empty")
+	if err := nc.Ping(ctx).Err(); err != nil {
+		return models.ClusterNode{}, fmt.Errorf("new node %s unreachable: %w", addr, err)
+	}
+	self, err := c.viewOf(ctx, addr)
+	if err != nil {
+		return models.ClusterNode{}, fmt.Errorf("%s is not running in cluster mode: %w", addr, err)
+	}
+	if len(self) > 1 {
+		return models.ClusterNode{}, fmt.Errorf("%s already knows %d other nodes; reset it first", addr, len(self)-1)
+	}
+	size, err := nc.DBSize(ctx).Result()
+	if err != nil {
+		return models.ClusterNode{}, fmt.Errorf("DBSIZE on %s: %w", addr, err)
+	}
+	if size > 0 {
+		return models.ClusterNode{}, fmt.Errorf("%s is not empty (%d keys)", addr, size)
+	}
+	newID := self[0].ID
+
+	if err := c.VerifyHealth(); err != nil {
+		return models.ClusterNode{}, err
+	}
+	opts.Progress("Health OK before join")
+
+	// Introduce the new node to one healthy member; gossip does the rest
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return models.ClusterNode{}, err
+	}
+	var seed models.ClusterNode
+	for _, n := range nodes {
+		if n.Role == "master" && !n.IsFailing() {
+			seed = n
+			break
+		}
+	}
+	if seed.ID == "" {
+		return models.ClusterNode{}, fmt.Errorf("no healthy master to meet")
+	}
+	host, port, err := net.SplitHostPort(seed.Address)
+	if err != nil {
+		return models.ClusterNode{}, err
+	}
+
+	opts.Progress(fmt.Sprintf("CLUSTER MEET %s (%s)", seed.Address, ShortID(seed.ID)))
+	meet := []interface{}{"CLUSTER", "MEET", host, port}
+	if seed.BusPort > 0 {
+		if p, _ := strconv.Atoi(port); seed.BusPort != p+10000 {
+			meet = append(meet, seed.BusPort)
+		}
+	}
+	if err := nc.Do(ctx, meet...).Err(); err != nil {
+		return models.ClusterNode{}, fmt.Errorf("CLUSTER MEET failed: %w", err)
+	}
+
+	opts.Progress("Waiting for gossip convergence")
+	if err := c.waitForConvergence(ctx, opts.Timeout, []string{newID}, nil); err != nil {
+		return models.ClusterNode{}, err
+	}
+	// The new node must also have learned the slot map
+	if err := waitFor(ctx, opts.Timeout, "new node to report cluster_state ok", func() (bool, error) {
+		info, err := nc.ClusterInfo(ctx).Result()
+		return err == nil && strings.Contains(info, "cluster_state:ok"), err
+	}); err != nil {
+		return models.ClusterNode{}, err
+	}
+
+	if err := c.VerifyHealth(); err != nil {
+		return models.ClusterNode{}, err
+	}
+	opts.Progress("Health OK after join")
+
+	return c.ResolveNode(newID)
+}
+
+// AddReplica joins the empty node at addr and makes it a replica of master
+func (c *Client) AddReplica(ctx context.Context, addr, masterRef string, opts NodeOpOptions) (models.ClusterNode, error) {
+	master, err := c.ResolveNode(masterRef)
+	if err != nil {
+		return models.ClusterNode{}, err
+	}
+	if master.Role != "master" {
+		return models.ClusterNode{}, fmt.Errorf("%s is not a master", master.Address)
+	}
+
+	node, err := c.AddNode(ctx, addr, opts)
+	if err != nil {
+		return models.ClusterNode{}, err
+	}
+	return c.Replicate(ctx, node.ID, master.ID, opts)
+}
+
+// Replicate makes an existing node a replica of master. A master that still
+// owns slots cannot be converted; drain it first.
+func (c *Client) Replicate(ctx context.Context, nodeRef, masterRef string, opts NodeOpOptions) (models.ClusterNode, error) {
+	opts = opts.withDefaults()
+	node, err := c.ResolveNode(nodeRef)
+	if err != nil {
+		return models.ClusterNode{}, err
+	}
+	master, err := c.ResolveNode(masterRef)
+	if err != nil {
+		return models.ClusterNode{}, err
+	}
+	switch {
+	case master.Role != "master":
+		return models.ClusterNode{}, fmt.Errorf("%s is not a master", master.Address)
+	case node.ID == master.ID:
+		return models.ClusterNode{}, fmt.Errorf("a node cannot replicate itself")
+	case node.Role == "master" && node.SlotCount() > 0:
+		return models.ClusterNode{}, fmt.Errorf("%s is a master with %d slots; move them away first", node.Address, node.SlotCount())
+	case node.MasterID == master.ID:
+		opts.Progress(fmt.Sprintf("%s already replicates %s", node.Address, ShortID(master.ID)))
+		return node, nil
+	}
+
+	opts.Progress(fmt.Sprintf("CLUSTER REPLICATE %s on %s", ShortID(master.ID), node.Address))
+	if err := c.NodeClient(node.Address).ClusterReplicate(ctx, master.ID).Err(); err != nil {
+		return models.ClusterNode{}, fmt.Errorf("CLUSTER REPLICATE failed: %w", err)
+	}
+
+	opts.Progress("Waiting for the cluster to see the new replica")
+	if err := c.waitForConvergence(ctx, opts.Timeout, []string{node.ID}, func(view []models.ClusterNode) bool {
+		for _, v := range view {
+			if v.ID == node.ID {
+				return v.Role == "replica" && v.MasterID == master.ID
+			}
+		}
+		return false
+	}); err != nil {
+		return models.ClusterNode{}, err
+	}
+
+	opts.Progress("Waiting for the replication link")
+	if err := waitFor(ctx, opts.Timeout, "master_link_status:up", func() (bool, error) {
+		info, err := c.NodeClient(node.Address).Info(ctx, "replication").Result()
+		return err == nil && strings.Contains(info, "master_link_status:up"), err
+	}); err != nil {
+		return models.ClusterNode{}, err
+	}
+
+	if err := c.verifyHealth(opts.removing); err != nil {
+		return models.ClusterNode{}, err
+	}
+	opts.Progress("Health OK after replicate")
+
+	return c.ResolveNode(node.ID)
+}
+
+// RemoveNode removes a node from the cluster. A master's slots are first
+// spread over the remaining masters and its replicas are moved to the master
+// with the fewest replicas. Every other node then runs CLUSTER FORGET and the
+// removed node is soft-reset so it does not rejoin through gossip. The node
+// itself may have failed, as long as it no longer serves slots; any other
+// failing node still stops the removal.
+func (c *Client) RemoveNode(ctx context.Context, ref string, opts NodeOpOptions) error {
+	opts = opts.withDefaults()
+	node, err := c.ResolveNode(ref)
+	if err != nil {
+		return err
+	}
+	opts.removing = node.ID
+	if err := c.verifyHealth(opts.removing); err != nil {
+		return err
+	}
+	opts.Progress("Health OK before removal")
+
+	if node.Role == "master" {
+		if err := c.drainMaster(ctx, node, opts); err != nil {
+			return err
+		}
+		if err := c.moveReplicasAway(ctx, node, opts); err != nil {
+			return err
+		}
+	}
+
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return err
+	}
+	opts.Progress(fmt.Sprintf("CLUSTER FORGET %s on %d nodes", ShortID(node.ID), len(nodes)-1))
+	// FORGET bans the ID for 60s, so all nodes must forget it well within
+	// that window or gossip re-adds it
+	for _, n := range nodes {
+		if n.ID == node.ID || n.Fail {
+			continue
+		}
+		if err := c.NodeClient(n.Address).ClusterForget(ctx, node.ID).Err(); err != nil {
+			return fmt.Errorf("CLUSTER FORGET on %s: %w", n.Address, err)
+		}
+	}
+
+	if !node.Fail {
+		opts.Progress("CLUSTER RESET SOFT on " + node.Address)
+		c.NodeClient(node.Address).ClusterResetSoft(ctx)
+	}
+
+	opts.Progress("Waiting for every node to drop it")
+	if err := waitFor(ctx, opts.Timeout, "node to be forgotten", func() (bool, error) {
+		nodes, err := c.GetClusterNodes()
+		if err != nil {
+			return false, err
+		}
+		for _, n := range nodes {
+			if n.ID == node.ID {
+				return false, fmt.Errorf("%s still listed", ShortID(node.ID))
+			}
+			if n.IsFailing() {
+				continue
+			}
+			view, err := c.viewOf(ctx, n.Address)
+			if err != nil {
+				return false, err
+			}
+			for _, v := range view {
+				if v.ID == node.ID {
+					return false, fmt.Errorf("%s still knows it", n.Address)
+				}
+			}
+		}
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	if err := c.VerifyHealth(); err != nil {
+		return err
+	}
+	opts.Progress("Health OK after removal")
+	return nil
+}
+
+// drainMaster moves every slot of node to the other masters, giving each an
+// equal share
+func (c *Client) drainMaster(ctx context.Context, node models.ClusterNode, opts NodeOpOptions) error {
+	if node.SlotCount() == 0 {
+		return nil
+	}
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return err
+	}
+	var targets []models.ClusterNode
+	for _, n := range nodes {
+		if n.Role == "master" && n.ID != node.ID && !n.IsFailing() {
+			targets = append(targets, n)
+		}
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no other master can take the %d slots of %s", node.SlotCount(), node.Address)
+	}
+
+	var slots []int
+	for _, r := range node.SlotRanges {
+		for s := r.Start; s <= r.End; s++ {
+			slots = append(slots, s)
+		}
+	}
+	// Contiguous chunks keep the receivers' ranges tidy
+	per := (len(slots) + len(targets) - 1) / len(targets)
+	for i, t := range targets {
+		lo, hi := i*per, (i+1)*per
+		if lo >= len(slots) {
+			break
+		}
+		if hi > len(slots) {
+			hi = len(slots)
+		}
+		plan := &ReshardPlan{
+			SourceID:  node.ID,
+			TargetID:  t.ID,
+			Slots:     slots[lo:hi],
+			BatchSize: opts.Migrate.BatchSize,
+			Timeout:   Duration(opts.Migrate.Timeout),
+			Replace:   opts.Migrate.Replace,
+		}
+		opts.Progress(fmt.Sprintf("Draining %d slots (%s) to %s", len(plan.Slots), formatSlotRanges(plan.SlotRanges()), t.Address))
+		if err := c.ExecuteReshard(ctx, plan, "", nil); err != nil {
+			return err
+		}
+	}
+
+	opts.Progress("Waiting for every node to agree the master is empty")
+	if err := c.waitForConvergence(ctx, opts.Timeout, nil, func(view []models.ClusterNode) bool {
+		for _, v := range view {
+			if v.ID == node.ID {
+				return v.SlotCount() == 0
+			}
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+	if err := c.verifyHealth(opts.removing); err != nil {
+		return err
+	}
+	opts.Progress("Health OK after drain")
+	return nil
+}
+
+// moveReplicasAway re-points replicas of node to the master with the fewest
+// replicas, so FORGET does not fail with "Can't forget my master"
+func (c *Client) moveReplicasAway(ctx context.Context, node models.ClusterNode, opts NodeOpOptions) error {
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return err
+	}
+	replicaCount := make(map[string]int)
+	var replicas []models.ClusterNode
+	for _, n := range nodes {
+		if n.Role == "replica" {
+			replicaCount[n.MasterID]++
+			if n.MasterID == node.ID {
+				replicas = append(replicas, n)
+			}
+		}
+	}
+
+	for _, r := range replicas {
+		var best models.ClusterNode
+		for _, m := range nodes {
+			if m.Role != "master" || m.ID == node.ID || m.IsFailing() || m.SlotCount() == 0 {
+				continue
+			}
+			if best.ID == "" || replicaCount[m.ID] < replicaCount[best.ID] {
+				best = m
+			}
+		}
+		if best.ID == "" {
+			return fmt.Errorf("no master available for replica %s", r.Address)
+		}
+		if _, err := c.Replicate(ctx, r.ID, best.ID, opts); err != nil {
+			return err
+		}
+		replicaCount[best.ID]++
+	}
+	return nil
+}
+
+// formatSlotRanges joins ranges compactly for progress messages
+func formatSlotRanges(ranges []models.SlotRange) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		parts = append(parts, r.String())
+	}
+	return strings.Join(parts, " ")
+}
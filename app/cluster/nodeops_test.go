@@ -0,0 +1,75 @@
+package cluster_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+)
+
+// newClient connects to fc as a fresh command would, with the current slot
+// map
+func newClient(t *testing.T, fc *clustertest.Cluster) *cluster.Client {
+	t.Helper()
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// emptyNode hands every slot of n to the node go-redis asks for CLUSTER
+// NODES
+func emptyNode(fc *clustertest.Cluster, n *clustertest.Node) {
+	seed := fc.Owner(cluster.KeySlot("nodes"))
+	for s := 0; s < cluster.TotalSlots; s++ {
+		if fc.Owner(s) == n {
+			fc.MoveSlots(s, s, seed)
+		}
+	}
+}
+
+// A crashed node without slots can be removed, but only if it is the sole
+// failing node
+func TestRemoveFailedNode(t *testing.T) {
+	fc := clustertest.New(t, 4)
+	var spare []*clustertest.Node
+	for _, n := range fc.Nodes() {
+		if n != fc.Owner(cluster.KeySlot("nodes")) {
+			spare = append(spare, n)
+		}
+	}
+	crashed, other := spare[0], spare[1]
+	emptyNode(fc, crashed)
+	emptyNode(fc, other)
+	fc.FailNode(crashed)
+	fc.FailNode(other)
+
+	ctx := context.Background()
+	opts := cluster.NodeOpOptions{Timeout: 5 * time.Second}
+	err := newClient(t, fc).RemoveNode(ctx, crashed.ID(), opts)
+	if err == nil || !strings.Contains(err.Error(), other.Addr()+" "+cluster.ShortID(other.ID())+" is failing") {
+		t.Fatalf("remove with another node down: got %v, want it refused", err)
+	}
+
+	if err := fc.RecoverNode(other); err != nil {
+		t.Fatal(err)
+	}
+	client := newClient(t, fc)
+	if err := client.RemoveNode(ctx, crashed.ID(), opts); err != nil {
+		t.Fatalf("remove crashed node: %v", err)
+	}
+	nodes, err := client.GetClusterNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range nodes {
+		if n.ID == crashed.ID() {
+			t.Fatalf("%s still listed after removal", crashed.Addr())
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package cluster
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"ticket-reservation/models"
+)
+
+// ParseClusterNodes parses CLUSTER NODES output. Each line has the form:
+//
+//	<id> <ip:port@cport[,hostname]> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> <slot> ... <slot>
+//
+// Slot fields are single slots ("42"), ranges ("0-5460") or migration markers
+// ("[42->-<target-id>]" on the source, "[42-<-<source-id>]" on the target).
+func ParseClusterNodes(nodesStr string) []models.ClusterNode {
+	var nodes []models.ClusterNode
+
+	for _, line := range strings.Split(nodesStr, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) < 8 {
+			continue
+		}
+
+		node := models.ClusterNode{
+			ID:        parts[0],
+			LinkState: parts[7],
+		}
+
+		// Address: ip:port@cport[,hostname]
+		addr := parts[1]
+		if i := strings.Index(addr, ","); i >= 0 {
+			node.Hostname = addr[i+1:]
+			addr = addr[:i]
+		}
+		if i := strings.Index(addr, "@"); i >= 0 {
+			node.BusPort, _ = strconv.Atoi(addr[i+1:])
+			addr = addr[:i]
+		}
+		node.Address = addr
+
+		// Flags
+		node.Flags = strings.Split(parts[2], ",")
+		for _, flag := range node.Flags {
+			switch flag {
+			case "myself":
+				node.Myself = true
+			case "master":
+				node.Role = "master"
+			case "slave":
+				node.Role = "replica"
+			case "fail?":
+				node.PFail = true
+			case "fail":
+				node.Fail = true
+			case "handshake":
+				node.Handshake = true
+			case "noaddr":
+				node.NoAddr = true
+			case "nofailover":
+				node.NoFailover = true
+			}
+		}
+
+		// Master ID (for replicas)
+		if parts[3] != "-" {
+			node.MasterID = parts[3]
+		}
+
+		node.PingSent, _ = strconv.ParseInt(parts[4], 10, 64)
+		node.PongRecv, _ = strconv.ParseInt(parts[5], 10, 64)
+		node.ConfigEpoch, _ = strconv.ParseInt(parts[6], 10, 64)
+
+		// Slots and migration markers
+		var ranges []string
+		for _, field := range parts[8:] {
+			if strings.HasPrefix(field, "[") {
+				parseMigrationMarker(&node, strings.Trim(field, "[]"))
+				continue
+			}
+			r, ok := parseSlotRange(field)
+			if !ok {
+				continue
+			}
+			node.SlotRanges = append(node.SlotRanges, r)
+			ranges = append(ranges, field)
+		}
+		node.Slots = strings.Join(ranges, " ")
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
+// parseMigrationMarker decodes "42->-<target>" or "42-<-<source>"
+func parseMigrationMarker(node *models.ClusterNode, marker string) {
+	if i := strings.Index(marker, "->-"); i > 0 {
+		slot, err := strconv.Atoi(marker[:i])
+		if err != nil {
+			return
+		}
+		if node.Migrating == nil {
+			node.Migrating = make(map[int]string)
+		}
+		node.Migrating[slot] = marker[i+3:]
+	} else if i := strings.Index(marker, "-<-"); i > 0 {
+		slot, err := strconv.Atoi(marker[:i])
+		if err != nil {
+			return
+		}
+		if node.Importing == nil {
+			node.Importing = make(map[int]string)
+		}
+		node.Importing[slot] = marker[i+3:]
+	}
+}
+
+// parseSlotRange parses "0-5460" or "42"
+func parseSlotRange(s string) (models.SlotRange, bool) {
+	if i := strings.Index(s, "-"); i > 0 {
+		start, err1 := strconv.Atoi(s[:i])
+		end, err2 := strconv.Atoi(s[i+1:])
+		if err1 != nil || err2 != nil {
+			return models.SlotRange{}, false
+		}
+		return models.SlotRange{Start: start, End: end}, true
+	}
+	slot, err := strconv.Atoi(s)
+	if err != nil {
+		return models.SlotRange{}, false
+	}
+	return models.SlotRange{Start: slot, End: slot}, true
+}
+
+// mergeMarkers copies the IMPORTING/MIGRATING markers of a node's own
+// CLUSTER NODES entry onto base. A node never reports another node's
+// markers, so only its own view has them.
+func mergeMarkers(base *models.ClusterNode, self models.ClusterNode) {
+	for slot, target := range self.Migrating {
+		if base.Migrating == nil {
+			base.Migrating = make(map[int]string)
+		}
+		base.Migrating[slot] = target
+	}
+	for slot, source := range self.Importing {
+		if base.Importing == nil {
+			base.Importing = make(map[int]string)
+		}
+		base.Importing[slot] = source
+	}
+}
+
+// FindMigrations returns all open slot migrations, merging the source
+// (MIGRATING) and target (IMPORTING) markers so a half-configured migration
+// still shows up. A node only reports its own markers, so nodes must carry
+// each node's own entry (see mergeMarkers), not one node's view.
+func FindMigrations(nodes []models.ClusterNode) []models.SlotMigration {
+	seen := make(map[models.SlotMigration]bool)
+	for _, n := range nodes {
+		for slot, target := range n.Migrating {
+			seen[models.SlotMigration{Slot: slot, SourceID: n.ID, TargetID: target}] = true
+		}
+		for slot, source := range n.Importing {
+			seen[models.SlotMigration{Slot: slot, SourceID: source, TargetID: n.ID}] = true
+		}
+	}
+
+	migrations := make([]models.SlotMigration, 0, len(seen))
+	for m := range seen {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Slot < migrations[j].Slot
+	})
+	return migrations
+}
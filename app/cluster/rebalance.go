@@ -0,0 +1,401 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"ticket-reservation/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RebalanceMode selects what the rebalancer tries to even out
+type RebalanceMode string
+
+const (
+	RebalanceBySlots  RebalanceMode = "slots"  // equal slot counts
+	RebalanceByKeys   RebalanceMode = "keys"   // equal COUNTKEYSINSLOT totals
+	RebalanceByMemory RebalanceMode = "memory" // equal estimated bytes (MEMORY USAGE samples)
+)
+
+// ParseRebalanceMode validates a mode name
+func ParseRebalanceMode(s string) (RebalanceMode, error) {
+	switch m := RebalanceMode(s); m {
+	case RebalanceBySlots, RebalanceByKeys, RebalanceByMemory:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown rebalance mode %q (use slots, keys or memory)", s)
+}
+
+// RebalanceOptions controls how a rebalance plan is computed
+type RebalanceOptions struct {
+	Mode      RebalanceMode
+	Weights   map[string]float64 // node ref (ID, prefix or address) -> weight; missing = 1, 0 = drain
+	Threshold float64            // percent of a node's target load tolerated before moving anything
+	Samples   int                // keys sampled per slot with MEMORY USAGE (memory mode)
+}
+
+// SlotStat is the measured load of a single slot
+type SlotStat struct {
+	Slot  int   `json:"slot"`
+	Keys  int64 `json:"keys"`
+	Bytes int64 `json:"bytes,omitempty"`
+}
+
+// RebalanceNode is a master's load before and after the plan
+type RebalanceNode struct {
+	ID      string  `json:"id"`
+	Address string  `json:"address"`
+	Weight  float64 `json:"weight"`
+	Slots   int     `json:"slots"`
+	Keys    int64   `json:"keys"`
+	Bytes   int64   `json:"bytes,omitempty"`
+	Load    float64 `json:"load"`   // current load in the plan's unit
+	Target  float64 `json:"target"` // ideal load given the weights
+	After   float64 `json:"after"`  // load once the plan is applied
+
+	stats []SlotStat
+}
+
+// SlotMove is a single slot assignment change
+type SlotMove struct {
+	Slot     int    `json:"slot"`
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+	Keys     int64  `json:"keys"`
+	Bytes    int64  `json:"bytes,omitempty"`
+}
+
+// RebalancePlan is the set of slot moves computed by PlanRebalance
+type RebalancePlan struct {
+	Mode      RebalanceMode   `json:"mode"`
+	Threshold float64         `json:"threshold"`
+	Nodes     []RebalanceNode `json:"nodes"`
+	Moves     []SlotMove      `json:"moves"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// SlotStats measures every slot owned by node: key counts always, and
+// estimated bytes (average MEMORY USAGE of up to samples keys times the key
+// count) when withMemory is set
+func (c *Client) SlotStats(ctx context.Context, node models.ClusterNode, withMemory bool, samples int) ([]SlotStat, error) {
+	nc := c.NodeClient(node.Address)
+
+	var stats []SlotStat
+	for _, r := range node.SlotRanges {
+		for s := r.Start; s <= r.End; s++ {
+			stats = append(stats, SlotStat{Slot: s})
+		}
+	}
+	if len(stats) == 0 {
+		return stats, nil
+	}
+
+	pipe := nc.Pipeline()
+	counts := make([]*redis.IntCmd, len(stats))
+	for i, st := range stats {
+		counts[i] = pipe.ClusterCountKeysInSlot(ctx, st.Slot)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to count keys on %s: %w", node.Address, err)
+	}
+	for i := range stats {
+		stats[i].Keys = counts[i].Val()
+	}
+
+	if !withMemory {
+		return stats, nil
+	}
+	if samples <= 0 {
+		samples = 5
+	}
+
+	// Sample keys of non-empty slots, then size them
+	pipe = nc.Pipeline()
+	keyCmds := make(map[int]*redis.StringSliceCmd)
+	for i, st := range stats {
+		if st.Keys > 0 {
+			keyCmds[i] = pipe.ClusterGetKeysInSlot(ctx, st.Slot, samples)
+		}
+	}
+	if len(keyCmds) == 0 {
+		return stats, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to sample keys on %s: %w", node.Address, err)
+	}
+
+	pipe = nc.Pipeline()
+	memCmds := make(map[int][]*redis.IntCmd)
+	for i, cmd := range keyCmds {
+		for _, key := range cmd.Val() {
+			memCmds[i] = append(memCmds[i], pipe.MemoryUsage(ctx, key))
+		}
+	}
+	// Keys can expire between sampling and sizing; those come back as nil
+	pipe.Exec(ctx)
+
+	for i, cmds := range memCmds {
+		var sum, n int64
+		for _, cmd := range cmds {
+			if cmd.Err() == nil {
+				sum += cmd.Val()
+				n++
+			}
+		}
+		if n > 0 {
+			stats[i].Bytes = sum / n * stats[i].Keys
+		}
+	}
+	return stats, nil
+}
+
+// PlanRebalance measures every master and computes a small set of slot moves
+// that brings each master's load within opts.Threshold percent of its
+// weighted target. Larger slots are moved first so fewer moves are needed.
+func (c *Client) PlanRebalance(ctx context.Context, opts RebalanceOptions) (*RebalancePlan, error) {
+	if opts.Mode == "" {
+		opts.Mode = RebalanceBySlots
+	}
+
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	var masters []models.ClusterNode
+	for _, n := range nodes {
+		if n.Role != "master" {
+			continue
+		}
+		if n.IsFailing() {
+			return nil, fmt.Errorf("master %s (%s) is failing; resolve it before rebalancing", n.Address, ShortID(n.ID))
+		}
+		masters = append(masters, n)
+	}
+	if len(masters) < 2 {
+		return nil, fmt.Errorf("need at least two masters to rebalance")
+	}
+	c.mergeOwnMarkers(nodes)
+	if migrations := FindMigrations(nodes); len(migrations) > 0 {
+		return nil, fmt.Errorf("%d slot migrations are open; finish or fix them before rebalancing", len(migrations))
+	}
+
+	weights := make(map[string]float64)
+	for ref, w := range opts.Weights {
+		if w < 0 {
+			return nil, fmt.Errorf("weight for %s must not be negative", ref)
+		}
+		n, err := c.ResolveNode(ref)
+		if err != nil {
+			return nil, fmt.Errorf("weight: %w", err)
+		}
+		weights[n.ID] = w
+	}
+
+	plan := &RebalancePlan{Mode: opts.Mode, Threshold: opts.Threshold, CreatedAt: time.Now()}
+	var totalLoad, totalWeight float64
+	for _, m := range masters {
+		stats, err := c.SlotStats(ctx, m, opts.Mode == RebalanceByMemory, opts.Samples)
+		if err != nil {
+			return nil, err
+		}
+		rn := RebalanceNode{ID: m.ID, Address: m.Address, Weight: 1, Slots: len(stats), stats: stats}
+		if w, ok := weights[m.ID]; ok {
+			rn.Weight = w
+		}
+		for _, st := range stats {
+			rn.Keys += st.Keys
+			rn.Bytes += st.Bytes
+			rn.Load += slotLoad(opts.Mode, st)
+		}
+		totalLoad += rn.Load
+		totalWeight += rn.Weight
+		plan.Nodes = append(plan.Nodes, rn)
+	}
+	if totalWeight == 0 {
+		return nil, fmt.Errorf("at least one master needs a positive weight")
+	}
+
+	for i := range plan.Nodes {
+		n := &plan.Nodes[i]
+		n.Target = totalLoad * n.Weight / totalWeight
+		n.After = n.Load
+	}
+
+	if plan.balanced() {
+		return plan, nil
+	}
+
+	// Greedy: repeatedly move from the most loaded to the most starved node.
+	// A pair that cannot exchange any slot without overshooting is skipped.
+	// Every round moves a slot or exhausts a pair, which bounds the rounds.
+	exhausted := make(map[[2]int]bool)
+	maxRounds := (TotalSlots + len(plan.Nodes)) * len(plan.Nodes)
+	for round := 0; ; round++ {
+		if round == maxRounds {
+			return nil, fmt.Errorf("rebalance plan did not converge after %d rounds", maxRounds)
+		}
+		donor, receiver := -1, -1
+		for i := range plan.Nodes {
+			if plan.Nodes[i].After-plan.Nodes[i].Target <= 0 {
+				continue
+			}
+			for j := range plan.Nodes {
+				if plan.Nodes[j].Target-plan.Nodes[j].After <= 0 || exhausted[[2]int{i, j}] {
+					continue
+				}
+				// Only move while one side is still outside the threshold
+				if plan.Nodes[i].surplus() <= plan.tolerance(i) && plan.Nodes[j].deficit() <= plan.tolerance(j) {
+					continue
+				}
+				if donor == -1 || plan.Nodes[i].surplus()+plan.Nodes[j].deficit() >
+					plan.Nodes[donor].surplus()+plan.Nodes[receiver].deficit() {
+					donor, receiver = i, j
+				}
+			}
+		}
+		if donor == -1 {
+			break
+		}
+
+		if plan.moveSlots(donor, receiver) == 0 {
+			exhausted[[2]int{donor, receiver}] = true
+		}
+	}
+
+	plan.drain()
+
+	sort.SliceStable(plan.Moves, func(i, j int) bool {
+		if plan.Moves[i].SourceID != plan.Moves[j].SourceID {
+			return plan.Moves[i].SourceID < plan.Moves[j].SourceID
+		}
+		if plan.Moves[i].TargetID != plan.Moves[j].TargetID {
+			return plan.Moves[i].TargetID < plan.Moves[j].TargetID
+		}
+		return plan.Moves[i].Slot < plan.Moves[j].Slot
+	})
+	return plan, nil
+}
+
+// moveSlots moves the donor's slots to receiver, largest first, as long as
+// each move strictly lowers the donor's surplus plus the receiver's deficit,
+// and returns how many were moved. A slot that would only swap the surplus
+// over to the receiver stays put, or the pair would trade it forever.
+func (p *RebalancePlan) moveSlots(donor, receiver int) int {
+	d, r := &p.Nodes[donor], &p.Nodes[receiver]
+
+	// Largest first; among equal loads keep low slot numbers together
+	sort.SliceStable(d.stats, func(i, j int) bool {
+		li, lj := slotLoad(p.Mode, d.stats[i]), slotLoad(p.Mode, d.stats[j])
+		if li != lj {
+			return li > lj
+		}
+		return d.stats[i].Slot < d.stats[j].Slot
+	})
+
+	moved := 0
+	kept := d.stats[:0]
+	for _, st := range d.stats {
+		load := slotLoad(p.Mode, st)
+		// Empty slots do not change a keys/memory balance; leave them put
+		surplus, deficit := d.surplus(), r.deficit()
+		if load <= 0 || math.Abs(surplus-load)+math.Abs(deficit-load) >= math.Abs(surplus)+math.Abs(deficit)-1e-9 {
+			kept = append(kept, st)
+			continue
+		}
+		d.After -= load
+		r.After += load
+		r.stats = append(r.stats, st)
+		p.Moves = append(p.Moves, SlotMove{Slot: st.Slot, SourceID: d.ID, TargetID: r.ID, Keys: st.Keys, Bytes: st.Bytes})
+		moved++
+	}
+	d.stats = kept
+	return moved
+}
+
+// drain hands the slots left on zero-weight nodes (empty slots in keys and
+// memory mode) to the positive-weight node with the fewest slots
+func (p *RebalancePlan) drain() {
+	for i := range p.Nodes {
+		d := &p.Nodes[i]
+		if d.Weight != 0 {
+			continue
+		}
+		for _, st := range d.stats {
+			r := -1
+			for j := range p.Nodes {
+				if p.Nodes[j].Weight > 0 && (r == -1 || len(p.Nodes[j].stats) < len(p.Nodes[r].stats)) {
+					r = j
+				}
+			}
+			load := slotLoad(p.Mode, st)
+			d.After -= load
+			p.Nodes[r].After += load
+			p.Nodes[r].stats = append(p.Nodes[r].stats, st)
+			p.Moves = append(p.Moves, SlotMove{Slot: st.Slot, SourceID: d.ID, TargetID: p.Nodes[r].ID, Keys: st.Keys, Bytes: st.Bytes})
+		}
+		d.stats = nil
+	}
+}
+
+// tolerance is how far node i may stray from its target
+func (p *RebalancePlan) tolerance(i int) float64 {
+	return p.Nodes[i].Target * p.Threshold / 100
+}
+
+// balanced reports whether every node is already within the threshold
+func (p *RebalancePlan) balanced() bool {
+	for _, n := range p.Nodes {
+		if math.Abs(n.Load-n.Target) > n.Target*p.Threshold/100 {
+			return false
+		}
+		if n.Weight == 0 && n.Slots > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (n RebalanceNode) surplus() float64 { return n.After - n.Target }
+func (n RebalanceNode) deficit() float64 { return n.Target - n.After }
+
+// slotLoad returns a slot's load in the unit of mode
+func slotLoad(mode RebalanceMode, st SlotStat) float64 {
+	switch mode {
+	case RebalanceByKeys:
+		return float64(st.Keys)
+	case RebalanceByMemory:
+		return float64(st.Bytes)
+	}
+	return 1
+}
+
+// ReshardPlans groups the moves by source and target so each group can be
+// executed with ExecuteReshard
+func (p *RebalancePlan) ReshardPlans(opts MigrateOptions) []*ReshardPlan {
+	var plans []*ReshardPlan
+	index := make(map[[2]string]*ReshardPlan)
+	for _, m := range p.Moves {
+		key := [2]string{m.SourceID, m.TargetID}
+		rp, ok := index[key]
+		if !ok {
+			rp = &ReshardPlan{
+				SourceID:  m.SourceID,
+				TargetID:  m.TargetID,
+				BatchSize: opts.BatchSize,
+				Timeout:   Duration(opts.Timeout),
+				Replace:   opts.Replace,
+				CreatedAt: p.CreatedAt,
+				UpdatedAt: p.CreatedAt,
+			}
+			index[key] = rp
+			plans = append(plans, rp)
+		}
+		rp.Slots = append(rp.Slots, m.Slot)
+	}
+	return plans
+}
@@ -0,0 +1,112 @@
+package cluster_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+)
+
+// planWithin runs PlanRebalance, failing the test if it does not return
+func planWithin(t *testing.T, client *cluster.Client, opts cluster.RebalanceOptions) *cluster.RebalancePlan {
+	t.Helper()
+	type result struct {
+		plan *cluster.RebalancePlan
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		plan, err := client.PlanRebalance(context.Background(), opts)
+		done <- result{plan, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		return r.plan
+	case <-time.After(10 * time.Second):
+		t.Fatal("PlanRebalance did not return")
+		return nil
+	}
+}
+
+// A slot that would only move the surplus from one master to the other
+// must stay put instead of bouncing between them
+func TestPlanRebalanceConverges(t *testing.T) {
+	fc := clustertest.New(t, 2)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	first := fc.Nodes()[0]
+	var onFirst []string
+	var onSecond string
+	slots := make(map[int]bool)
+	for i := 0; len(onFirst) < 2 || onSecond == ""; i++ {
+		key := fmt.Sprintf("key:%d", i)
+		slot := cluster.KeySlot(key)
+		switch {
+		case fc.NodeForKey(key) != first:
+			if onSecond == "" {
+				onSecond = key
+			}
+		case len(onFirst) < 2 && !slots[slot]:
+			onFirst = append(onFirst, key)
+			slots[slot] = true
+		}
+	}
+	for _, key := range append(onFirst, onSecond) {
+		if err := client.Redis().Set(ctx, key, "v", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan := planWithin(t, client, cluster.RebalanceOptions{Mode: cluster.RebalanceByKeys, Threshold: 2})
+	if len(plan.Moves) != 0 {
+		t.Fatalf("moves = %+v, want none: any move only flips the imbalance", plan.Moves)
+	}
+}
+
+// A migration between two masters other than the one answering CLUSTER
+// NODES still blocks the plan
+func TestPlanRebalanceRefusesOpenMigration(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	beginMigrationAwayFromSeed(t, fc)
+
+	_, err = client.PlanRebalance(context.Background(), cluster.RebalanceOptions{Mode: cluster.RebalanceBySlots})
+	if err == nil || !strings.Contains(err.Error(), "migrations are open") {
+		t.Fatalf("err = %v, want the open migration refused", err)
+	}
+}
+
+func TestPlanRebalanceBySlotsZeroThreshold(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	plan := planWithin(t, client, cluster.RebalanceOptions{Mode: cluster.RebalanceBySlots, Threshold: 0})
+	for _, n := range plan.Nodes {
+		if n.Slots < cluster.TotalSlots/3 || n.Slots > cluster.TotalSlots/3+1 {
+			t.Fatalf("node %s has %d slots before any move; want an even split", n.Address, n.Slots)
+		}
+	}
+	if len(plan.Moves) != 0 {
+		t.Fatalf("moves = %+v, want none for a split that is even up to one slot", plan.Moves)
+	}
+}
@@ -0,0 +1,240 @@
+package cluster
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedirectKind is the type of cluster redirect a node answered with
+type RedirectKind string
+
+const (
+	RedirectMoved RedirectKind = "MOVED" // slot permanently owned by another node
+	RedirectAsk   RedirectKind = "ASK"   // slot is migrating, retry this command on the target
+)
+
+// recentRedirects is how many individual redirects a tracer keeps
+const recentRedirects = 50
+
+// RedirectEvent is one redirect observed by the cluster client
+type RedirectEvent struct {
+	Time    time.Time    `json:"time"`
+	Kind    RedirectKind `json:"kind"`
+	Node    string       `json:"node"`   // node that redirected
+	Target  string       `json:"target"` // node it redirected to
+	Slot    int          `json:"slot"`
+	Command string       `json:"command"`
+}
+
+// RedirectCount counts redirects by kind
+type RedirectCount struct {
+	Moved int64 `json:"moved"`
+	Ask   int64 `json:"ask"`
+}
+
+// Total returns MOVED plus ASK
+func (c RedirectCount) Total() int64 {
+	return c.Moved + c.Ask
+}
+
+func (c *RedirectCount) add(kind RedirectKind) {
+	if kind == RedirectAsk {
+		c.Ask++
+	} else {
+		c.Moved++
+	}
+}
+
+// RedirectStats is a snapshot of a tracer's counters
+type RedirectStats struct {
+	Since     time.Time                `json:"since"`
+	Commands  int64                    `json:"commands"` // commands sent to nodes, redirected or not
+	Total     RedirectCount            `json:"total"`
+	ByNode    map[string]RedirectCount `json:"by_node"`
+	BySlot    map[int]RedirectCount    `json:"by_slot"`
+	ByCommand map[string]RedirectCount `json:"by_command"`
+	Recent    []RedirectEvent          `json:"recent,omitempty"` // newest last
+}
+
+// TopSlots returns up to n slots with the most redirects, most first
+func (s RedirectStats) TopSlots(n int) []int {
+	slots := make([]int, 0, len(s.BySlot))
+	for slot := range s.BySlot {
+		slots = append(slots, slot)
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		ti, tj := s.BySlot[slots[i]].Total(), s.BySlot[slots[j]].Total()
+		if ti != tj {
+			return ti > tj
+		}
+		return slots[i] < slots[j]
+	})
+	if len(slots) > n {
+		slots = slots[:n]
+	}
+	return slots
+}
+
+// RedirectTracer counts the MOVED and ASK redirects that go-redis follows
+// silently, per node, slot and command. It is installed as a hook on every
+// node connection of the cluster clients.
+type RedirectTracer struct {
+	mu        sync.Mutex
+	since     time.Time
+	commands  int64
+	total     RedirectCount
+	byNode    map[string]RedirectCount
+	bySlot    map[int]RedirectCount
+	byCommand map[string]RedirectCount
+	recent    []RedirectEvent
+	logging   bool
+}
+
+// NewRedirectTracer creates an empty tracer
+func NewRedirectTracer() *RedirectTracer {
+	t := &RedirectTracer{}
+	t.Reset()
+	return t
+}
+
+// SetLogging enables logging every redirect as it happens
+func (t *RedirectTracer) SetLogging(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logging = enabled
+}
+
+// Reset clears all counters
+func (t *RedirectTracer) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.since = time.Now()
+	t.commands = 0
+	t.total = RedirectCount{}
+	t.byNode = make(map[string]RedirectCount)
+	t.bySlot = make(map[int]RedirectCount)
+	t.byCommand = make(map[string]RedirectCount)
+	t.recent = nil
+}
+
+// Snapshot returns a copy of the counters
+func (t *RedirectTracer) Snapshot() RedirectStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := RedirectStats{
+		Since:     t.since,
+		Commands:  t.commands,
+		Total:     t.total,
+		ByNode:    make(map[string]RedirectCount, len(t.byNode)),
+		BySlot:    make(map[int]RedirectCount, len(t.bySlot)),
+		ByCommand: make(map[string]RedirectCount, len(t.byCommand)),
+		Recent:    append([]RedirectEvent(nil), t.recent...),
+	}
+	for k, v := range t.byNode {
+		s.ByNode[k] = v
+	}
+	for k, v := range t.bySlot {
+		s.BySlot[k] = v
+	}
+	for k, v := range t.byCommand {
+		s.ByCommand[k] = v
+	}
+	return s
+}
+
+// observe records the outcome of one command sent to node
+func (t *RedirectTracer) observe(node string, cmd redis.Cmder) {
+	kind, slot, target, ok := parseRedirect(cmd.Err())
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.commands++
+	if !ok {
+		return
+	}
+
+	name := strings.ToUpper(cmd.Name())
+	ev := RedirectEvent{Time: time.Now(), Kind: kind, Node: node, Target: target, Slot: slot, Command: name}
+
+	t.total.add(kind)
+	c := t.byNode[node]
+	c.add(kind)
+	t.byNode[node] = c
+	c = t.bySlot[slot]
+	c.add(kind)
+	t.bySlot[slot] = c
+	c = t.byCommand[name]
+	c.add(kind)
+	t.byCommand[name] = c
+
+	if len(t.recent) == recentRedirects {
+		t.recent = append(t.recent[:0], t.recent[1:]...)
+	}
+	t.recent = append(t.recent, ev)
+
+	if t.logging {
+		log.Printf("[Redirect] %s slot %d %s: %s -> %s", kind, slot, name, node, target)
+	}
+}
+
+// parseRedirect extracts kind, slot and target from "MOVED 3999 host:port"
+// or "ASK 3999 host:port"
+func parseRedirect(err error) (RedirectKind, int, string, bool) {
+	if err == nil {
+		return "", 0, "", false
+	}
+	fields := strings.Fields(err.Error())
+	if len(fields) != 3 || (fields[0] != string(RedirectMoved) && fields[0] != string(RedirectAsk)) {
+		return "", 0, "", false
+	}
+	slot, convErr := strconv.Atoi(fields[1])
+	if convErr != nil {
+		return "", 0, "", false
+	}
+	return RedirectKind(fields[0]), slot, fields[2], true
+}
+
+// hook returns the go-redis hook for the connection to node
+func (t *RedirectTracer) hook(node string) redis.Hook {
+	return tracerHook{tracer: t, node: node}
+}
+
+// tracerHook feeds every command a node answers into a RedirectTracer
+type tracerHook struct {
+	tracer *RedirectTracer
+	node   string
+}
+
+func (h tracerHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h tracerHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		h.tracer.observe(h.node, cmd)
+		return err
+	}
+}
+
+func (h tracerHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			h.tracer.observe(h.node, cmd)
+		}
+		return err
+	}
+}
+
+// Redirects returns the tracer shared by the client's cluster connections
+func (c *Client) Redirects() *RedirectTracer {
+	return c.tracer
+}
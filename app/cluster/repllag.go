@@ -0,0 +1,311 @@
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReplicaLag is the replication state of one replica
+type ReplicaLag struct {
+	Replica    string `json:"replica"`
+	Master     string `json:"master,omitempty"`
+	LinkUp     bool   `json:"link_up"`
+	LagBytes   int64  `json:"lag_bytes"`   // master_repl_offset minus the replica's offset
+	LagSeconds int64  `json:"lag_seconds"` // seconds since the master heard from the replica
+	Error      string `json:"error,omitempty"`
+
+	Breach string `json:"breach,omitempty"` // why the replica is over a threshold, "" if healthy
+}
+
+// ReplLagThresholds decide when a replica counts as lagging. Zero disables a
+// threshold; a broken replication link always counts.
+type ReplLagThresholds struct {
+	MaxBytes int64         // offset difference
+	MaxDelay time.Duration // time since the master last heard from the replica
+}
+
+// check sets r.Breach according to the thresholds
+func (t ReplLagThresholds) check(r *ReplicaLag) {
+	switch {
+	case r.Error != "":
+		r.Breach = "unreachable: " + r.Error
+	case !r.LinkUp:
+		r.Breach = "replication link down"
+	case t.MaxBytes > 0 && r.LagBytes > t.MaxBytes:
+		r.Breach = fmt.Sprintf("%d bytes behind (max %d)", r.LagBytes, t.MaxBytes)
+	case t.MaxDelay > 0 && time.Duration(r.LagSeconds)*time.Second > t.MaxDelay:
+		r.Breach = fmt.Sprintf("%ds since last ack (max %v)", r.LagSeconds, t.MaxDelay)
+	}
+}
+
+// ReplLagReport is the result of one replication lag check
+type ReplLagReport struct {
+	Time     time.Time    `json:"time"`
+	Degraded bool         `json:"degraded"`
+	Replicas []ReplicaLag `json:"replicas"`
+}
+
+// Lagging returns the replicas that breach a threshold
+func (r *ReplLagReport) Lagging() []ReplicaLag {
+	var out []ReplicaLag
+	for _, rl := range r.Replicas {
+		if rl.Breach != "" {
+			out = append(out, rl)
+		}
+	}
+	return out
+}
+
+// CheckReplLag compares every master's master_repl_offset with the offset and
+// link status of each of its replicas
+func (c *Client) CheckReplLag(ctx context.Context, thresholds ReplLagThresholds) (*ReplLagReport, error) {
+	infos, err := c.NodeInfos(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byAddr := make(map[string]*NodeInfo, len(infos))
+	for _, info := range infos {
+		byAddr[info.Addr] = info
+	}
+
+	report := &ReplLagReport{Time: time.Now()}
+	for _, info := range infos {
+		if info.Error != "" {
+			rl := ReplicaLag{Replica: info.Addr, LagBytes: -1, Error: info.Error}
+			thresholds.check(&rl)
+			report.Replicas = append(report.Replicas, rl)
+			continue
+		}
+		repl := info.Replication
+		if !repl.IsReplica() {
+			continue
+		}
+
+		rl := ReplicaLag{
+			Replica:    info.Addr,
+			Master:     repl.MasterAddr,
+			LinkUp:     repl.MasterLinkStatus == "up",
+			LagBytes:   -1,
+			LagSeconds: repl.MasterLastIO,
+		}
+		if master, ok := byAddr[repl.MasterAddr]; ok && master.Error == "" {
+			rl.LagBytes = master.Replication.MasterReplOffset - repl.SlaveReplOffset
+			if rl.LagBytes < 0 {
+				rl.LagBytes = 0
+			}
+			for _, r := range master.Replication.Replicas {
+				if r.Addr == info.Addr {
+					rl.LagSeconds = r.LagSecs
+				}
+			}
+		}
+		thresholds.check(&rl)
+		report.Replicas = append(report.Replicas, rl)
+	}
+
+	sort.Slice(report.Replicas, func(i, j int) bool { return report.Replicas[i].Replica < report.Replicas[j].Replica })
+	report.Degraded = len(report.Lagging()) > 0
+	return report, nil
+}
+
+// LagAlert is sent when a replica starts or stops breaching a threshold
+type LagAlert struct {
+	Time     time.Time  `json:"time"`
+	Resolved bool       `json:"resolved"` // false: replica started lagging, true: it caught up
+	Replica  ReplicaLag `json:"replica"`
+}
+
+func (a LagAlert) String() string {
+	if a.Resolved {
+		return fmt.Sprintf("replica %s caught up with %s (%d bytes behind)",
+			a.Replica.Replica, a.Replica.Master, a.Replica.LagBytes)
+	}
+	return fmt.Sprintf("replica %s of %s is lagging: %s", a.Replica.Replica, a.Replica.Master, a.Replica.Breach)
+}
+
+// AlertSink receives replication lag alerts
+type AlertSink interface {
+	Send(alert LagAlert) error
+}
+
+// LogSink writes alerts to the standard logger
+type LogSink struct{}
+
+// Send implements AlertSink
+func (LogSink) Send(alert LagAlert) error {
+	log.Printf("[ReplLag] %s", alert)
+	return nil
+}
+
+// JSONSink writes one JSON alert per line, e.g. to stdout
+type JSONSink struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONSink creates a sink writing to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// Send implements AlertSink
+func (s *JSONSink) Send(alert LagAlert) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enc.Encode(alert)
+}
+
+// WebhookSink POSTs each alert as JSON to a URL
+type WebhookSink struct {
+	URL    string
+	Client *http.Client
+}
+
+// NewWebhookSink creates a sink posting to url with a 5s timeout
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{URL: url, Client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// Send implements AlertSink
+func (s *WebhookSink) Send(alert LagAlert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", s.URL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s: %s", s.URL, resp.Status)
+	}
+	return nil
+}
+
+// ReplLagMonitor checks replication lag periodically, keeps the latest
+// report and alerts the sinks whenever a replica starts or stops lagging
+type ReplLagMonitor struct {
+	client     *Client
+	interval   time.Duration
+	thresholds ReplLagThresholds
+	sinks      []AlertSink
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+
+	mu      sync.RWMutex
+	last    *ReplLagReport
+	lastErr error
+	lagging map[string]bool // replicas currently in breach
+}
+
+// NewReplLagMonitor creates a monitor that checks every interval (default 5s)
+func NewReplLagMonitor(client *Client, interval time.Duration, thresholds ReplLagThresholds, sinks ...AlertSink) *ReplLagMonitor {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(client.Context())
+	return &ReplLagMonitor{
+		client:     client,
+		interval:   interval,
+		thresholds: thresholds,
+		sinks:      sinks,
+		ctx:        ctx,
+		cancel:     cancel,
+		lagging:    make(map[string]bool),
+	}
+}
+
+// Start runs the first check and then checks in the background
+func (m *ReplLagMonitor) Start() {
+	m.Check()
+	m.wg.Add(1)
+	go m.loop()
+}
+
+// Stop stops the background checks
+func (m *ReplLagMonitor) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// Report returns the latest report and the error of the latest check
+func (m *ReplLagMonitor) Report() (*ReplLagReport, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.last, m.lastErr
+}
+
+// Degraded reports whether the latest check found a lagging replica or failed
+func (m *ReplLagMonitor) Degraded() bool {
+	report, err := m.Report()
+	return err != nil || (report != nil && report.Degraded)
+}
+
+func (m *ReplLagMonitor) loop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.Check()
+		case <-m.ctx.Done():
+			return
+		}
+	}
+}
+
+// Check runs one lag check now and alerts on changes
+func (m *ReplLagMonitor) Check() {
+	ctx, cancel := context.WithTimeout(m.ctx, m.interval)
+	defer cancel()
+	report, err := m.client.CheckReplLag(ctx, m.thresholds)
+
+	m.mu.Lock()
+	m.lastErr = err
+	if err != nil {
+		m.mu.Unlock()
+		log.Printf("[ReplLag] check failed: %v", err)
+		return
+	}
+	m.last = report
+
+	var alerts []LagAlert
+	seen := make(map[string]bool, len(report.Replicas))
+	for _, rl := range report.Replicas {
+		seen[rl.Replica] = true
+		breach := rl.Breach != ""
+		if breach != m.lagging[rl.Replica] {
+			alerts = append(alerts, LagAlert{Time: report.Time, Resolved: !breach, Replica: rl})
+		}
+		m.lagging[rl.Replica] = breach
+	}
+	// Replicas that disappeared (removed, promoted) no longer count
+	for addr := range m.lagging {
+		if !seen[addr] {
+			delete(m.lagging, addr)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, alert := range alerts {
+		for _, sink := range m.sinks {
+			if err := sink.Send(alert); err != nil {
+				log.Printf("[ReplLag] alert sink: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"ticket-reservation/models"
+)
+
+// MigrateOptions tunes how keys are moved for a slot
+type MigrateOptions struct {
+	BatchSize int           // keys per GETKEYSINSLOT/MIGRATE round (default 100)
+	Timeout   time.Duration // MIGRATE timeout (default 5s)
+	Replace   bool          // overwrite keys that already exist on the target
+}
+
+func (o MigrateOptions) withDefaults() MigrateOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 5 * time.Second
+	}
+	return o
+}
+
+// SetSlotImporting runs CLUSTER SETSLOT <slot> IMPORTING <source> on the target
+func (c *Client) SetSlotImporting(ctx context.Context, target models.ClusterNode, slot int, sourceID string) error {
+	return c.NodeClient(target.Address).Do(ctx, "CLUSTER", "SETSLOT", slot, "IMPORTING", sourceID).Err()
+}
+
+// SetSlotMigrating runs CLUSTER SETSLOT <slot> MIGRATING <target> on the source
+func (c *Client) SetSlotMigrating(ctx context.Context, source models.ClusterNode, slot int, targetID string) error {
+	return c.NodeClient(source.Address).Do(ctx, "CLUSTER", "SETSLOT", slot, "MIGRATING", targetID).Err()
+}
+
+// SetSlotNode runs CLUSTER SETSLOT <slot> NODE <owner> on node
+func (c *Client) SetSlotNode(ctx context.Context, node models.ClusterNode, slot int, ownerID string) error {
+	return c.NodeClient(node.Address).Do(ctx, "CLUSTER", "SETSLOT", slot, "NODE", ownerID).Err()
+}
+
+// SetSlotStable runs CLUSTER SETSLOT <slot> STABLE on node
+func (c *Client) SetSlotStable(ctx context.Context, node models.ClusterNode, slot int) error {
+	return c.NodeClient(node.Address).Do(ctx, "CLUSTER", "SETSLOT", slot, "STABLE").Err()
+}
+
+// CountKeysInSlot runs CLUSTER COUNTKEYSINSLOT on node
+func (c *Client) CountKeysInSlot(ctx context.Context, node models.ClusterNode, slot int) (int64, error) {
+	return c.NodeClient(node.Address).ClusterCountKeysInSlot(ctx, slot).Result()
+}
+
+// MigrateKeys moves every key of slot from source to target with
+// GETKEYSINSLOT + MIGRATE batches and returns how many keys were moved.
+// The slot must already be in IMPORTING/MIGRATING state on both nodes.
+func (c *Client) MigrateKeys(ctx context.Context, slot int, source, target models.ClusterNode, opts MigrateOptions) (int, error) {
+	opts = opts.withDefaults()
+	src := c.NodeClient(source.Address)
+
+	// MIGRATE is executed by the source, so it needs the address the target
+	// announces to the cluster, not the remapped one we dial
+	host, port, err := net.SplitHostPort(target.Address)
+	if err != nil {
+		return 0, fmt.Errorf("invalid target address %s: %w", target.Address, err)
+	}
+
+	moved := 0
+	for {
+		keys, err := src.ClusterGetKeysInSlot(ctx, slot, opts.BatchSize).Result()
+		if err != nil {
+			return moved, fmt.Errorf("GETKEYSINSLOT %d: %w", slot, err)
+		}
+		if len(keys) == 0 {
+			return moved, nil
+		}
+
+		args := []interface{}{"MIGRATE", host, port, "", 0, opts.Timeout.Milliseconds()}
+		if opts.Replace {
+			args = append(args, "REPLACE")
+		}
+		args = append(args, "KEYS")
+		for _, k := range keys {
+			args = append(args, k)
+		}
+
+		if err := src.Do(ctx, args...).Err(); err != nil {
+			if strings.Contains(err.Error(), "BUSYKEY") {
+				return moved, fmt.Errorf("MIGRATE slot %d: target already has some keys (retry with replace): %w", slot, err)
+			}
+			return moved, fmt.Errorf("MIGRATE slot %d: %w", slot, err)
+		}
+		moved += len(keys)
+	}
+}
+
+// MoveSlot performs the full redis-cli reshard sequence for one slot:
+// IMPORTING on the target, MIGRATING on the source, key migration, then
+// SETSLOT NODE on target, source and every other master.
+func (c *Client) MoveSlot(ctx context.Context, slot int, source, target models.ClusterNode, masters []models.ClusterNode, opts MigrateOptions) (int, error) {
+	if err := c.SetSlotImporting(ctx, target, slot, source.ID); err != nil {
+		return 0, fmt.Errorf("SETSLOT %d IMPORTING on %s: %w", slot, target.Address, err)
+	}
+	if err := c.SetSlotMigrating(ctx, source, slot, target.ID); err != nil {
+		return 0, fmt.Errorf("SETSLOT %d MIGRATING on %s: %w", slot, source.Address, err)
+	}
+
+	moved, err := c.MigrateKeys(ctx, slot, source, target, opts)
+	if err != nil {
+		return moved, err
+	}
+
+	// Target first, so it never answers MOVED back to the source
+	if err := c.SetSlotNode(ctx, target, slot, target.ID); err != nil {
+		return moved, fmt.Errorf("SETSLOT %d NODE on %s: %w", slot, target.Address, err)
+	}
+	if err := c.SetSlotNode(ctx, source, slot, target.ID); err != nil {
+		return moved, fmt.Errorf("SETSLOT %d NODE on %s: %w", slot, source.Address, err)
+	}
+	for _, m := range masters {
+		if m.ID == source.ID || m.ID == target.ID {
+			continue
+		}
+		// Best effort: gossip propagates the new owner to nodes we miss
+		c.SetSlotNode(ctx, m, slot, target.ID)
+	}
+
+	return moved, nil
+}
+
+// ReshardPlan is a persisted list of slots to move from one master to another
+type ReshardPlan struct {
+	SourceID  string    `json:"source_id"`
+	TargetID  string    `json:"target_id"`
+	Slots     []int     `json:"slots"`
+	Done      []int     `json:"done"`
+	KeysMoved int       `json:"keys_moved"`
+	BatchSize int       `json:"batch_size"`
+	Timeout   Duration  `json:"timeout"`
+	Replace   bool      `json:"replace"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewReshardPlan picks count slots owned by source (lowest first) to move to target
+func (c *Client) NewReshardPlan(sourceRef, targetRef string, count int) (*ReshardPlan, error) {
+	source, err := c.ResolveNode(sourceRef)
+	if err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	target, err := c.ResolveNode(targetRef)
+	if err != nil {
+		return nil, fmt.Errorf("target: %w", err)
+	}
+	if source.ID == target.ID {
+		return nil, fmt.Errorf("source and target are the same node")
+	}
+	if source.Role != "master" || target.Role != "master" {
+		return nil, fmt.Errorf("source and target must both be masters")
+	}
+	if count <= 0 {
+		return nil, fmt.Errorf("slot count must be positive")
+	}
+	if count > source.SlotCount() {
+		return nil, fmt.Errorf("source %s only owns %d slots", source.Address, source.SlotCount())
+	}
+
+	var slots []int
+	for _, r := range source.SlotRanges {
+		for s := r.Start; s <= r.End && len(slots) < count; s++ {
+			slots = append(slots, s)
+		}
+	}
+
+	now := time.Now()
+	return &ReshardPlan{
+		SourceID:  source.ID,
+		TargetID:  target.ID,
+		Slots:     slots,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// LoadReshardPlan reads a plan saved by Save
+func LoadReshardPlan(path string) (*ReshardPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reshard plan: %w", err)
+	}
+	var plan ReshardPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse reshard plan %s: %w", path, err)
+	}
+	return &plan, nil
+}
+
+// Save writes the plan atomically (temp file + rename)
+func (p *ReshardPlan) Save(path string) error {
+	p.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write reshard plan: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Pending returns the slots not yet moved, in plan order
+func (p *ReshardPlan) Pending() []int {
+	done := make(map[int]bool, len(p.Done))
+	for _, s := range p.Done {
+		done[s] = true
+	}
+	var pending []int
+	for _, s := range p.Slots {
+		if !done[s] {
+			pending = append(pending, s)
+		}
+	}
+	return pending
+}
+
+// SlotRanges compacts the plan's slots into ranges for display
+func (p *ReshardPlan) SlotRanges() []models.SlotRange {
+	return CompactSlots(p.Slots)
+}
+
+// CompactSlots turns a list of slots into sorted contiguous ranges
+func CompactSlots(slots []int) []models.SlotRange {
+	sorted := append([]int(nil), slots...)
+	sort.Ints(sorted)
+
+	var ranges []models.SlotRange
+	for _, s := range sorted {
+		if n := len(ranges); n > 0 && ranges[n-1].End+1 == s {
+			ranges[n-1].End = s
+			continue
+		}
+		ranges = append(ranges, models.SlotRange{Start: s, End: s})
+	}
+	return ranges
+}
+
+// SlotProgress reports the outcome of one slot during a reshard
+type SlotProgress struct {
+	Slot      int
+	Index     int // 1-based position among the slots processed in this run
+	Total     int // slots to process in this run
+	KeysMoved int
+	Skipped   bool // target already owned the slot
+	Elapsed   time.Duration
+}
+
+// ExecuteReshard moves every pending slot of plan, saving progress to
+// planPath after each slot so an interrupted run can be resumed. Cancelling
+// ctx stops between slots; the slot in progress is always completed.
+func (c *Client) ExecuteReshard(ctx context.Context, plan *ReshardPlan, planPath string, progress func(SlotProgress)) error {
+	nodes, err := c.GetClusterNodes()
+	if err != nil {
+		return err
+	}
+
+	var source, target models.ClusterNode
+	var masters []models.ClusterNode
+	for _, n := range nodes {
+		if n.Role == "master" {
+			masters = append(masters, n)
+		}
+		switch n.ID {
+		case plan.SourceID:
+			source = n
+		case plan.TargetID:
+			target = n
+		}
+	}
+	if source.ID == "" || target.ID == "" {
+		return fmt.Errorf("source or target node from the plan is no longer in the cluster")
+	}
+
+	opts := MigrateOptions{
+		BatchSize: plan.BatchSize,
+		Timeout:   time.Duration(plan.Timeout),
+		Replace:   plan.Replace,
+	}
+
+	pending := plan.Pending()
+	for i, slot := range pending {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		start := time.Now()
+		p := SlotProgress{Slot: slot, Index: i + 1, Total: len(pending)}
+
+		if target.OwnsSlot(slot) {
+			// Already moved by a previous run that died before saving; make
+			// sure the remaining nodes agree on the owner
+			for _, m := range masters {
+				if m.ID != target.ID {
+					c.SetSlotNode(context.Background(), m, slot, target.ID)
+				}
+			}
+			p.Skipped = true
+		} else {
+			// Use a fresh context so cancellation never leaves the slot half-moved
+			moved, err := c.MoveSlot(context.Background(), slot, source, target, masters, opts)
+			plan.KeysMoved += moved
+			p.KeysMoved = moved
+			if err != nil {
+				if planPath != "" {
+					plan.Save(planPath)
+				}
+				return err
+			}
+		}
+
+		plan.Done = append(plan.Done, slot)
+		if planPath != "" {
+			if err := plan.Save(planPath); err != nil {
+				return err
+			}
+		}
+
+		p.Elapsed = time.Since(start)
+		if progress != nil {
+			progress(p)
+		}
+	}
+
+	c.slots.Invalidate()
+	return nil
+}
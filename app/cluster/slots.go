@@ -0,0 +1,202 @@
+package cluster
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ticket-reservation/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// CRC16 calculates the CRC16-CCITT (XMODEM) checksum Redis uses for key slots
+func CRC16(key string) uint16 {
+	crc := uint16(0)
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = (crc << 1) ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// HashTag returns the part of key that is hashed: the content of the first
+// {...} if it is non-empty, otherwise the whole key
+func HashTag(key string) string {
+	start := strings.Index(key, "{")
+	if start == -1 {
+		return key
+	}
+	end := strings.Index(key[start+1:], "}")
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+// KeySlot computes the hash slot for key locally, without a server round trip
+func KeySlot(key string) int {
+	return int(CRC16(HashTag(key)) % TotalSlots)
+}
+
+// SlotTable is a cached slot -> master mapping built from CLUSTER NODES.
+// It is invalidated by MOVED redirects and refreshed lazily on the next lookup.
+type SlotTable struct {
+	mu          sync.RWMutex
+	owners      [TotalSlots]string // slot -> master node ID
+	nodes       map[string]models.ClusterNode
+	refreshedAt time.Time
+	stale       bool
+	moved       atomic.Int64 // MOVED redirects seen since creation
+}
+
+// NewSlotTable creates an empty (stale) slot table
+func NewSlotTable() *SlotTable {
+	return &SlotTable{stale: true}
+}
+
+// Update rebuilds the table from a CLUSTER NODES snapshot
+func (t *SlotTable) Update(nodes []models.ClusterNode) {
+	var owners [TotalSlots]string
+	byID := make(map[string]models.ClusterNode, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+		if n.Role != "master" {
+			continue
+		}
+		for _, r := range n.SlotRanges {
+			for s := r.Start; s <= r.End && s < TotalSlots; s++ {
+				owners[s] = n.ID
+			}
+		}
+	}
+
+	t.mu.Lock()
+	t.owners = owners
+	t.nodes = byID
+	t.refreshedAt = time.Now()
+	t.stale = false
+	t.mu.Unlock()
+}
+
+// Invalidate marks the table stale so the next lookup refreshes it
+func (t *SlotTable) Invalidate() {
+	t.mu.Lock()
+	t.stale = true
+	t.mu.Unlock()
+}
+
+// MovedCount returns how many MOVED redirects the client has followed
+func (t *SlotTable) MovedCount() int64 {
+	return t.moved.Load()
+}
+
+// noteMoved records a MOVED redirect and invalidates the table
+func (t *SlotTable) noteMoved() {
+	t.moved.Add(1)
+	t.Invalidate()
+}
+
+// Stale reports whether the table needs a refresh
+func (t *SlotTable) Stale() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.stale
+}
+
+// RefreshedAt returns when the table was last rebuilt
+func (t *SlotTable) RefreshedAt() time.Time {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.refreshedAt
+}
+
+// Owner returns the master that owns slot, if any
+func (t *SlotTable) Owner(slot int) (models.ClusterNode, bool) {
+	if slot < 0 || slot >= TotalSlots {
+		return models.ClusterNode{}, false
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	node, ok := t.nodes[t.owners[slot]]
+	return node, ok
+}
+
+// Node returns a node by ID
+func (t *SlotTable) Node(id string) (models.ClusterNode, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	node, ok := t.nodes[id]
+	return node, ok
+}
+
+// Nodes returns every node known to the table
+func (t *SlotTable) Nodes() []models.ClusterNode {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	nodes := make([]models.ClusterNode, 0, len(t.nodes))
+	for _, n := range t.nodes {
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// movedHook invalidates the slot table whenever a node answers with MOVED
+type movedHook struct {
+	table *SlotTable
+}
+
+func (h movedHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h movedHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		if isMoved(err) {
+			h.table.noteMoved()
+		}
+		return err
+	}
+}
+
+func (h movedHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		for _, cmd := range cmds {
+			if isMoved(cmd.Err()) {
+				h.table.noteMoved()
+			}
+		}
+		return err
+	}
+}
+
+// isMoved reports whether err is a MOVED redirect
+func isMoved(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "MOVED ")
+}
+
+// Slots returns the client's slot table, refreshing it first if it is stale
+func (c *Client) Slots() (*SlotTable, error) {
+	if c.slots.Stale() {
+		if err := c.RefreshSlots(); err != nil {
+			return nil, err
+		}
+	}
+	return c.slots, nil
+}
+
+// RefreshSlots rebuilds the slot table from CLUSTER NODES
+func (c *Client) RefreshSlots() error {
+	_, err := c.GetClusterNodes()
+	return err
+}
@@ -0,0 +1,388 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ticket-reservation/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TotalSlots is the number of hash slots in a Redis cluster
+const TotalSlots = 16384
+
+// Topology is a point-in-time view of the cluster
+type Topology struct {
+	CapturedAt time.Time
+	Nodes      map[string]*models.ClusterNode // by node ID
+	Owners     [TotalSlots]string             // slot -> master node ID ("" = unassigned)
+}
+
+// Migrations returns all open slot migrations in the snapshot
+func (t *Topology) Migrations() []models.SlotMigration {
+	nodes := make([]models.ClusterNode, 0, len(t.Nodes))
+	for _, n := range t.Nodes {
+		nodes = append(nodes, *n)
+	}
+	return FindMigrations(nodes)
+}
+
+// GetTopology captures a topology snapshot. Slot ownership comes from one
+// node's CLUSTER NODES; PFAIL/FAIL flags are merged from every reachable
+// node because PFAIL is only ever set in the observing node's local view,
+// and IMPORTING/MIGRATING markers from each node's own entry because no
+// node reports another's.
+func (c *Client) GetTopology() (*Topology, error) {
+	nodesStr, err := c.rdb.ClusterNodes(c.ctx).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster nodes: %w", err)
+	}
+	nodes := ParseClusterNodes(nodesStr)
+	c.slots.Update(nodes)
+	topo := newTopology(nodes)
+
+	var mu sync.Mutex
+	c.rdb.ForEachShard(c.ctx, func(ctx context.Context, client *redis.Client) error {
+		view, err := client.ClusterNodes(ctx).Result()
+		if err != nil {
+			return nil // unreachable nodes are reported through the other nodes' flags
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, n := range ParseClusterNodes(view) {
+			if base, ok := topo.Nodes[n.ID]; ok {
+				base.PFail = base.PFail || n.PFail
+				base.Fail = base.Fail || n.Fail
+				if n.Myself {
+					mergeMarkers(base, n)
+				}
+			}
+		}
+		return nil
+	})
+
+	return topo, nil
+}
+
+// newTopology indexes parsed nodes into a snapshot
+func newTopology(nodes []models.ClusterNode) *Topology {
+	topo := &Topology{
+		CapturedAt: time.Now(),
+		Nodes:      make(map[string]*models.ClusterNode, len(nodes)),
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		topo.Nodes[n.ID] = n
+		if n.Role != "master" {
+			continue
+		}
+		for _, r := range n.SlotRanges {
+			for s := r.Start; s <= r.End && s < TotalSlots; s++ {
+				topo.Owners[s] = n.ID
+			}
+		}
+	}
+	return topo
+}
+
+// TopologyEventType identifies a kind of topology change
+type TopologyEventType string
+
+const (
+	EventNodeAdded         TopologyEventType = "node_added"
+	EventNodeRemoved       TopologyEventType = "node_removed"
+	EventRoleChanged       TopologyEventType = "role_changed"
+	EventFailover          TopologyEventType = "failover"
+	EventSlotsMoved        TopologyEventType = "slots_moved"
+	EventMigrationOpened   TopologyEventType = "migration_opened"
+	EventMigrationClosed   TopologyEventType = "migration_closed"
+	EventNodePFail         TopologyEventType = "node_pfail"
+	EventNodeFail          TopologyEventType = "node_fail"
+	EventNodeRecovered     TopologyEventType = "node_recovered"
+	EventTopologyPollError TopologyEventType = "poll_error"
+)
+
+// TopologyEvent is a single change between two successive snapshots
+type TopologyEvent struct {
+	Time    time.Time         `json:"time"`
+	Type    TopologyEventType `json:"type"`
+	NodeID  string            `json:"node_id,omitempty"`
+	Address string            `json:"address,omitempty"`
+	From    string            `json:"from,omitempty"` // source node ID (slots, migrations, old master)
+	To      string            `json:"to,omitempty"`   // target node ID (slots, migrations, new master)
+	Slots   *models.SlotRange `json:"slots,omitempty"`
+	Message string            `json:"message"`
+}
+
+// String formats the event as a single log line
+func (e TopologyEvent) String() string {
+	return fmt.Sprintf("%s [%s] %s", e.Time.Format("15:04:05.000"), strings.ToUpper(string(e.Type)), e.Message)
+}
+
+// DiffTopology returns the events that turn prev into cur
+func DiffTopology(prev, cur *Topology) []TopologyEvent {
+	now := cur.CapturedAt
+	var events []TopologyEvent
+
+	ids := make([]string, 0, len(cur.Nodes)+len(prev.Nodes))
+	for id := range cur.Nodes {
+		ids = append(ids, id)
+	}
+	for id := range prev.Nodes {
+		if _, ok := cur.Nodes[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		old, wasKnown := prev.Nodes[id]
+		n, isKnown := cur.Nodes[id]
+
+		switch {
+		case !wasKnown:
+			events = append(events, TopologyEvent{
+				Time: now, Type: EventNodeAdded, NodeID: id, Address: n.Address,
+				Message: fmt.Sprintf("node %s (%s) joined as %s", ShortID(id), n.Address, n.Role),
+			})
+			continue
+		case !isKnown:
+			events = append(events, TopologyEvent{
+				Time: now, Type: EventNodeRemoved, NodeID: id, Address: old.Address,
+				Message: fmt.Sprintf("node %s (%s) left the cluster", ShortID(id), old.Address),
+			})
+			continue
+		}
+
+		if old.Role == "replica" && n.Role == "master" {
+			events = append(events, TopologyEvent{
+				Time: now, Type: EventFailover, NodeID: id, Address: n.Address,
+				From: old.MasterID, To: id,
+				Message: fmt.Sprintf("replica %s (%s) promoted to master, replacing %s", ShortID(id), n.Address, ShortID(old.MasterID)),
+			})
+		} else if old.Role != n.Role || old.MasterID != n.MasterID {
+			msg := fmt.Sprintf("node %s (%s) is now %s", ShortID(id), n.Address, n.Role)
+			if n.MasterID != "" {
+				msg += " of " + ShortID(n.MasterID)
+			}
+			events = append(events, TopologyEvent{
+				Time: now, Type: EventRoleChanged, NodeID: id, Address: n.Address,
+				From: old.MasterID, To: n.MasterID, Message: msg,
+			})
+		}
+
+		switch {
+		case n.Fail && !old.Fail:
+			events = append(events, TopologyEvent{
+				Time: now, Type: EventNodeFail, NodeID: id, Address: n.Address,
+				Message: fmt.Sprintf("node %s (%s) marked FAIL", ShortID(id), n.Address),
+			})
+		case n.PFail && !old.PFail && !n.Fail:
+			events = append(events, TopologyEvent{
+				Time: now, Type: EventNodePFail, NodeID: id, Address: n.Address,
+				Message: fmt.Sprintf("node %s (%s) marked PFAIL", ShortID(id), n.Address),
+			})
+		case (old.Fail || old.PFail) && !n.Fail && !n.PFail:
+			events = append(events, TopologyEvent{
+				Time: now, Type: EventNodeRecovered, NodeID: id, Address: n.Address,
+				Message: fmt.Sprintf("node %s (%s) reachable again", ShortID(id), n.Address),
+			})
+		}
+	}
+
+	events = append(events, diffSlotOwners(prev, cur)...)
+	events = append(events, diffMigrations(prev, cur)...)
+	return events
+}
+
+// diffSlotOwners groups ownership changes into contiguous ranges per (from, to) pair
+func diffSlotOwners(prev, cur *Topology) []TopologyEvent {
+	var events []TopologyEvent
+	flush := func(from, to string, start, end int) {
+		r := models.SlotRange{Start: start, End: end}
+		events = append(events, TopologyEvent{
+			Time: cur.CapturedAt, Type: EventSlotsMoved, From: from, To: to, Slots: &r,
+			Message: fmt.Sprintf("slots %s (%d) moved %s -> %s", r, r.Count(), ShortID(from), ShortID(to)),
+		})
+	}
+
+	start := -1
+	var from, to string
+	for s := 0; s < TotalSlots; s++ {
+		f, t := prev.Owners[s], cur.Owners[s]
+		if f != t && start >= 0 && f == from && t == to {
+			continue
+		}
+		if start >= 0 {
+			flush(from, to, start, s-1)
+			start = -1
+		}
+		if f != t {
+			start, from, to = s, f, t
+		}
+	}
+	if start >= 0 {
+		flush(from, to, start, TotalSlots-1)
+	}
+	return events
+}
+
+// diffMigrations reports migrations that opened or closed between snapshots
+func diffMigrations(prev, cur *Topology) []TopologyEvent {
+	before := make(map[models.SlotMigration]bool)
+	for _, m := range prev.Migrations() {
+		before[m] = true
+	}
+	after := make(map[models.SlotMigration]bool)
+	for _, m := range cur.Migrations() {
+		after[m] = true
+	}
+
+	var events []TopologyEvent
+	for _, m := range cur.Migrations() {
+		if !before[m] {
+			events = append(events, migrationEvent(cur.CapturedAt, EventMigrationOpened, m, "opened"))
+		}
+	}
+	for _, m := range prev.Migrations() {
+		if !after[m] {
+			events = append(events, migrationEvent(cur.CapturedAt, EventMigrationClosed, m, "closed"))
+		}
+	}
+	return events
+}
+
+func migrationEvent(t time.Time, typ TopologyEventType, m models.SlotMigration, verb string) TopologyEvent {
+	r := models.SlotRange{Start: m.Slot, End: m.Slot}
+	return TopologyEvent{
+		Time: t, Type: typ, From: m.SourceID, To: m.TargetID, Slots: &r,
+		Message: fmt.Sprintf("migration of slot %d %s -> %s %s", m.Slot, ShortID(m.SourceID), ShortID(m.TargetID), verb),
+	}
+}
+
+// ShortID abbreviates a 40-char node ID for display
+func ShortID(id string) string {
+	if id == "" {
+		return "(none)"
+	}
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// TopologyWatcher polls the cluster topology and publishes changes as events
+type TopologyWatcher struct {
+	client   *Client
+	interval time.Duration
+	events   chan TopologyEvent
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu   sync.RWMutex
+	last *Topology
+}
+
+// NewTopologyWatcher creates a watcher that polls every interval (default 1s)
+func NewTopologyWatcher(client *Client, interval time.Duration) *TopologyWatcher {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ctx, cancel := context.WithCancel(client.Context())
+	return &TopologyWatcher{
+		client:   client,
+		interval: interval,
+		events:   make(chan TopologyEvent, 256),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Events returns the event channel. It is closed when the watcher stops.
+func (w *TopologyWatcher) Events() <-chan TopologyEvent {
+	return w.events
+}
+
+// Snapshot returns the most recent topology, or nil before the first poll
+func (w *TopologyWatcher) Snapshot() *Topology {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.last
+}
+
+// Start takes the baseline snapshot and begins polling in the background
+func (w *TopologyWatcher) Start() error {
+	topo, err := w.client.GetTopology()
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.last = topo
+	w.mu.Unlock()
+
+	w.wg.Add(1)
+	go w.pollLoop()
+	return nil
+}
+
+// Stop stops polling and closes the event channel
+func (w *TopologyWatcher) Stop() {
+	w.cancel()
+	w.wg.Wait()
+	close(w.events)
+}
+
+func (w *TopologyWatcher) pollLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.poll()
+		case <-w.ctx.Done():
+			return
+		}
+	}
+}
+
+// poll takes a new snapshot, diffs it against the last one and publishes events
+func (w *TopologyWatcher) poll() {
+	topo, err := w.client.GetTopology()
+	if err != nil {
+		w.publish(TopologyEvent{
+			Time: time.Now(), Type: EventTopologyPollError, Message: err.Error(),
+		})
+		return
+	}
+
+	w.mu.Lock()
+	prev := w.last
+	w.last = topo
+	w.mu.Unlock()
+
+	for _, e := range DiffTopology(prev, topo) {
+		if !w.publish(e) {
+			return
+		}
+	}
+}
+
+// publish sends an event unless the watcher is stopping
+func (w *TopologyWatcher) publish(e TopologyEvent) bool {
+	select {
+	case w.events <- e:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
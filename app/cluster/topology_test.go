@@ -0,0 +1,89 @@
+package cluster_test
+
+import (
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+	"ticket-reservation/models"
+)
+
+// beginMigrationAwayFromSeed opens a migration between the two nodes that
+// go-redis does not send CLUSTER NODES to (it asks the owner of the slot of
+// "nodes"), so that node's view shows no markers
+func beginMigrationAwayFromSeed(t *testing.T, fc *clustertest.Cluster) models.SlotMigration {
+	t.Helper()
+	asked := fc.Owner(cluster.KeySlot("nodes"))
+	var ends []*clustertest.Node
+	for _, n := range fc.Nodes() {
+		if n != asked {
+			ends = append(ends, n)
+		}
+	}
+	source, target := ends[0], ends[1]
+	slot := cluster.KeySlot(keyOn(t, fc, source))
+	fc.BeginMigration(slot, target)
+	return models.SlotMigration{Slot: slot, SourceID: source.ID(), TargetID: target.ID()}
+}
+
+// Only the source and the target list a migration in CLUSTER NODES, so the
+// watcher must see it even when it asks a third node
+func TestTopologyWatcherSeesMigrationOnBothEnds(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	w := cluster.NewTopologyWatcher(client, 20*time.Millisecond)
+	if err := w.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	want := beginMigrationAwayFromSeed(t, fc)
+
+	timeout := time.After(5 * time.Second)
+	for opened := false; !opened; {
+		select {
+		case e := <-w.Events():
+			if e.Type == cluster.EventMigrationClosed {
+				t.Fatalf("migration reported closed while open: %s", e)
+			}
+			opened = e.Type == cluster.EventMigrationOpened && e.From == want.SourceID && e.To == want.TargetID
+		case <-timeout:
+			t.Fatal("no migration_opened event")
+		}
+	}
+
+	// Every snapshot has it, not only those taken from the two ends
+	for i := 0; i < 20; i++ {
+		topo, err := client.GetTopology()
+		if err != nil {
+			t.Fatal(err)
+		}
+		migrations := topo.Migrations()
+		if len(migrations) != 1 || migrations[0] != want {
+			t.Fatalf("snapshot %d: migrations = %+v, want [%+v]", i, migrations, want)
+		}
+	}
+}
+
+func TestClusterInfoListsMigrationsFromBothEnds(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	want := beginMigrationAwayFromSeed(t, fc)
+
+	info, err := client.GetClusterInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Migrations) != 1 || info.Migrations[0] != want {
+		t.Fatalf("migrations = %+v, want [%+v]", info.Migrations, want)
+	}
+}
@@ -0,0 +1,264 @@
+package clustertest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// infoSection is one "# Name" block of INFO output
+type infoSection struct {
+	name   string
+	fields [][2]string
+}
+
+// cmdInfo renders the INFO sections cluster.ParseNodeInfo reads
+func cmdInfo(n *Node, args []string) interface{} {
+	want := "all"
+	if len(args) > 1 {
+		want = strings.ToLower(args[1])
+	}
+	keys, expires := 0, 0
+	for _, k := range n.keysLocked() {
+		keys++
+		if !n.db[k].ExpireAt.IsZero() {
+			expires++
+		}
+	}
+	n.lnMu.Lock()
+	clients := len(n.conns)
+	n.lnMu.Unlock()
+	_, port, _ := strings.Cut(n.addr, ":")
+
+	sections := []infoSection{
+		{"server", [][2]string{
+			{"redis_version", "7.2.0"},
+			{"redis_mode", "cluster"},
+			{"tcp_port", port},
+			{"uptime_in_seconds", strconv.Itoa(int(time.Since(n.started).Seconds()))},
+		}},
+		{"clients", [][2]string{
+			{"connected_clients", strconv.Itoa(clients)},
+			{"blocked_clients", "0"},
+		}},
+		{"memory", [][2]string{
+			{"used_memory", strconv.Itoa(1<<20 + 100*keys)},
+			{"used_memory_peak", strconv.Itoa(1<<20 + 100*keys)},
+			{"maxmemory", n.config["maxmemory"]},
+			{"maxmemory_policy", n.config["maxmemory-policy"]},
+			{"mem_fragmentation_ratio", "1.00"},
+		}},
+		{"stats", [][2]string{
+			{"total_commands_processed", strconv.FormatInt(n.commands.Load(), 10)},
+			{"instantaneous_ops_per_sec", "0"},
+			{"keyspace_hits", strconv.FormatInt(n.hits.Load(), 10)},
+			{"keyspace_misses", strconv.FormatInt(n.misses.Load(), 10)},
+			{"expired_keys", strconv.FormatInt(n.expired.Load(), 10)},
+			{"evicted_keys", "0"},
+			{"instantaneous_input_kbps", "0.00"},
+			{"instantaneous_output_kbps", "0.00"},
+		}},
+		{"replication", [][2]string{
+			{"role", "master"},
+			{"connected_slaves", "0"},
+			{"master_repl_offset", "0"},
+		}},
+		{"cluster", [][2]string{
+			{"cluster_enabled", "1"},
+		}},
+	}
+	keyspace := infoSection{name: "keyspace"}
+	if keys > 0 {
+		keyspace.fields = [][2]string{{"db0", fmt.Sprintf("keys=%d,expires=%d,avg_ttl=0", keys, expires)}}
+	}
+	sections = append(sections, keyspace)
+
+	var b strings.Builder
+	for _, s := range sections {
+		if want != "all" && want != "everything" && want != "default" && want != s.name {
+			continue
+		}
+		fmt.Fprintf(&b, "# %s\r\n", strings.ToUpper(s.name[:1])+s.name[1:])
+		for _, f := range s.fields {
+			fmt.Fprintf(&b, "%s:%s\r\n", f[0], f[1])
+		}
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+func cmdCluster(n *Node, args []string) interface{} {
+	c := n.cluster
+	switch strings.ToUpper(args[1]) {
+	case "SLOTS":
+		return c.clusterSlots()
+	case "SHARDS":
+		return errReply("ERR unknown subcommand 'SHARDS'")
+	case "NODES":
+		return c.clusterNodes(n)
+	case "MYID":
+		return n.id
+	case "INFO":
+		return c.clusterInfo()
+	case "KEYSLOT":
+		if len(args) != 3 {
+			return errWrongArgs("cluster|keyslot")
+		}
+		return int64(cluster.KeySlot(args[2]))
+	case "COUNTKEYSINSLOT", "GETKEYSINSLOT":
+		slot, valid := parseInt(args[2])
+		if !valid || slot < 0 || slot >= cluster.TotalSlots {
+			return errReply("ERR Invalid slot")
+		}
+		var keys []string
+		for _, k := range n.keysLocked() {
+			if cluster.KeySlot(k) == int(slot) {
+				keys = append(keys, k)
+			}
+		}
+		if strings.ToUpper(args[1]) == "COUNTKEYSINSLOT" {
+			return int64(len(keys))
+		}
+		count := int64(len(keys))
+		if len(args) > 3 {
+			count, _ = parseInt(args[3])
+		}
+		if count < int64(len(keys)) {
+			keys = keys[:count]
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		return keys
+	case "SETSLOT":
+		return c.setSlot(n, args)
+	case "FORGET":
+		return c.forget(n, args)
+	case "REPLICAS", "SLAVES":
+		return []string{}
+	}
+	return errorf("ERR unknown subcommand '%s'", args[1])
+}
+
+func (c *Cluster) clusterInfo() string {
+	c.mu.RLock()
+	assigned, failing := 0, 0
+	for _, owner := range c.owner {
+		if owner == nil {
+			continue
+		}
+		assigned++
+		if owner.Failed() {
+			failing++
+		}
+	}
+	epoch := c.epoch
+	c.mu.RUnlock()
+	if epoch < int64(len(c.nodes)) {
+		epoch = int64(len(c.nodes))
+	}
+
+	state := "ok"
+	if assigned < cluster.TotalSlots || failing > 0 {
+		state = "fail"
+	}
+	lines := []string{
+		"cluster_enabled:1",
+		"cluster_state:" + state,
+		fmt.Sprintf("cluster_slots_assigned:%d", assigned),
+		fmt.Sprintf("cluster_slots_ok:%d", assigned-failing),
+		"cluster_slots_pfail:0",
+		fmt.Sprintf("cluster_slots_fail:%d", failing),
+		fmt.Sprintf("cluster_known_nodes:%d", len(c.nodes)),
+		fmt.Sprintf("cluster_size:%d", len(c.nodes)),
+		fmt.Sprintf("cluster_current_epoch:%d", epoch),
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// forget handles CLUSTER FORGET node-id: the node drops from n's CLUSTER
+// NODES only. Slots stay with their owner.
+func (c *Cluster) forget(n *Node, args []string) interface{} {
+	if len(args) != 3 {
+		return errWrongArgs("cluster|forget")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if args[2] == n.id {
+		return errReply("ERR I tried hard but I can't forget myself...")
+	}
+	if c.nodeByID(args[2]) == nil || n.forgotten[args[2]] {
+		return errorf("ERR Unknown node %s", args[2])
+	}
+	if n.forgotten == nil {
+		n.forgotten = make(map[string]bool)
+	}
+	n.forgotten[args[2]] = true
+	return ok
+}
+
+// setSlot handles CLUSTER SETSLOT slot IMPORTING|MIGRATING|NODE|STABLE.
+// The fake cluster has one shared slot map, so each node's view is the same.
+func (c *Cluster) setSlot(n *Node, args []string) interface{} {
+	if len(args) < 4 {
+		return errWrongArgs("cluster|setslot")
+	}
+	s, valid := parseInt(args[2])
+	if !valid || s < 0 || s >= cluster.TotalSlots {
+		return errReply("ERR Invalid or out of range slot")
+	}
+	slot := int(s)
+	nodeArg := func() (*Node, interface{}) {
+		if len(args) < 5 {
+			return nil, errWrongArgs("cluster|setslot")
+		}
+		other := c.nodeByID(args[4])
+		if other == nil {
+			return nil, errorf("ERR I don't know about node %s", args[4])
+		}
+		return other, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch strings.ToUpper(args[3]) {
+	case "MIGRATING":
+		to, errR := nodeArg()
+		if errR != nil {
+			return errR
+		}
+		if c.owner[slot] != n {
+			return errorf("ERR I'm not the owner of hash slot %d", slot)
+		}
+		c.migrating[slot] = to
+	case "IMPORTING":
+		from, errR := nodeArg()
+		if errR != nil {
+			return errR
+		}
+		if c.owner[slot] != from {
+			return errorf("ERR I'm not importing from the owner of hash slot %d", slot)
+		}
+		c.migrating[slot] = n
+	case "STABLE":
+		c.migrating[slot] = nil
+	case "NODE":
+		to, errR := nodeArg()
+		if errR != nil {
+			return errR
+		}
+		// Keys still on the old owner stay there, as in Redis; MIGRATE
+		// should have moved them first
+		if c.owner[slot] != to {
+			c.owner[slot] = to
+			c.bumpEpochLocked(to)
+		}
+		c.migrating[slot] = nil
+	default:
+		return errSyntax
+	}
+	return ok
+}
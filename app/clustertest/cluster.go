@@ -0,0 +1,646 @@
+// Package clustertest runs a fake Redis Cluster inside the test process.
+//
+// Each node is a RESP server on 127.0.0.1 that owns a range of hash slots,
+// keeps its own keyspace and answers MOVED/ASK redirects exactly like a real
+// cluster node, so the go-redis cluster client, cluster.Client and the
+// services built on them run unmodified against it. Tests can move slots,
+// leave a slot half-migrated or fail a node while clients are connected.
+//
+// Supported: strings, hashes, sets, sorted sets, lists, streams, key expiry,
+// SCAN/KEYS, MULTI/EXEC, EVAL/EVALSHA (Lua), DUMP/RESTORE, MIGRATE and the
+// CLUSTER subcommands clients and cluster tooling use (SLOTS, NODES, INFO,
+// KEYSLOT, SETSLOT, COUNTKEYSINSLOT, GETKEYSINSLOT, ...). Nodes are masters
+// without replicas; DUMP payloads are only understood by this package.
+package clustertest
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// Cluster is a set of fake cluster nodes sharing one slot map
+type Cluster struct {
+	mu        sync.RWMutex
+	nodes     []*Node
+	owner     [cluster.TotalSlots]*Node
+	migrating [cluster.TotalSlots]*Node // slot -> import target while the owner migrates it
+	epoch     int64
+}
+
+// Node is one fake master
+type Node struct {
+	cluster *Cluster
+	id      string
+	addr    string
+	index   int
+
+	lnMu   sync.Mutex
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	failed bool
+	wg     sync.WaitGroup
+
+	forgotten map[string]bool // IDs dropped from this node's view, guarded by Cluster.mu
+
+	mu      sync.Mutex // guards the keyspace; held while a command runs
+	db      map[string]*item
+	scripts map[string]string
+	config  map[string]string
+	epoch   int64
+
+	started  time.Time
+	commands atomic.Int64
+	hits     atomic.Int64
+	misses   atomic.Int64
+	expired  atomic.Int64
+}
+
+// Start starts masters nodes and splits the slots evenly between them
+func Start(masters int) (*Cluster, error) {
+	if masters < 1 {
+		return nil, fmt.Errorf("clustertest: need at least one node")
+	}
+	c := &Cluster{}
+	for i := 0; i < masters; i++ {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		n := newNode(c, i, ln.Addr().String())
+		c.nodes = append(c.nodes, n)
+		n.serve(ln)
+	}
+
+	per := cluster.TotalSlots / masters
+	for i, n := range c.nodes {
+		end := (i + 1) * per
+		if i == masters-1 {
+			end = cluster.TotalSlots
+		}
+		for s := i * per; s < end; s++ {
+			c.owner[s] = n
+		}
+	}
+	return c, nil
+}
+
+// New starts a cluster of masters nodes that is closed when the test ends
+func New(tb testing.TB, masters int) *Cluster {
+	tb.Helper()
+	c, err := Start(masters)
+	if err != nil {
+		tb.Fatalf("clustertest: %v", err)
+	}
+	tb.Cleanup(c.Close)
+	return c
+}
+
+func newNode(c *Cluster, index int, addr string) *Node {
+	id := make([]byte, 20)
+	rand.Read(id)
+	return &Node{
+		cluster: c,
+		id:      hex.EncodeToString(id),
+		addr:    addr,
+		index:   index,
+		conns:   make(map[net.Conn]struct{}),
+		db:      make(map[string]*item),
+		scripts: make(map[string]string),
+		config: map[string]string{
+			"notify-keyspace-events": "",
+			"maxmemory":              "0",
+			"maxmemory-policy":       "noeviction",
+			"appendonly":             "no",
+			"save":                   "",
+			"cluster-node-timeout":   "15000",
+		},
+		epoch:   int64(index + 1),
+		started: time.Now(),
+	}
+}
+
+// Close stops every node
+func (c *Cluster) Close() {
+	for _, n := range c.nodes {
+		n.stop()
+	}
+}
+
+// Addrs returns the node addresses, usable as seed addresses
+func (c *Cluster) Addrs() []string {
+	addrs := make([]string, len(c.nodes))
+	for i, n := range c.nodes {
+		addrs[i] = n.addr
+	}
+	return addrs
+}
+
+// Config returns a cluster.ClusterConfig for this cluster with short
+// timeouts and retries suited to tests
+func (c *Cluster) Config() *cluster.ClusterConfig {
+	cfg := cluster.DefaultConfig()
+	cfg.Addrs = c.Addrs()
+	cfg.Remap = nil
+	cfg.PoolSize = 4
+	cfg.MinIdleConns = 0
+	cfg.DialTimeout = cluster.Duration(time.Second)
+	cfg.ReadTimeout = cluster.Duration(2 * time.Second)
+	cfg.WriteTimeout = cluster.Duration(2 * time.Second)
+	cfg.MaxRetries = 3
+	cfg.MinRetryBackoff = cluster.Duration(time.Millisecond)
+	cfg.MaxRetryBackoff = cluster.Duration(10 * time.Millisecond)
+	cfg.ConnectRetries = 1
+	return cfg
+}
+
+// Nodes returns the nodes in creation order
+func (c *Cluster) Nodes() []*Node {
+	return append([]*Node(nil), c.nodes...)
+}
+
+// Owner returns the node owning slot
+func (c *Cluster) Owner(slot int) *Node {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.owner[slot]
+}
+
+// NodeForKey returns the node owning key's slot
+func (c *Cluster) NodeForKey(key string) *Node {
+	return c.Owner(cluster.KeySlot(key))
+}
+
+// lockNodes locks the keyspaces of nodes in a fixed order, then the slot map
+func (c *Cluster) lockNodes(nodes ...*Node) func() {
+	sorted := append([]*Node(nil), nodes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].index < sorted[j].index })
+	var locked []*Node
+	for i, n := range sorted {
+		if i > 0 && n == sorted[i-1] {
+			continue
+		}
+		n.mu.Lock()
+		locked = append(locked, n)
+	}
+	c.mu.Lock()
+	return func() {
+		c.mu.Unlock()
+		for _, n := range locked {
+			n.mu.Unlock()
+		}
+	}
+}
+
+// MoveSlots hands slots start..end (inclusive) and their keys to node to at
+// once, as if a reshard had completed
+func (c *Cluster) MoveSlots(start, end int, to *Node) {
+	unlock := c.lockNodes(c.nodes...)
+	defer unlock()
+	for s := start; s <= end; s++ {
+		if from := c.owner[s]; from != to {
+			from.moveKeysLocked(to, func(key string) bool { return cluster.KeySlot(key) == s })
+			c.owner[s] = to
+		}
+		c.migrating[s] = nil
+	}
+	c.bumpEpochLocked(to)
+}
+
+// BeginMigration marks slot as MIGRATING on its owner and IMPORTING on to.
+// Until FinishMigration the owner answers ASK for keys it no longer has.
+func (c *Cluster) BeginMigration(slot int, to *Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.migrating[slot] = to
+}
+
+// MigrateKeys moves the keys of a migrating slot matching keys (all if none
+// given) from the owner to the import target, keeping the migration open
+func (c *Cluster) MigrateKeys(slot int, keys ...string) {
+	c.mu.RLock()
+	from, to := c.owner[slot], c.migrating[slot]
+	c.mu.RUnlock()
+	if to == nil {
+		return
+	}
+	want := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		want[k] = true
+	}
+	unlock := c.lockNodes(from, to)
+	defer unlock()
+	from.moveKeysLocked(to, func(key string) bool {
+		return cluster.KeySlot(key) == slot && (len(keys) == 0 || want[key])
+	})
+}
+
+// FinishMigration moves the remaining keys and assigns slot to the target
+func (c *Cluster) FinishMigration(slot int) {
+	c.mu.RLock()
+	to := c.migrating[slot]
+	c.mu.RUnlock()
+	if to != nil {
+		c.MoveSlots(slot, slot, to)
+	}
+}
+
+func (c *Cluster) bumpEpochLocked(n *Node) {
+	c.epoch++
+	if c.epoch <= int64(len(c.nodes)) {
+		c.epoch = int64(len(c.nodes)) + 1
+	}
+	n.epoch = c.epoch
+}
+
+// FailNode stops node n: its listener and client connections are closed and
+// the other nodes flag it as failed. Its slots stay assigned to it.
+func (c *Cluster) FailNode(n *Node) {
+	n.stop()
+	n.lnMu.Lock()
+	n.failed = true
+	n.lnMu.Unlock()
+}
+
+// RecoverNode restarts a failed node on its previous address, with its data
+func (c *Cluster) RecoverNode(n *Node) error {
+	ln, err := net.Listen("tcp", n.addr)
+	if err != nil {
+		return err
+	}
+	n.lnMu.Lock()
+	n.failed = false
+	n.lnMu.Unlock()
+	n.serve(ln)
+	return nil
+}
+
+// FlushAll empties every node
+func (c *Cluster) FlushAll() {
+	for _, n := range c.nodes {
+		n.mu.Lock()
+		n.db = make(map[string]*item)
+		n.mu.Unlock()
+	}
+}
+
+// ID returns the node's 40-character cluster node ID
+func (n *Node) ID() string { return n.id }
+
+// Addr returns the node's host:port
+func (n *Node) Addr() string { return n.addr }
+
+// Failed reports whether the node was stopped with FailNode
+func (n *Node) Failed() bool {
+	n.lnMu.Lock()
+	defer n.lnMu.Unlock()
+	return n.failed
+}
+
+// Keys returns the node's live keys, sorted
+func (n *Node) Keys() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.keysLocked()
+}
+
+func (n *Node) keysLocked() []string {
+	now := time.Now()
+	keys := make([]string, 0, len(n.db))
+	for k, it := range n.db {
+		if !it.expired(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// moveKeysLocked moves the keys selected by match to another node; both
+// keyspaces must be locked
+func (n *Node) moveKeysLocked(to *Node, match func(key string) bool) {
+	for k, it := range n.db {
+		if match(k) {
+			to.db[k] = it
+			delete(n.db, k)
+		}
+	}
+}
+
+func (n *Node) serve(ln net.Listener) {
+	n.lnMu.Lock()
+	n.ln = ln
+	n.lnMu.Unlock()
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			n.lnMu.Lock()
+			n.conns[conn] = struct{}{}
+			n.lnMu.Unlock()
+			n.wg.Add(1)
+			go n.handle(conn)
+		}
+	}()
+}
+
+func (n *Node) stop() {
+	n.lnMu.Lock()
+	if n.ln != nil {
+		n.ln.Close()
+		n.ln = nil
+	}
+	for conn := range n.conns {
+		conn.Close()
+	}
+	n.lnMu.Unlock()
+	n.wg.Wait()
+}
+
+// session is the per-connection state
+type session struct {
+	asking   bool
+	multi    bool
+	queued   [][]string
+	multiErr bool
+}
+
+func (n *Node) handle(conn net.Conn) {
+	defer n.wg.Done()
+	defer func() {
+		conn.Close()
+		n.lnMu.Lock()
+		delete(n.conns, conn)
+		n.lnMu.Unlock()
+	}()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	sess := &session{}
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		writeReply(w, n.dispatch(sess, args))
+		// Flush once the pipelined commands already received are answered
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// dispatch runs one client command, handling transactions and redirects
+func (n *Node) dispatch(sess *session, args []string) interface{} {
+	n.commands.Add(1)
+	name := strings.ToUpper(args[0])
+	spec, found := commands[name]
+
+	if sess.multi {
+		switch name {
+		case "EXEC":
+			return n.exec(sess)
+		case "DISCARD":
+			sess.multi, sess.queued = false, nil
+			return ok
+		case "MULTI":
+			return errReply("ERR MULTI calls can not be nested")
+		}
+		if !found || spec.fn == nil {
+			sess.multiErr = true
+			return errorf("ERR unknown command '%s'", args[0])
+		}
+		n.mu.Lock()
+		r := n.checkRedirectLocked(sess, spec, args)
+		n.mu.Unlock()
+		if r != nil {
+			sess.multiErr = true
+			return r
+		}
+		sess.queued = append(sess.queued, args)
+		return status("QUEUED")
+	}
+
+	if !found {
+		return errorf("ERR unknown command '%s', with args beginning with: %s", args[0], strings.Join(args[1:], " "))
+	}
+	if !spec.arityOK(len(args)) {
+		return errWrongArgs(name)
+	}
+	if spec.session != nil {
+		return spec.session(n, sess, args)
+	}
+
+	n.mu.Lock()
+	if r := n.checkRedirectLocked(sess, spec, args); r != nil {
+		n.mu.Unlock()
+		return r
+	}
+	sess.asking = false
+	if spec.unlocked != nil {
+		// Blocking reads and cross-node commands manage their own locking
+		n.mu.Unlock()
+		return spec.unlocked(n, args)
+	}
+	defer n.mu.Unlock()
+	reply := spec.fn(n, args)
+	if name != "OBJECT" && name != "MEMORY" {
+		n.touchLocked(spec.keys(args))
+	}
+	return reply
+}
+
+// exec runs a MULTI block atomically
+func (n *Node) exec(sess *session) interface{} {
+	queued, failed := sess.queued, sess.multiErr
+	sess.multi, sess.queued, sess.multiErr = false, nil, false
+	if failed {
+		return errReply("EXECABORT Transaction discarded because of previous errors.")
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	replies := make([]interface{}, len(queued))
+	for i, args := range queued {
+		replies[i] = n.callLocked(args)
+	}
+	return replies
+}
+
+// callLocked runs a command with the keyspace already locked (MULTI, Lua)
+func (n *Node) callLocked(args []string) interface{} {
+	name := strings.ToUpper(args[0])
+	spec, found := commands[name]
+	if !found || spec.fn == nil {
+		return errorf("ERR unknown command '%s'", args[0])
+	}
+	if !spec.arityOK(len(args)) {
+		return errWrongArgs(name)
+	}
+	return spec.fn(n, args)
+}
+
+// checkRedirectLocked returns the MOVED/ASK/CROSSSLOT error for a command
+// whose keys are not served by this node, or nil if the node may run it.
+// The keyspace must be locked so the answer holds while the command runs.
+func (n *Node) checkRedirectLocked(sess *session, spec *command, args []string) interface{} {
+	keys := spec.keys(args)
+	if len(keys) == 0 {
+		return nil
+	}
+	slot := cluster.KeySlot(keys[0])
+	for _, k := range keys[1:] {
+		if cluster.KeySlot(k) != slot {
+			return errReply("CROSSSLOT Keys in request don't hash to the same slot")
+		}
+	}
+
+	c := n.cluster
+	c.mu.RLock()
+	owner, target := c.owner[slot], c.migrating[slot]
+	c.mu.RUnlock()
+
+	switch {
+	case owner == n && target != nil && target != n:
+		// MIGRATING: keys already moved are served by the target
+		missing := 0
+		for _, k := range keys {
+			if n.lookupLocked(k) == nil {
+				missing++
+			}
+		}
+		if missing == len(keys) {
+			return errorf("ASK %d %s", slot, target.addr)
+		}
+		if missing > 0 {
+			return errReply("TRYAGAIN Multiple keys request during rehashing of slot")
+		}
+		return nil
+	case owner == n:
+		return nil
+	case target == n && sess.asking:
+		return nil
+	case owner == nil:
+		return errorf("CLUSTERDOWN Hash slot not served")
+	default:
+		return errorf("MOVED %d %s", slot, owner.addr)
+	}
+}
+
+// clusterNodes renders CLUSTER NODES as seen by node self
+func (c *Cluster) clusterNodes(self *Node) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var b strings.Builder
+	for _, n := range c.nodes {
+		if self.forgotten[n.id] {
+			continue
+		}
+		flags := "master"
+		if n == self {
+			flags = "myself,master"
+		}
+		link := "connected"
+		if n.Failed() {
+			flags += ",fail"
+			link = "disconnected"
+		}
+		port := n.addr[strings.LastIndex(n.addr, ":")+1:]
+		fmt.Fprintf(&b, "%s %s@1%s %s - 0 %d %d %s", n.id, n.addr, port, flags, time.Now().UnixMilli(), n.epoch, link)
+		for _, r := range c.rangesLocked(n) {
+			if r[0] == r[1] {
+				fmt.Fprintf(&b, " %d", r[0])
+			} else {
+				fmt.Fprintf(&b, " %d-%d", r[0], r[1])
+			}
+		}
+		for s, target := range c.migrating {
+			if target == nil {
+				continue
+			}
+			if c.owner[s] == n && n == self {
+				fmt.Fprintf(&b, " [%d->-%s]", s, target.id)
+			}
+			if target == n && n == self {
+				fmt.Fprintf(&b, " [%d-<-%s]", s, c.owner[s].id)
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// rangesLocked returns the contiguous slot ranges owned by n
+func (c *Cluster) rangesLocked(n *Node) [][2]int {
+	var ranges [][2]int
+	for s := 0; s < cluster.TotalSlots; s++ {
+		if c.owner[s] != n {
+			continue
+		}
+		if l := len(ranges); l > 0 && ranges[l-1][1] == s-1 {
+			ranges[l-1][1] = s
+		} else {
+			ranges = append(ranges, [2]int{s, s})
+		}
+	}
+	return ranges
+}
+
+// clusterSlots renders CLUSTER SLOTS
+func (c *Cluster) clusterSlots() []interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var out []interface{}
+	for _, n := range c.nodes {
+		host, port, _ := net.SplitHostPort(n.addr)
+		var p int64
+		fmt.Sscan(port, &p)
+		for _, r := range c.rangesLocked(n) {
+			out = append(out, []interface{}{int64(r[0]), int64(r[1]), []interface{}{host, p, n.id}})
+		}
+	}
+	return out
+}
+
+// nodeByAddr finds a node by address
+func (c *Cluster) nodeByAddr(addr string) *Node {
+	for _, n := range c.nodes {
+		if n.addr == addr {
+			return n
+		}
+	}
+	return nil
+}
+
+// nodeByID finds a node by ID
+func (c *Cluster) nodeByID(id string) *Node {
+	for _, n := range c.nodes {
+		if n.id == id {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package clustertest
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"ticket-reservation/cluster"
+)
+
+func newClient(t *testing.T, c *Cluster) *redis.ClusterClient {
+	t.Helper()
+	rdb := redis.NewClusterClient(&redis.ClusterOptions{
+		Addrs:           c.Addrs(),
+		MaxRedirects:    8,
+		MinRetryBackoff: time.Millisecond,
+		MaxRetryBackoff: 10 * time.Millisecond,
+	})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// nodeClient connects to one node without following redirects
+func nodeClient(t *testing.T, n *Node) *redis.Client {
+	t.Helper()
+	rdb := redis.NewClient(&redis.Options{Addr: n.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// keyOn returns a key whose slot is owned by n
+func keyOn(t *testing.T, c *Cluster, n *Node, prefix string) string {
+	t.Helper()
+	for i := 0; i < 10000; i++ {
+		key := prefix + strconv.Itoa(i)
+		if c.NodeForKey(key) == n {
+			return key
+		}
+	}
+	t.Fatalf("no key found on %s", n.Addr())
+	return ""
+}
+
+func TestRedirects(t *testing.T) {
+	c := New(t, 3)
+	ctx := context.Background()
+	nodes := c.Nodes()
+	key := keyOn(t, c, nodes[0], "k")
+
+	err := nodeClient(t, nodes[1]).Set(ctx, key, "v", 0).Err()
+	want := "MOVED " + strconv.Itoa(cluster.KeySlot(key)) + " " + nodes[0].Addr()
+	if err == nil || err.Error() != want {
+		t.Fatalf("SET on wrong node: got %v, want %q", err, want)
+	}
+
+	err = nodeClient(t, nodes[0]).MSet(ctx, "{a}x", "1", "{b}y", "2").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "CROSSSLOT") {
+		t.Fatalf("MSET across slots: got %v, want CROSSSLOT", err)
+	}
+
+	rdb := newClient(t, c)
+	if err := rdb.Set(ctx, key, "v", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := nodes[0].Keys(); len(got) != 1 || got[0] != key {
+		t.Fatalf("owner keys = %v, want [%s]", got, key)
+	}
+}
+
+func TestMoveSlots(t *testing.T) {
+	c := New(t, 2)
+	ctx := context.Background()
+	rdb := newClient(t, c)
+	from, to := c.Nodes()[0], c.Nodes()[1]
+	key := keyOn(t, c, from, "moving")
+	slot := cluster.KeySlot(key)
+
+	if err := rdb.Set(ctx, key, "before", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	c.MoveSlots(slot, slot, to)
+	if c.Owner(slot) != to {
+		t.Fatalf("slot %d not moved", slot)
+	}
+
+	// The client's slot map is stale: it must follow MOVED to the new owner
+	got, err := rdb.Get(ctx, key).Result()
+	if err != nil || got != "before" {
+		t.Fatalf("GET after move = %q, %v", got, err)
+	}
+	if err := rdb.Set(ctx, key, "after", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(from.Keys()) != 0 || len(to.Keys()) != 1 {
+		t.Fatalf("keys not moved: from %v, to %v", from.Keys(), to.Keys())
+	}
+}
+
+func TestMigrationAsk(t *testing.T) {
+	c := New(t, 2)
+	ctx := context.Background()
+	rdb := newClient(t, c)
+	from, to := c.Nodes()[0], c.Nodes()[1]
+	tag := keyOn(t, c, from, "tag")
+	moved, stayed := "{"+tag+"}moved", "{"+tag+"}stayed"
+	slot := cluster.KeySlot(moved)
+
+	rdb.Set(ctx, moved, "1", 0)
+	rdb.Set(ctx, stayed, "2", 0)
+	c.BeginMigration(slot, to)
+	c.MigrateKeys(slot, moved)
+
+	// The owner answers ASK for keys it no longer has
+	err := nodeClient(t, from).Get(ctx, moved).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "ASK ") {
+		t.Fatalf("GET of migrated key on owner: got %v, want ASK", err)
+	}
+	// Without ASKING the target redirects back
+	err = nodeClient(t, to).Get(ctx, moved).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "MOVED ") {
+		t.Fatalf("GET on importing node without ASKING: got %v, want MOVED", err)
+	}
+	// Keys split between the nodes cannot be used together
+	err = nodeClient(t, from).MGet(ctx, moved, stayed).Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "TRYAGAIN") {
+		t.Fatalf("MGET during migration: got %v, want TRYAGAIN", err)
+	}
+
+	for key, want := range map[string]string{moved: "1", stayed: "2"} {
+		if got, err := rdb.Get(ctx, key).Result(); err != nil || got != want {
+			t.Fatalf("GET %s = %q, %v; want %q", key, got, err, want)
+		}
+	}
+
+	c.FinishMigration(slot)
+	if c.Owner(slot) != to || len(from.Keys()) != 0 {
+		t.Fatalf("migration not finished: owner %s, left on source %v", c.Owner(slot).Addr(), from.Keys())
+	}
+}
+
+func TestFailNode(t *testing.T) {
+	c := New(t, 3)
+	ctx := context.Background()
+	rdb := newClient(t, c)
+	failed := c.Nodes()[2]
+	key := keyOn(t, c, failed, "down")
+	if err := rdb.Set(ctx, key, "v", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	c.FailNode(failed)
+	if err := rdb.Get(ctx, key).Err(); err == nil {
+		t.Fatal("GET on a failed node succeeded")
+	}
+	nodes, err := nodeClient(t, c.Nodes()[0]).ClusterNodes(ctx).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(nodes, failed.ID()+" "+failed.Addr()) || !strings.Contains(nodes, "master,fail") {
+		t.Fatalf("CLUSTER NODES does not flag the failed node:\n%s", nodes)
+	}
+	info, _ := nodeClient(t, c.Nodes()[0]).ClusterInfo(ctx).Result()
+	if !strings.Contains(info, "cluster_state:fail") {
+		t.Fatalf("CLUSTER INFO state not fail:\n%s", info)
+	}
+
+	if err := c.RecoverNode(failed); err != nil {
+		t.Fatal(err)
+	}
+	// The client backs off from a node it failed to dial; give it time to
+	// notice the node is back
+	var got string
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err = rdb.Get(ctx, key).Result()
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil || got != "v" {
+		t.Fatalf("GET after recovery = %q, %v", got, err)
+	}
+}
+
+func TestScripts(t *testing.T) {
+	c := New(t, 2)
+	ctx := context.Background()
+	rdb := newClient(t, c)
+
+	script := redis.NewScript(`
+		local n = redis.call('HINCRBY', KEYS[1], 'n', ARGV[1])
+		if n > 10 then
+			return redis.error_reply('TOO_MANY')
+		end
+		redis.call('HINCRBYFLOAT', KEYS[1], 'total', ARGV[2])
+		return {n, redis.call('HGET', KEYS[1], 'total'), redis.call('GET', 'missing' .. KEYS[1])}
+	`)
+	// First Run loads the script via EVALSHA -> NOSCRIPT -> EVAL
+	got, err := script.Run(ctx, rdb, []string{"{s}h"}, 3, "1.5").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals := got.([]interface{})
+	if len(vals) != 3 || vals[0] != int64(3) || vals[1] != "1.5" || vals[2] != nil {
+		t.Fatalf("script result = %#v", vals)
+	}
+	if _, err := script.Run(ctx, rdb, []string{"{s}h"}, 8, "1").Result(); err == nil || err.Error() != "TOO_MANY" {
+		t.Fatalf("error_reply: got %v", err)
+	}
+
+	var redisErr redis.Error
+	err = rdb.Eval(ctx, "return redis.call('INCR', KEYS[1])", []string{"{s}h"}).Err()
+	if !errors.As(err, &redisErr) || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("redis.call error: got %v", err)
+	}
+}
+
+func TestStreamsAndSortedSets(t *testing.T) {
+	c := New(t, 1)
+	ctx := context.Background()
+	rdb := newClient(t, c)
+
+	done := make(chan []redis.XStream, 1)
+	go func() {
+		res, _ := rdb.XRead(ctx, &redis.XReadArgs{Streams: []string{"events", "$"}, Block: 2 * time.Second}).Result()
+		done <- res
+	}()
+	time.Sleep(50 * time.Millisecond)
+	id, err := rdb.XAdd(ctx, &redis.XAddArgs{Stream: "events", Values: map[string]interface{}{"type": "sold"}}).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case res := <-done:
+		if len(res) != 1 || len(res[0].Messages) != 1 || res[0].Messages[0].ID != id {
+			t.Fatalf("XREAD BLOCK = %#v", res)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("XREAD BLOCK did not wake up")
+	}
+
+	rdb.ZAdd(ctx, "z", redis.Z{Score: 2, Member: "b"}, redis.Z{Score: 1, Member: "a"}, redis.Z{Score: 3, Member: "c"})
+	zs, err := rdb.ZRangeWithScores(ctx, "z", 0, -1).Result()
+	if err != nil || len(zs) != 3 || zs[0].Member != "a" || zs[2].Score != 3 {
+		t.Fatalf("ZRANGE WITHSCORES = %v, %v", zs, err)
+	}
+}
+
+func TestClusterForget(t *testing.T) {
+	c := New(t, 3)
+	ctx := context.Background()
+	a, b, gone := nodeClient(t, c.Nodes()[0]), nodeClient(t, c.Nodes()[1]), c.Nodes()[2]
+
+	if err := a.ClusterForget(ctx, gone.ID()).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.ClusterForget(ctx, gone.ID()).Err(); err == nil {
+		t.Fatal("forgot an unknown node")
+	}
+	if err := a.ClusterForget(ctx, c.Nodes()[0].ID()).Err(); err == nil {
+		t.Fatal("a node forgot itself")
+	}
+	viewA, _ := a.ClusterNodes(ctx).Result()
+	viewB, _ := b.ClusterNodes(ctx).Result()
+	if strings.Contains(viewA, gone.ID()) || !strings.Contains(viewB, gone.ID()) {
+		t.Fatalf("only the node that ran FORGET should drop it:\n%s\n%s", viewA, viewB)
+	}
+}
@@ -0,0 +1,1745 @@
+package clustertest
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// command describes one supported command. Key positions follow the
+// COMMAND INFO convention: first/last/step are argument indexes (last -1 =
+// the final argument); keyFn overrides them for variable layouts.
+type command struct {
+	arity             int // >0 exact argument count including the name, <0 minimum
+	first, last, step int
+	keyFn             func(args []string) []string
+	fn                func(n *Node, args []string) interface{}
+	session           func(n *Node, sess *session, args []string) interface{}
+	unlocked          func(n *Node, args []string) interface{}
+}
+
+func (c *command) arityOK(argc int) bool {
+	if c.arity > 0 {
+		return argc == c.arity
+	}
+	return argc >= -c.arity
+}
+
+func (c *command) keys(args []string) []string {
+	if c.keyFn != nil {
+		return c.keyFn(args)
+	}
+	if c.first == 0 || c.first >= len(args) {
+		return nil
+	}
+	last := c.last
+	if last < 0 {
+		last = len(args) + last
+	}
+	step := c.step
+	if step == 0 {
+		step = 1
+	}
+	var keys []string
+	for i := c.first; i <= last && i < len(args); i += step {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+// commands is filled in init to break the initialization cycle with
+// callLocked (used by EVAL)
+var commands map[string]*command
+
+func init() {
+	key1 := func(arity int, fn func(n *Node, args []string) interface{}) *command {
+		return &command{arity: arity, first: 1, last: 1, step: 1, fn: fn}
+	}
+	noKeys := func(arity int, fn func(n *Node, args []string) interface{}) *command {
+		return &command{arity: arity, fn: fn}
+	}
+	allKeys := func(arity int, fn func(n *Node, args []string) interface{}) *command {
+		return &command{arity: arity, first: 1, last: -1, step: 1, fn: fn}
+	}
+
+	commands = map[string]*command{
+		// Connection and server
+		"PING":      noKeys(-1, cmdPing),
+		"ECHO":      noKeys(2, func(n *Node, args []string) interface{} { return args[1] }),
+		"HELLO":     noKeys(-1, func(n *Node, args []string) interface{} { return errReply("ERR unknown command 'HELLO'") }),
+		"CLIENT":    noKeys(-2, cmdClient),
+		"SELECT":    noKeys(2, cmdSelect),
+		"READONLY":  noKeys(1, func(n *Node, args []string) interface{} { return ok }),
+		"READWRITE": noKeys(1, func(n *Node, args []string) interface{} { return ok }),
+		"ASKING": {arity: 1, session: func(n *Node, sess *session, args []string) interface{} {
+			sess.asking = true
+			return ok
+		}},
+		"MULTI": {arity: 1, session: func(n *Node, sess *session, args []string) interface{} {
+			sess.multi = true
+			return ok
+		}},
+		"EXEC":     {arity: 1, session: func(n *Node, sess *session, args []string) interface{} { return errReply("ERR EXEC without MULTI") }},
+		"DISCARD":  {arity: 1, session: func(n *Node, sess *session, args []string) interface{} { return errReply("ERR DISCARD without MULTI") }},
+		"WATCH":    allKeys(-2, func(n *Node, args []string) interface{} { return ok }),
+		"UNWATCH":  noKeys(1, func(n *Node, args []string) interface{} { return ok }),
+		"INFO":     noKeys(-1, cmdInfo),
+		"CONFIG":   noKeys(-2, cmdConfig),
+		"DBSIZE":   noKeys(1, func(n *Node, args []string) interface{} { return int64(len(n.keysLocked())) }),
+		"FLUSHALL": noKeys(-1, cmdFlush),
+		"FLUSHDB":  noKeys(-1, cmdFlush),
+		"TIME":     noKeys(1, cmdTime),
+		"CLUSTER":  noKeys(-2, cmdCluster),
+		"DEBUG":    noKeys(-2, cmdDebug),
+		"MEMORY":   {arity: -2, keyFn: memoryKeys, fn: cmdMemory},
+		"OBJECT":   {arity: -2, keyFn: objectKeys, fn: cmdObject},
+
+		// Keyspace
+		"DEL":       allKeys(-2, cmdDel),
+		"UNLINK":    allKeys(-2, cmdDel),
+		"EXISTS":    allKeys(-2, cmdExists),
+		"TYPE":      key1(2, cmdType),
+		"TTL":       key1(2, cmdTTL),
+		"PTTL":      key1(2, cmdTTL),
+		"EXPIRE":    key1(-3, cmdExpire),
+		"PEXPIRE":   key1(-3, cmdExpire),
+		"EXPIREAT":  key1(-3, cmdExpire),
+		"PEXPIREAT": key1(-3, cmdExpire),
+		"PERSIST":   key1(2, cmdPersist),
+		"KEYS":      noKeys(2, cmdKeys),
+		"SCAN":      noKeys(-2, cmdScan),
+		"RANDOMKEY": noKeys(1, cmdRandomKey),
+		"DUMP":      key1(2, cmdDump),
+		"RESTORE":   key1(-4, cmdRestore),
+		"MIGRATE":   {arity: -6, keyFn: migrateKeys, unlocked: cmdMigrate},
+
+		// Strings
+		"GET":         key1(2, cmdGet),
+		"SET":         key1(-3, cmdSet),
+		"SETNX":       key1(3, func(n *Node, args []string) interface{} { return setNX(n, args) }),
+		"SETEX":       key1(4, cmdSetEx),
+		"PSETEX":      key1(4, cmdSetEx),
+		"GETDEL":      key1(2, cmdGetDel),
+		"GETSET":      key1(3, cmdGetSet),
+		"MGET":        allKeys(-2, cmdMGet),
+		"MSET":        {arity: -3, first: 1, last: -1, step: 2, fn: cmdMSet},
+		"INCR":        key1(2, cmdIncr),
+		"DECR":        key1(2, cmdIncr),
+		"INCRBY":      key1(3, cmdIncr),
+		"DECRBY":      key1(3, cmdIncr),
+		"INCRBYFLOAT": key1(3, cmdIncrByFloat),
+		"APPEND":      key1(3, cmdAppend),
+		"STRLEN":      key1(2, cmdStrlen),
+
+		// Hashes
+		"HSET":         key1(-4, cmdHSet),
+		"HMSET":        key1(-4, cmdHSet),
+		"HSETNX":       key1(4, cmdHSetNX),
+		"HGET":         key1(3, cmdHGet),
+		"HMGET":        key1(-3, cmdHMGet),
+		"HGETALL":      key1(2, cmdHGetAll),
+		"HDEL":         key1(-3, cmdHDel),
+		"HEXISTS":      key1(3, cmdHExists),
+		"HLEN":         key1(2, cmdHLen),
+		"HKEYS":        key1(2, cmdHKeys),
+		"HVALS":        key1(2, cmdHVals),
+		"HINCRBY":      key1(4, cmdHIncrBy),
+		"HINCRBYFLOAT": key1(4, cmdHIncrByFloat),
+
+		// Sets
+		"SADD":      key1(-3, cmdSAdd),
+		"SREM":      key1(-3, cmdSRem),
+		"SMEMBERS":  key1(2, cmdSMembers),
+		"SISMEMBER": key1(3, cmdSIsMember),
+		"SCARD":     key1(2, cmdSCard),
+
+		// Sorted sets
+		"ZADD":          key1(-4, cmdZAdd),
+		"ZREM":          key1(-3, cmdZRem),
+		"ZCARD":         key1(2, cmdZCard),
+		"ZSCORE":        key1(3, cmdZScore),
+		"ZINCRBY":       key1(4, cmdZIncrBy),
+		"ZRANGE":        key1(-4, cmdZRange),
+		"ZREVRANGE":     key1(-4, cmdZRange),
+		"ZRANGEBYSCORE": key1(-4, cmdZRangeByScore),
+		"ZRANK":         key1(3, cmdZRank),
+		"ZPOPMIN":       key1(-2, cmdZPopMin),
+
+		// Lists
+		"LPUSH":  key1(-3, cmdPush),
+		"RPUSH":  key1(-3, cmdPush),
+		"LPOP":   key1(-2, cmdPop),
+		"RPOP":   key1(-2, cmdPop),
+		"LLEN":   key1(2, cmdLLen),
+		"LRANGE": key1(4, cmdLRange),
+
+		// Streams
+		"XADD":   key1(-5, cmdXAdd),
+		"XLEN":   key1(2, cmdXLen),
+		"XRANGE": key1(-4, cmdXRange),
+		"XDEL":   key1(-3, cmdXDel),
+		"XREAD":  {arity: -4, keyFn: xreadKeys, fn: cmdXRead, unlocked: cmdXReadBlocking},
+
+		// Scripting
+		"EVAL":    {arity: -3, keyFn: evalKeys, fn: cmdEval},
+		"EVALSHA": {arity: -3, keyFn: evalKeys, fn: cmdEvalSHA},
+		"SCRIPT":  noKeys(-2, cmdScript),
+	}
+}
+
+// Argument helpers
+
+func parseInt(s string) (int64, bool) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	return v, err == nil
+}
+
+func parseFloat(s string) (float64, bool) {
+	switch strings.ToLower(s) {
+	case "+inf", "inf":
+		return math.Inf(1), true
+	case "-inf":
+		return math.Inf(-1), true
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil && !math.IsNaN(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "inf"
+	case math.IsInf(v, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Connection and server
+
+func cmdPing(n *Node, args []string) interface{} {
+	if len(args) > 1 {
+		return args[1]
+	}
+	return status("PONG")
+}
+
+func cmdClient(n *Node, args []string) interface{} {
+	switch strings.ToUpper(args[1]) {
+	case "SETNAME", "SETINFO", "NO-EVICT", "NO-TOUCH", "REPLY":
+		return ok
+	case "GETNAME":
+		return nil
+	case "ID":
+		return int64(1)
+	}
+	return ok
+}
+
+func cmdSelect(n *Node, args []string) interface{} {
+	if args[1] != "0" {
+		return errReply("ERR SELECT is not allowed in cluster mode")
+	}
+	return ok
+}
+
+func cmdFlush(n *Node, args []string) interface{} {
+	n.db = make(map[string]*item)
+	return ok
+}
+
+func cmdTime(n *Node, args []string) interface{} {
+	now := time.Now()
+	return []string{strconv.FormatInt(now.Unix(), 10), strconv.Itoa(now.Nanosecond() / 1000)}
+}
+
+func cmdConfig(n *Node, args []string) interface{} {
+	switch strings.ToUpper(args[1]) {
+	case "GET":
+		if len(args) != 3 {
+			return errWrongArgs("config|get")
+		}
+		var out []string
+		names := make([]string, 0, len(n.config))
+		for k := range n.config {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			if cluster.MatchPattern(strings.ToLower(args[2]), k) {
+				out = append(out, k, n.config[k])
+			}
+		}
+		return out
+	case "SET":
+		if len(args) < 4 || len(args)%2 != 0 {
+			return errWrongArgs("config|set")
+		}
+		for i := 2; i < len(args); i += 2 {
+			n.config[strings.ToLower(args[i])] = args[i+1]
+		}
+		return ok
+	case "RESETSTAT", "REWRITE":
+		return ok
+	}
+	return errorf("ERR unknown subcommand '%s'", args[1])
+}
+
+func cmdDebug(n *Node, args []string) interface{} {
+	switch strings.ToUpper(args[1]) {
+	case "DIGEST-VALUE":
+		out := make([]interface{}, 0, len(args)-2)
+		for _, key := range args[2:] {
+			it := n.lookupLocked(key)
+			if it == nil {
+				out = append(out, strings.Repeat("0", 40))
+				continue
+			}
+			payload, _ := json.Marshal(it)
+			out = append(out, sha1hex(string(payload)))
+		}
+		return out
+	case "SLEEP":
+		if d, ok := parseFloat(args[2]); ok {
+			time.Sleep(time.Duration(d * float64(time.Second)))
+		}
+		return ok
+	}
+	return errorf("ERR unknown DEBUG subcommand '%s'", args[1])
+}
+
+func memoryKeys(args []string) []string {
+	if strings.ToUpper(args[1]) == "USAGE" && len(args) > 2 {
+		return args[2:3]
+	}
+	return nil
+}
+
+func cmdMemory(n *Node, args []string) interface{} {
+	if strings.ToUpper(args[1]) != "USAGE" || len(args) < 3 {
+		return errorf("ERR unknown subcommand '%s'", args[1])
+	}
+	it := n.lookupLocked(args[2])
+	if it == nil {
+		return nil
+	}
+	payload, _ := json.Marshal(it)
+	return int64(len(args[2]) + len(payload) + 48)
+}
+
+func objectKeys(args []string) []string {
+	if len(args) > 2 {
+		return args[2:3]
+	}
+	return nil
+}
+
+// cmdObject answers OBJECT ENCODING with the encoding a real server would
+// pick for the value under its default listpack/intset limits, and OBJECT
+// FREQ with the key's access count (capped like the LFU counter) when an LFU
+// policy is configured
+func cmdObject(n *Node, args []string) interface{} {
+	sub := strings.ToUpper(args[1])
+	if sub != "ENCODING" && sub != "FREQ" || len(args) != 3 {
+		return errorf("ERR unknown subcommand '%s'", args[1])
+	}
+	it := n.lookupLocked(args[2])
+	if it == nil {
+		return nil
+	}
+	if sub == "FREQ" {
+		if !strings.Contains(n.config["maxmemory-policy"], "lfu") {
+			return errReply("ERR An LFU maxmemory policy is not selected, access frequency not tracked. Please note that when switching between policies at runtime LRU and LFU data will take some time to adjust.")
+		}
+		if it.hits > 255 {
+			return int64(255)
+		}
+		return it.hits
+	}
+	return objectEncoding(it)
+}
+
+func objectEncoding(it *item) string {
+	switch it.Kind {
+	case kindString:
+		if _, isInt := parseInt(it.Str); isInt && len(it.Str) <= 20 {
+			return "int"
+		}
+		if len(it.Str) <= 44 {
+			return "embstr"
+		}
+		return "raw"
+	case kindHash:
+		if len(it.Hash) > 128 {
+			return "hashtable"
+		}
+		for f, v := range it.Hash {
+			if len(f) > 64 || len(v) > 64 {
+				return "hashtable"
+			}
+		}
+		return "listpack"
+	case kindSet:
+		ints := true
+		for m := range it.Set {
+			if _, isInt := parseInt(m); !isInt {
+				ints = false
+				break
+			}
+		}
+		switch {
+		case ints && len(it.Set) <= 512:
+			return "intset"
+		case len(it.Set) <= 128:
+			return "listpack"
+		}
+		return "hashtable"
+	case kindZSet:
+		if len(it.ZSet) <= 128 {
+			return "listpack"
+		}
+		return "skiplist"
+	case kindList:
+		if len(it.List) <= 128 {
+			return "listpack"
+		}
+		return "quicklist"
+	}
+	return it.Kind
+}
+
+// Keyspace
+
+func cmdDel(n *Node, args []string) interface{} {
+	var deleted int64
+	for _, key := range args[1:] {
+		if n.lookupLocked(key) != nil {
+			delete(n.db, key)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+func cmdExists(n *Node, args []string) interface{} {
+	var count int64
+	for _, key := range args[1:] {
+		if n.lookupLocked(key) != nil {
+			count++
+		}
+	}
+	return count
+}
+
+func cmdType(n *Node, args []string) interface{} {
+	it := n.lookupLocked(args[1])
+	if it == nil {
+		return status("none")
+	}
+	return status(it.Kind)
+}
+
+func cmdTTL(n *Node, args []string) interface{} {
+	it := n.lookupLocked(args[1])
+	switch {
+	case it == nil:
+		return int64(-2)
+	case it.ExpireAt.IsZero():
+		return int64(-1)
+	}
+	left := time.Until(it.ExpireAt)
+	if strings.ToUpper(args[0]) == "PTTL" {
+		return left.Milliseconds()
+	}
+	return int64(math.Ceil(left.Seconds() - 0.0005))
+}
+
+func cmdExpire(n *Node, args []string) interface{} {
+	v, valid := parseInt(args[2])
+	if !valid {
+		return errNotInt
+	}
+	it := n.lookupLocked(args[1])
+	if it == nil {
+		return int64(0)
+	}
+	var at time.Time
+	switch strings.ToUpper(args[0]) {
+	case "EXPIRE":
+		at = time.Now().Add(time.Duration(v) * time.Second)
+	case "PEXPIRE":
+		at = time.Now().Add(time.Duration(v) * time.Millisecond)
+	case "EXPIREAT":
+		at = time.Unix(v, 0)
+	case "PEXPIREAT":
+		at = time.UnixMilli(v)
+	}
+	it.ExpireAt = at
+	n.lookupLocked(args[1]) // a past time deletes the key
+	return int64(1)
+}
+
+func cmdPersist(n *Node, args []string) interface{} {
+	it := n.lookupLocked(args[1])
+	if it == nil || it.ExpireAt.IsZero() {
+		return int64(0)
+	}
+	it.ExpireAt = time.Time{}
+	return int64(1)
+}
+
+func cmdKeys(n *Node, args []string) interface{} {
+	out := []string{}
+	for _, k := range n.keysLocked() {
+		if cluster.MatchPattern(args[1], k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// cmdScan treats the cursor as an offset into the sorted key list
+func cmdScan(n *Node, args []string) interface{} {
+	cursor, valid := parseInt(args[1])
+	if !valid || cursor < 0 {
+		return errReply("ERR invalid cursor")
+	}
+	match, count, typ := "*", int64(10), ""
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return errSyntax
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			match = args[i+1]
+		case "COUNT":
+			if count, valid = parseInt(args[i+1]); !valid || count < 1 {
+				return errSyntax
+			}
+		case "TYPE":
+			typ = strings.ToLower(args[i+1])
+		default:
+			return errSyntax
+		}
+	}
+
+	keys := n.keysLocked()
+	end := cursor + count
+	next := end
+	if end >= int64(len(keys)) {
+		end, next = int64(len(keys)), 0
+	}
+	page := []string{}
+	for i := cursor; i < end; i++ {
+		k := keys[i]
+		if !cluster.MatchPattern(match, k) || typ != "" && n.db[k].Kind != typ {
+			continue
+		}
+		page = append(page, k)
+	}
+	return []interface{}{strconv.FormatInt(next, 10), page}
+}
+
+func cmdRandomKey(n *Node, args []string) interface{} {
+	keys := n.keysLocked()
+	if len(keys) == 0 {
+		return nil
+	}
+	return keys[time.Now().UnixNano()%int64(len(keys))]
+}
+
+// DUMP payloads are this package's JSON encoding of the item plus a marker
+const dumpPrefix = "clustertest:"
+
+func cmdDump(n *Node, args []string) interface{} {
+	it := n.lookupLocked(args[1])
+	if it == nil {
+		return nil
+	}
+	payload, err := json.Marshal(it)
+	if err != nil {
+		return errorf("ERR %v", err)
+	}
+	return dumpPrefix + string(payload)
+}
+
+func cmdRestore(n *Node, args []string) interface{} {
+	ttl, valid := parseInt(args[2])
+	if !valid || ttl < 0 {
+		return errReply("ERR Invalid TTL value, must be >= 0")
+	}
+	replace, absTTL := false, false
+	for _, opt := range args[4:] {
+		switch strings.ToUpper(opt) {
+		case "REPLACE":
+			replace = true
+		case "ABSTTL":
+			absTTL = true
+		}
+	}
+	if !replace && n.lookupLocked(args[1]) != nil {
+		return errReply("BUSYKEY Target key name already exists.")
+	}
+	if !strings.HasPrefix(args[3], dumpPrefix) {
+		return errReply("ERR DUMP payload version or checksum are wrong")
+	}
+	var it item
+	if err := json.Unmarshal([]byte(args[3][len(dumpPrefix):]), &it); err != nil {
+		return errReply("ERR Bad data format")
+	}
+	switch {
+	case ttl > 0 && absTTL:
+		it.ExpireAt = time.UnixMilli(ttl)
+	case ttl > 0:
+		it.ExpireAt = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+	}
+	n.db[args[1]] = &it
+	return ok
+}
+
+// migrateKeys returns the keys of MIGRATE host port key|"" db timeout [...] [KEYS k...]
+func migrateKeys(args []string) []string {
+	for i := 6; i < len(args); i++ {
+		if strings.ToUpper(args[i]) == "KEYS" {
+			return args[i+1:]
+		}
+	}
+	if args[3] != "" {
+		return args[3:4]
+	}
+	return nil
+}
+
+// cmdMigrate moves keys to another node of the same fake cluster
+func cmdMigrate(n *Node, args []string) interface{} {
+	target := n.cluster.nodeByAddr(args[1] + ":" + args[2])
+	if target == nil || target.Failed() {
+		return errorf("IOERR error or timeout connecting to the client")
+	}
+	replace := false
+	for _, opt := range args[6:] {
+		if strings.ToUpper(opt) == "REPLACE" {
+			replace = true
+		}
+	}
+	keys := migrateKeys(args)
+
+	unlock := n.cluster.lockNodes(n, target)
+	defer unlock()
+	moved := 0
+	for _, k := range keys {
+		it := n.lookupLocked(k)
+		if it == nil {
+			continue
+		}
+		if !replace && target.lookupLocked(k) != nil {
+			return errReply("BUSYKEY Target key name already exists.")
+		}
+		target.db[k] = it
+		delete(n.db, k)
+		moved++
+	}
+	if moved == 0 {
+		return status("NOKEY")
+	}
+	return ok
+}
+
+// Strings
+
+func cmdGet(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindString)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return nil
+	}
+	return it.Str
+}
+
+func cmdSet(n *Node, args []string) interface{} {
+	key, value := args[1], args[2]
+	var nx, xx, get, keepTTL bool
+	var expireAt time.Time
+	for i := 3; i < len(args); i++ {
+		opt := strings.ToUpper(args[i])
+		switch opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GET":
+			get = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if i+1 >= len(args) {
+				return errSyntax
+			}
+			v, valid := parseInt(args[i+1])
+			if !valid || v <= 0 {
+				return errReply("ERR invalid expire time in 'set' command")
+			}
+			i++
+			switch opt {
+			case "EX":
+				expireAt = time.Now().Add(time.Duration(v) * time.Second)
+			case "PX":
+				expireAt = time.Now().Add(time.Duration(v) * time.Millisecond)
+			case "EXAT":
+				expireAt = time.Unix(v, 0)
+			case "PXAT":
+				expireAt = time.UnixMilli(v)
+			}
+		default:
+			return errSyntax
+		}
+	}
+
+	old := n.lookupLocked(key)
+	var prev interface{}
+	if get && old != nil {
+		if old.Kind != kindString {
+			return errWrongType
+		}
+		prev = old.Str
+	}
+	if nx && old != nil || xx && old == nil {
+		if get {
+			return prev
+		}
+		return nil
+	}
+	it := &item{Kind: kindString, Str: value, ExpireAt: expireAt}
+	if keepTTL && old != nil {
+		it.ExpireAt = old.ExpireAt
+	}
+	n.db[key] = it
+	if get {
+		return prev
+	}
+	return ok
+}
+
+func setNX(n *Node, args []string) interface{} {
+	if n.lookupLocked(args[1]) != nil {
+		return int64(0)
+	}
+	n.db[args[1]] = &item{Kind: kindString, Str: args[2]}
+	return int64(1)
+}
+
+func cmdSetEx(n *Node, args []string) interface{} {
+	v, valid := parseInt(args[2])
+	if !valid || v <= 0 {
+		return errorf("ERR invalid expire time in '%s' command", strings.ToLower(args[0]))
+	}
+	unit := time.Second
+	if strings.ToUpper(args[0]) == "PSETEX" {
+		unit = time.Millisecond
+	}
+	n.db[args[1]] = &item{Kind: kindString, Str: args[3], ExpireAt: time.Now().Add(time.Duration(v) * unit)}
+	return ok
+}
+
+func cmdGetDel(n *Node, args []string) interface{} {
+	v := cmdGet(n, args)
+	if _, isStr := v.(string); isStr {
+		delete(n.db, args[1])
+	}
+	return v
+}
+
+func cmdGetSet(n *Node, args []string) interface{} {
+	v := cmdGet(n, args)
+	if _, isErr := v.(errReply); isErr {
+		return v
+	}
+	n.db[args[1]] = &item{Kind: kindString, Str: args[2]}
+	return v
+}
+
+func cmdMGet(n *Node, args []string) interface{} {
+	out := make([]interface{}, 0, len(args)-1)
+	for _, key := range args[1:] {
+		it := n.lookupLocked(key)
+		if it == nil || it.Kind != kindString {
+			out = append(out, nil)
+			continue
+		}
+		out = append(out, it.Str)
+	}
+	return out
+}
+
+func cmdMSet(n *Node, args []string) interface{} {
+	if len(args)%2 != 1 {
+		return errWrongArgs("mset")
+	}
+	for i := 1; i < len(args); i += 2 {
+		n.db[args[i]] = &item{Kind: kindString, Str: args[i+1]}
+	}
+	return ok
+}
+
+func cmdIncr(n *Node, args []string) interface{} {
+	delta := int64(1)
+	if len(args) == 3 {
+		var valid bool
+		if delta, valid = parseInt(args[2]); !valid {
+			return errNotInt
+		}
+	}
+	if strings.HasPrefix(strings.ToUpper(args[0]), "DECR") {
+		delta = -delta
+	}
+	it, errR := n.get(args[1], kindString)
+	if errR != nil {
+		return errR
+	}
+	var cur int64
+	if it != nil {
+		var valid bool
+		if cur, valid = parseInt(it.Str); !valid {
+			return errNotInt
+		}
+	} else {
+		it = &item{Kind: kindString}
+		n.db[args[1]] = it
+	}
+	cur += delta
+	it.Str = strconv.FormatInt(cur, 10)
+	return cur
+}
+
+func cmdIncrByFloat(n *Node, args []string) interface{} {
+	delta, valid := parseFloat(args[2])
+	if !valid {
+		return errNotFloat
+	}
+	it, errR := n.get(args[1], kindString)
+	if errR != nil {
+		return errR
+	}
+	var cur float64
+	if it != nil {
+		if cur, valid = parseFloat(it.Str); !valid {
+			return errNotFloat
+		}
+	} else {
+		it = &item{Kind: kindString}
+		n.db[args[1]] = it
+	}
+	it.Str = formatFloat(cur + delta)
+	return it.Str
+}
+
+func cmdAppend(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindString)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		it = &item{Kind: kindString}
+		n.db[args[1]] = it
+	}
+	it.Str += args[2]
+	return int64(len(it.Str))
+}
+
+func cmdStrlen(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindString)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	return int64(len(it.Str))
+}
+
+// Hashes
+
+func cmdHSet(n *Node, args []string) interface{} {
+	if len(args)%2 != 0 {
+		return errWrongArgs(args[0])
+	}
+	it, errR := n.getOrCreate(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	var added int64
+	for i := 2; i < len(args); i += 2 {
+		if _, exists := it.Hash[args[i]]; !exists {
+			added++
+		}
+		it.Hash[args[i]] = args[i+1]
+	}
+	if strings.ToUpper(args[0]) == "HMSET" {
+		return ok
+	}
+	return added
+}
+
+func cmdHSetNX(n *Node, args []string) interface{} {
+	it, errR := n.getOrCreate(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	if _, exists := it.Hash[args[2]]; exists {
+		return int64(0)
+	}
+	it.Hash[args[2]] = args[3]
+	return int64(1)
+}
+
+func cmdHGet(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return nil
+	}
+	if v, exists := it.Hash[args[2]]; exists {
+		return v
+	}
+	return nil
+}
+
+func cmdHMGet(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	out := make([]interface{}, 0, len(args)-2)
+	for _, f := range args[2:] {
+		if it != nil {
+			if v, exists := it.Hash[f]; exists {
+				out = append(out, v)
+				continue
+			}
+		}
+		out = append(out, nil)
+	}
+	return out
+}
+
+func cmdHGetAll(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	out := []string{}
+	if it == nil {
+		return out
+	}
+	for _, f := range sortedKeys(it.Hash) {
+		out = append(out, f, it.Hash[f])
+	}
+	return out
+}
+
+func cmdHDel(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil || it == nil {
+		if errR != nil {
+			return errR
+		}
+		return int64(0)
+	}
+	var deleted int64
+	for _, f := range args[2:] {
+		if _, exists := it.Hash[f]; exists {
+			delete(it.Hash, f)
+			deleted++
+		}
+	}
+	n.dropIfEmpty(args[1], it)
+	return deleted
+}
+
+func cmdHExists(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	_, exists := it.Hash[args[2]]
+	return boolInt(exists)
+}
+
+func cmdHLen(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	return int64(len(it.Hash))
+}
+
+func cmdHKeys(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return []string{}
+	}
+	return sortedKeys(it.Hash)
+}
+
+func cmdHVals(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	out := []string{}
+	if it != nil {
+		for _, f := range sortedKeys(it.Hash) {
+			out = append(out, it.Hash[f])
+		}
+	}
+	return out
+}
+
+func cmdHIncrBy(n *Node, args []string) interface{} {
+	delta, valid := parseInt(args[3])
+	if !valid {
+		return errNotInt
+	}
+	it, errR := n.getOrCreate(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	var cur int64
+	if v, exists := it.Hash[args[2]]; exists {
+		if cur, valid = parseInt(v); !valid {
+			return errReply("ERR hash value is not an integer")
+		}
+	}
+	cur += delta
+	it.Hash[args[2]] = strconv.FormatInt(cur, 10)
+	return cur
+}
+
+func cmdHIncrByFloat(n *Node, args []string) interface{} {
+	delta, valid := parseFloat(args[3])
+	if !valid {
+		return errNotFloat
+	}
+	it, errR := n.getOrCreate(args[1], kindHash)
+	if errR != nil {
+		return errR
+	}
+	var cur float64
+	if v, exists := it.Hash[args[2]]; exists {
+		if cur, valid = parseFloat(v); !valid {
+			return errReply("ERR hash value is not a float")
+		}
+	}
+	it.Hash[args[2]] = formatFloat(cur + delta)
+	return it.Hash[args[2]]
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Sets
+
+func cmdSAdd(n *Node, args []string) interface{} {
+	it, errR := n.getOrCreate(args[1], kindSet)
+	if errR != nil {
+		return errR
+	}
+	var added int64
+	for _, m := range args[2:] {
+		if !it.Set[m] {
+			it.Set[m] = true
+			added++
+		}
+	}
+	return added
+}
+
+func cmdSRem(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	var removed int64
+	for _, m := range args[2:] {
+		if it.Set[m] {
+			delete(it.Set, m)
+			removed++
+		}
+	}
+	n.dropIfEmpty(args[1], it)
+	return removed
+}
+
+func cmdSMembers(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindSet)
+	if errR != nil {
+		return errR
+	}
+	out := []string{}
+	if it != nil {
+		for m := range it.Set {
+			out = append(out, m)
+		}
+		sort.Strings(out)
+	}
+	return out
+}
+
+func cmdSIsMember(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindSet)
+	if errR != nil {
+		return errR
+	}
+	return boolInt(it != nil && it.Set[args[2]])
+}
+
+func cmdSCard(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	return int64(len(it.Set))
+}
+
+// Sorted sets
+
+func cmdZAdd(n *Node, args []string) interface{} {
+	i := 2
+	var nx, xx, ch, incr bool
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "CH":
+			ch = true
+		case "INCR":
+			incr = true
+		case "GT", "LT":
+		default:
+			goto pairs
+		}
+	}
+pairs:
+	if (len(args)-i)%2 != 0 || i == len(args) {
+		return errSyntax
+	}
+	it, errR := n.getOrCreate(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	var added, changed int64
+	var last float64
+	for ; i < len(args); i += 2 {
+		score, valid := parseFloat(args[i])
+		if !valid {
+			return errNotFloat
+		}
+		member := args[i+1]
+		old, exists := it.ZSet[member]
+		if nx && exists || xx && !exists {
+			continue
+		}
+		if incr {
+			score += old
+		}
+		if !exists {
+			added++
+		} else if old != score {
+			changed++
+		}
+		it.ZSet[member] = score
+		last = score
+	}
+	n.dropIfEmpty(args[1], it)
+	switch {
+	case incr:
+		return formatFloat(last)
+	case ch:
+		return added + changed
+	}
+	return added
+}
+
+func cmdZRem(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	var removed int64
+	for _, m := range args[2:] {
+		if _, exists := it.ZSet[m]; exists {
+			delete(it.ZSet, m)
+			removed++
+		}
+	}
+	n.dropIfEmpty(args[1], it)
+	return removed
+}
+
+func cmdZCard(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	return int64(len(it.ZSet))
+}
+
+func cmdZScore(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return nil
+	}
+	if s, exists := it.ZSet[args[2]]; exists {
+		return formatFloat(s)
+	}
+	return nil
+}
+
+func cmdZIncrBy(n *Node, args []string) interface{} {
+	delta, valid := parseFloat(args[2])
+	if !valid {
+		return errNotFloat
+	}
+	it, errR := n.getOrCreate(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	it.ZSet[args[3]] += delta
+	return formatFloat(it.ZSet[args[3]])
+}
+
+func zreply(members []zmember, withScores bool) []string {
+	out := []string{}
+	for _, m := range members {
+		out = append(out, m.Member)
+		if withScores {
+			out = append(out, formatFloat(m.Score))
+		}
+	}
+	return out
+}
+
+// cmdZRange supports ZRANGE/ZREVRANGE key start stop [REV] [WITHSCORES] by index
+func cmdZRange(n *Node, args []string) interface{} {
+	start, ok1 := parseInt(args[2])
+	stop, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return errNotInt
+	}
+	rev := strings.ToUpper(args[0]) == "ZREVRANGE"
+	withScores := false
+	for _, opt := range args[4:] {
+		switch strings.ToUpper(opt) {
+		case "WITHSCORES":
+			withScores = true
+		case "REV":
+			rev = true
+		default:
+			return errSyntax
+		}
+	}
+	it, errR := n.get(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return []string{}
+	}
+	members := sortedZSet(it.ZSet)
+	if rev {
+		for i, j := 0, len(members)-1; i < j; i, j = i+1, j-1 {
+			members[i], members[j] = members[j], members[i]
+		}
+	}
+	lo, hi, empty := clampRange(start, stop, len(members))
+	if empty {
+		return []string{}
+	}
+	return zreply(members[lo:hi+1], withScores)
+}
+
+// clampRange converts Redis start/stop indexes (negative from the end) into
+// an inclusive slice range
+func clampRange(start, stop int64, size int) (int, int, bool) {
+	n := int64(size)
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop || start >= n {
+		return 0, 0, true
+	}
+	return int(start), int(stop), false
+}
+
+// parseScoreBound parses a ZRANGEBYSCORE bound such as "5", "(5" or "-inf"
+func parseScoreBound(s string) (float64, bool, bool) {
+	exclusive := strings.HasPrefix(s, "(")
+	v, valid := parseFloat(strings.TrimPrefix(s, "("))
+	return v, exclusive, valid
+}
+
+func cmdZRangeByScore(n *Node, args []string) interface{} {
+	min, minEx, ok1 := parseScoreBound(args[2])
+	max, maxEx, ok2 := parseScoreBound(args[3])
+	if !ok1 || !ok2 {
+		return errReply("ERR min or max is not a float")
+	}
+	withScores := false
+	offset, count := int64(0), int64(-1)
+	for i := 4; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return errSyntax
+			}
+			offset, _ = parseInt(args[i+1])
+			count, _ = parseInt(args[i+2])
+			i += 2
+		default:
+			return errSyntax
+		}
+	}
+	it, errR := n.get(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return []string{}
+	}
+	var in []zmember
+	for _, m := range sortedZSet(it.ZSet) {
+		if m.Score < min || minEx && m.Score == min || m.Score > max || maxEx && m.Score == max {
+			continue
+		}
+		in = append(in, m)
+	}
+	if offset > int64(len(in)) {
+		offset = int64(len(in))
+	}
+	in = in[offset:]
+	if count >= 0 && count < int64(len(in)) {
+		in = in[:count]
+	}
+	return zreply(in, withScores)
+}
+
+func cmdZRank(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return nil
+	}
+	for i, m := range sortedZSet(it.ZSet) {
+		if m.Member == args[2] {
+			return int64(i)
+		}
+	}
+	return nil
+}
+
+func cmdZPopMin(n *Node, args []string) interface{} {
+	count := int64(1)
+	if len(args) > 2 {
+		var valid bool
+		if count, valid = parseInt(args[2]); !valid || count < 0 {
+			return errNotInt
+		}
+	}
+	it, errR := n.get(args[1], kindZSet)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return []string{}
+	}
+	members := sortedZSet(it.ZSet)
+	if count < int64(len(members)) {
+		members = members[:count]
+	}
+	for _, m := range members {
+		delete(it.ZSet, m.Member)
+	}
+	n.dropIfEmpty(args[1], it)
+	return zreply(members, true)
+}
+
+// Lists
+
+func cmdPush(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindList)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		it = &item{Kind: kindList}
+		n.db[args[1]] = it
+	}
+	for _, v := range args[2:] {
+		if strings.ToUpper(args[0]) == "LPUSH" {
+			it.List = append([]string{v}, it.List...)
+		} else {
+			it.List = append(it.List, v)
+		}
+	}
+	return int64(len(it.List))
+}
+
+func cmdPop(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindList)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		if len(args) > 2 {
+			return nilArray{}
+		}
+		return nil
+	}
+	count := int64(1)
+	if len(args) > 2 {
+		var valid bool
+		if count, valid = parseInt(args[2]); !valid || count < 0 {
+			return errNotInt
+		}
+	}
+	if count > int64(len(it.List)) {
+		count = int64(len(it.List))
+	}
+	var popped []string
+	if strings.ToUpper(args[0]) == "LPOP" {
+		popped, it.List = append([]string(nil), it.List[:count]...), it.List[count:]
+	} else {
+		cut := int64(len(it.List)) - count
+		popped = append([]string(nil), it.List[cut:]...)
+		for i, j := 0, len(popped)-1; i < j; i, j = i+1, j-1 {
+			popped[i], popped[j] = popped[j], popped[i]
+		}
+		it.List = it.List[:cut]
+	}
+	n.dropIfEmpty(args[1], it)
+	if len(args) > 2 {
+		return popped
+	}
+	return popped[0]
+}
+
+func cmdLLen(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindList)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	return int64(len(it.List))
+}
+
+func cmdLRange(n *Node, args []string) interface{} {
+	start, ok1 := parseInt(args[2])
+	stop, ok2 := parseInt(args[3])
+	if !ok1 || !ok2 {
+		return errNotInt
+	}
+	it, errR := n.get(args[1], kindList)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return []string{}
+	}
+	lo, hi, empty := clampRange(start, stop, len(it.List))
+	if empty {
+		return []string{}
+	}
+	return append([]string(nil), it.List[lo:hi+1]...)
+}
+
+// Streams
+
+func cmdXAdd(n *Node, args []string) interface{} {
+	i := 2
+	maxLen := int64(-1)
+	noMkStream := false
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "NOMKSTREAM":
+			noMkStream = true
+			continue
+		case "MAXLEN":
+			i++
+			if i < len(args) && (args[i] == "~" || args[i] == "=") {
+				i++
+			}
+			if i >= len(args) {
+				return errSyntax
+			}
+			var valid bool
+			if maxLen, valid = parseInt(args[i]); !valid {
+				return errNotInt
+			}
+			continue
+		}
+		break
+	}
+	if i >= len(args) || (len(args)-i-1)%2 != 0 || len(args)-i-1 == 0 {
+		return errWrongArgs("xadd")
+	}
+
+	if noMkStream && n.lookupLocked(args[1]) == nil {
+		return nil
+	}
+	it, errR := n.getOrCreate(args[1], kindStream)
+	if errR != nil {
+		return errR
+	}
+	s := it.Stream
+
+	var id streamID
+	if args[i] == "*" {
+		id = streamID{Ms: uint64(time.Now().UnixMilli())}
+		if !s.LastID.less(id) {
+			id = streamID{Ms: s.LastID.Ms, Seq: s.LastID.Seq + 1}
+		}
+	} else {
+		var valid bool
+		if id, valid = parseStreamID(args[i], 0); !valid {
+			return errReply("ERR Invalid stream ID specified as stream command argument")
+		}
+		if !s.LastID.less(id) {
+			return errReply("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+		}
+	}
+	s.Entries = append(s.Entries, streamEntry{ID: id, Fields: append([]string(nil), args[i+1:]...)})
+	s.LastID = id
+	if maxLen >= 0 && int64(len(s.Entries)) > maxLen {
+		s.Entries = s.Entries[int64(len(s.Entries))-maxLen:]
+	}
+	return id.String()
+}
+
+func cmdXLen(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindStream)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	return int64(len(it.Stream.Entries))
+}
+
+func cmdXRange(n *Node, args []string) interface{} {
+	start, ok1 := parseStreamID(args[2], 0)
+	end, ok2 := parseStreamID(args[3], ^uint64(0))
+	if !ok1 || !ok2 {
+		return errReply("ERR Invalid stream ID specified as stream command argument")
+	}
+	count := int64(-1)
+	if len(args) == 6 && strings.ToUpper(args[4]) == "COUNT" {
+		count, _ = parseInt(args[5])
+	}
+	it, errR := n.get(args[1], kindStream)
+	if errR != nil {
+		return errR
+	}
+	out := []interface{}{}
+	if it == nil {
+		return out
+	}
+	for _, e := range it.Stream.Entries {
+		if e.ID.less(start) || end.less(e.ID) {
+			continue
+		}
+		if count >= 0 && int64(len(out)) >= count {
+			break
+		}
+		out = append(out, it.Stream.reply(e))
+	}
+	return out
+}
+
+func cmdXDel(n *Node, args []string) interface{} {
+	it, errR := n.get(args[1], kindStream)
+	if errR != nil {
+		return errR
+	}
+	if it == nil {
+		return int64(0)
+	}
+	del := make(map[streamID]bool)
+	for _, a := range args[2:] {
+		if id, valid := parseStreamID(a, 0); valid {
+			del[id] = true
+		}
+	}
+	var deleted int64
+	kept := it.Stream.Entries[:0]
+	for _, e := range it.Stream.Entries {
+		if del[e.ID] {
+			deleted++
+			continue
+		}
+		kept = append(kept, e)
+	}
+	it.Stream.Entries = kept
+	return deleted
+}
+
+// xreadArgs splits XREAD [COUNT n] [BLOCK ms] STREAMS k1 k2 id1 id2
+func xreadArgs(args []string) (count, block int64, keys, ids []string, valid bool) {
+	block = -1
+	for i := 1; i < len(args); i++ {
+		switch strings.ToUpper(args[i]) {
+		case "COUNT":
+			if i+1 < len(args) {
+				count, _ = parseInt(args[i+1])
+			}
+			i++
+		case "BLOCK":
+			if i+1 < len(args) {
+				block, _ = parseInt(args[i+1])
+			}
+			i++
+		case "STREAMS":
+			rest := args[i+1:]
+			if len(rest) == 0 || len(rest)%2 != 0 {
+				return 0, 0, nil, nil, false
+			}
+			return count, block, rest[:len(rest)/2], rest[len(rest)/2:], true
+		}
+	}
+	return 0, 0, nil, nil, false
+}
+
+func xreadKeys(args []string) []string {
+	_, _, keys, _, _ := xreadArgs(args)
+	return keys
+}
+
+func cmdXRead(n *Node, args []string) interface{} {
+	count, _, keys, ids, valid := xreadArgs(args)
+	if !valid {
+		return errReply("ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.")
+	}
+	var out []interface{}
+	for i, key := range keys {
+		it, errR := n.get(key, kindStream)
+		if errR != nil {
+			return errR
+		}
+		if it == nil || ids[i] == "$" {
+			continue
+		}
+		id, valid := parseStreamID(ids[i], 0)
+		if !valid {
+			return errReply("ERR Invalid stream ID specified as stream command argument")
+		}
+		if entries := it.Stream.after(id, int(count)); len(entries) > 0 {
+			out = append(out, []interface{}{key, entries})
+		}
+	}
+	if out == nil {
+		return nilArray{}
+	}
+	return out
+}
+
+// cmdXReadBlocking polls XREAD until data arrives or BLOCK times out
+func cmdXReadBlocking(n *Node, args []string) interface{} {
+	_, block, keys, ids, valid := xreadArgs(args)
+	if !valid {
+		return cmdXRead(n, args)
+	}
+
+	// "$" means entries added after this call started
+	n.mu.Lock()
+	args = append([]string(nil), args...)
+	for i, id := range ids {
+		if id == "$" {
+			last := "0-0"
+			if it := n.lookupLocked(keys[i]); it != nil && it.Kind == kindStream {
+				last = it.Stream.LastID.String()
+			}
+			args[len(args)-len(ids)+i] = last
+		}
+	}
+	n.mu.Unlock()
+
+	var deadline time.Time
+	if block > 0 {
+		deadline = time.Now().Add(time.Duration(block) * time.Millisecond)
+	}
+	for {
+		n.mu.Lock()
+		r := cmdXRead(n, args)
+		n.mu.Unlock()
+		if _, empty := r.(nilArray); !empty || block < 0 || !deadline.IsZero() && time.Now().After(deadline) {
+			return r
+		}
+		if n.Failed() {
+			return nilArray{}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
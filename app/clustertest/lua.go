@@ -0,0 +1,275 @@
+package clustertest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"math"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"ticket-reservation/cluster"
+)
+
+func sha1hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// evalKeys returns the keys of EVAL/EVALSHA script numkeys key... arg...
+func evalKeys(args []string) []string {
+	numKeys, valid := parseInt(args[2])
+	if !valid || numKeys < 0 || int(numKeys) > len(args)-3 {
+		return nil
+	}
+	return args[3 : 3+numKeys]
+}
+
+func cmdEval(n *Node, args []string) interface{} {
+	sha := sha1hex(args[1])
+	n.scripts[sha] = args[1]
+	return n.runScript(args[1], args)
+}
+
+func cmdEvalSHA(n *Node, args []string) interface{} {
+	script, found := n.scripts[strings.ToLower(args[1])]
+	if !found {
+		return errReply("NOSCRIPT No matching script. Please use EVAL.")
+	}
+	return n.runScript(script, args)
+}
+
+func cmdScript(n *Node, args []string) interface{} {
+	switch strings.ToUpper(args[1]) {
+	case "LOAD":
+		if len(args) != 3 {
+			return errWrongArgs("script|load")
+		}
+		L := lua.NewState(lua.Options{SkipOpenLibs: true})
+		_, err := L.LoadString(args[2])
+		L.Close()
+		if err != nil {
+			return errorf("ERR Error compiling script (new function): %v", err)
+		}
+		sha := sha1hex(args[2])
+		n.scripts[sha] = args[2]
+		return sha
+	case "EXISTS":
+		out := make([]interface{}, 0, len(args)-2)
+		for _, sha := range args[2:] {
+			_, found := n.scripts[strings.ToLower(sha)]
+			out = append(out, boolInt(found))
+		}
+		return out
+	case "FLUSH":
+		n.scripts = make(map[string]string)
+		return ok
+	}
+	return errorf("ERR unknown subcommand '%s'", args[1])
+}
+
+// runScript runs a Lua script with the keyspace locked, so it is atomic
+// like in Redis
+func (n *Node) runScript(script string, args []string) interface{} {
+	numKeys, valid := parseInt(args[2])
+	if !valid {
+		return errNotInt
+	}
+	if numKeys < 0 || int(numKeys) > len(args)-3 {
+		return errReply("ERR Number of keys can't be greater than number of args")
+	}
+	keys, argv := args[3:3+numKeys], args[3+numKeys:]
+
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	for _, open := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(open.fn))
+		L.Push(lua.LString(open.name))
+		L.Call(1, 0)
+	}
+	L.SetGlobal("KEYS", stringsTable(L, keys))
+	L.SetGlobal("ARGV", stringsTable(L, argv))
+	L.SetGlobal("redis", n.redisModule(L))
+
+	fn, err := L.LoadString(script)
+	if err != nil {
+		return errorf("ERR Error compiling script (new function): %v", err)
+	}
+	L.Push(fn)
+	if err := L.PCall(0, 1, nil); err != nil {
+		if apiErr, isAPI := err.(*lua.ApiError); isAPI {
+			if t, isTable := apiErr.Object.(*lua.LTable); isTable {
+				if msg := t.RawGetString("err"); msg != lua.LNil {
+					return errReply(msg.String())
+				}
+			}
+		}
+		return errorf("ERR user_script: %v", err)
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+	return luaToReply(ret)
+}
+
+// redisModule builds the script's redis table
+func (n *Node) redisModule(L *lua.LState) *lua.LTable {
+	mod := L.NewTable()
+	call := func(raise bool) lua.LGFunction {
+		return func(L *lua.LState) int {
+			top := L.GetTop()
+			if top == 0 {
+				L.RaiseError("Please specify at least one argument for this redis lib call")
+			}
+			args := make([]string, top)
+			for i := 1; i <= top; i++ {
+				switch v := L.Get(i).(type) {
+				case lua.LString:
+					args[i-1] = string(v)
+				case lua.LNumber:
+					args[i-1] = formatLuaNumber(v)
+				default:
+					L.RaiseError("Lua redis lib command arguments must be strings or integers")
+				}
+			}
+			r := n.callFromScript(args)
+			if e, isErr := r.(errReply); isErr && raise {
+				t := L.NewTable()
+				t.RawSetString("err", lua.LString(e))
+				L.Error(t, 1)
+			}
+			L.Push(replyToLua(L, r))
+			return 1
+		}
+	}
+	mod.RawSetString("call", L.NewFunction(call(true)))
+	mod.RawSetString("pcall", L.NewFunction(call(false)))
+	mod.RawSetString("sha1hex", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(sha1hex(L.CheckString(1))))
+		return 1
+	}))
+	mod.RawSetString("error_reply", L.NewFunction(func(L *lua.LState) int {
+		t := L.NewTable()
+		t.RawSetString("err", lua.LString(L.CheckString(1)))
+		L.Push(t)
+		return 1
+	}))
+	mod.RawSetString("status_reply", L.NewFunction(func(L *lua.LState) int {
+		t := L.NewTable()
+		t.RawSetString("ok", lua.LString(L.CheckString(1)))
+		L.Push(t)
+		return 1
+	}))
+	mod.RawSetString("log", L.NewFunction(func(L *lua.LState) int { return 0 }))
+	for name, level := range map[string]int{"LOG_DEBUG": 0, "LOG_VERBOSE": 1, "LOG_NOTICE": 2, "LOG_WARNING": 3} {
+		mod.RawSetString(name, lua.LNumber(level))
+	}
+	return mod
+}
+
+// callFromScript runs a redis.call command. Keys must belong to this node;
+// scripts cannot follow redirects.
+func (n *Node) callFromScript(args []string) interface{} {
+	spec, found := commands[strings.ToUpper(args[0])]
+	if !found || spec.fn == nil {
+		return errReply("ERR Unknown Redis command called from script")
+	}
+	n.cluster.mu.RLock()
+	for _, k := range spec.keys(args) {
+		if n.cluster.owner[cluster.KeySlot(k)] != n {
+			n.cluster.mu.RUnlock()
+			return errReply("ERR Script attempted to access a non local key in a cluster node")
+		}
+	}
+	n.cluster.mu.RUnlock()
+	return n.callLocked(args)
+}
+
+func stringsTable(L *lua.LState, values []string) *lua.LTable {
+	t := L.CreateTable(len(values), 0)
+	for _, v := range values {
+		t.Append(lua.LString(v))
+	}
+	return t
+}
+
+func formatLuaNumber(v lua.LNumber) string {
+	f := float64(v)
+	if f == math.Trunc(f) && math.Abs(f) < 1e17 {
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', 17, 64)
+}
+
+// replyToLua converts a command reply as Redis does: nil becomes false,
+// status and error replies become {ok=...} and {err=...} tables
+func replyToLua(L *lua.LState, r interface{}) lua.LValue {
+	switch v := r.(type) {
+	case nil, nilArray:
+		return lua.LFalse
+	case status:
+		t := L.NewTable()
+		t.RawSetString("ok", lua.LString(v))
+		return t
+	case errReply:
+		t := L.NewTable()
+		t.RawSetString("err", lua.LString(v))
+		return t
+	case int:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []string:
+		return stringsTable(L, v)
+	case []interface{}:
+		t := L.CreateTable(len(v), 0)
+		for _, e := range v {
+			t.Append(replyToLua(L, e))
+		}
+		return t
+	}
+	return lua.LFalse
+}
+
+// luaToReply converts a script's return value: numbers are truncated to
+// integers, false becomes nil and arrays stop at the first nil
+func luaToReply(v lua.LValue) interface{} {
+	switch v := v.(type) {
+	case lua.LString:
+		return string(v)
+	case lua.LNumber:
+		return int64(v)
+	case lua.LBool:
+		if v {
+			return int64(1)
+		}
+		return nil
+	case *lua.LTable:
+		if e := v.RawGetString("err"); e != lua.LNil {
+			return errReply(e.String())
+		}
+		if s := v.RawGetString("ok"); s != lua.LNil {
+			return status(s.String())
+		}
+		out := []interface{}{}
+		for i := 1; ; i++ {
+			e := v.RawGetInt(i)
+			if e == lua.LNil {
+				break
+			}
+			out = append(out, luaToReply(e))
+		}
+		return out
+	}
+	return nil
+}
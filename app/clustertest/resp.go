@@ -0,0 +1,123 @@
+package clustertest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Reply values produced by commands and written by writeReply:
+//
+//	string        bulk string
+//	status        simple string (+OK)
+//	errReply      error (-ERR ...)
+//	int64, int    integer
+//	nil           null bulk string
+//	nilArray      null array
+//	[]interface{} array of replies
+//	[]string      array of bulk strings
+type (
+	status   string
+	errReply string
+	nilArray struct{}
+)
+
+var ok = status("OK")
+
+func errorf(format string, args ...interface{}) errReply {
+	return errReply(fmt.Sprintf(format, args...))
+}
+
+// Common errors, worded like Redis so client error checks behave the same
+var (
+	errWrongType = errReply("WRONGTYPE Operation against a key holding the wrong kind of value")
+	errNotInt    = errReply("ERR value is not an integer or out of range")
+	errNotFloat  = errReply("ERR value is not a valid float")
+	errSyntax    = errReply("ERR syntax error")
+)
+
+func errWrongArgs(cmd string) errReply {
+	return errorf("ERR wrong number of arguments for '%s' command", strings.ToLower(cmd))
+}
+
+// readCommand reads one command: a RESP array of bulk strings, or an inline
+// command line as typed into telnet
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, nil
+	}
+	if line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("invalid multibulk length %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(line) == 0 || line[0] != '$' {
+			return nil, fmt.Errorf("expected bulk string, got %q", line)
+		}
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// writeReply encodes v as RESP2
+func writeReply(w *bufio.Writer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteString("$-1\r\n")
+	case nilArray:
+		w.WriteString("*-1\r\n")
+	case status:
+		w.WriteString("+" + string(v) + "\r\n")
+	case errReply:
+		w.WriteString("-" + strings.ReplaceAll(string(v), "\r\n", " ") + "\r\n")
+	case int:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []string:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, s := range v {
+			writeReply(w, s)
+		}
+	case []interface{}:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, e := range v {
+			writeReply(w, e)
+		}
+	default:
+		writeReply(w, errorf("ERR internal: unknown reply type %T", v))
+	}
+}
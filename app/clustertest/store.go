@@ -0,0 +1,207 @@
+package clustertest
+
+import (
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Value kinds, named as TYPE reports them
+const (
+	kindString = "string"
+	kindHash   = "hash"
+	kindSet    = "set"
+	kindZSet   = "zset"
+	kindList   = "list"
+	kindStream = "stream"
+)
+
+// item is one key's value
+type item struct {
+	Kind     string             `json:"kind"`
+	Str      string             `json:"str,omitempty"`
+	Hash     map[string]string  `json:"hash,omitempty"`
+	Set      map[string]bool    `json:"set,omitempty"`
+	ZSet     map[string]float64 `json:"zset,omitempty"`
+	List     []string           `json:"list,omitempty"`
+	Stream   *stream            `json:"stream,omitempty"`
+	ExpireAt time.Time          `json:"-"`
+
+	hits int64 // client accesses, reported by OBJECT FREQ
+}
+
+func (it *item) expired(now time.Time) bool {
+	return !it.ExpireAt.IsZero() && !now.Before(it.ExpireAt)
+}
+
+// lookupLocked returns the live item for key, deleting it if it expired
+func (n *Node) lookupLocked(key string) *item {
+	it, found := n.db[key]
+	if !found {
+		return nil
+	}
+	if it.expired(time.Now()) {
+		delete(n.db, key)
+		n.expired.Add(1)
+		return nil
+	}
+	return it
+}
+
+// touchLocked counts a client access to each existing key
+func (n *Node) touchLocked(keys []string) {
+	for _, key := range keys {
+		if it := n.db[key]; it != nil {
+			it.hits++
+		}
+	}
+}
+
+// get returns key's item if it has the wanted kind. A missing key returns
+// (nil, nil); a key of another kind returns errWrongType.
+func (n *Node) get(key, kind string) (*item, interface{}) {
+	it := n.lookupLocked(key)
+	if it == nil {
+		n.misses.Add(1)
+		return nil, nil
+	}
+	n.hits.Add(1)
+	if it.Kind != kind {
+		return nil, errWrongType
+	}
+	return it, nil
+}
+
+// getOrCreate returns key's item, creating an empty one of kind if missing
+func (n *Node) getOrCreate(key, kind string) (*item, interface{}) {
+	it, errR := n.get(key, kind)
+	if errR != nil {
+		return nil, errR
+	}
+	if it == nil {
+		it = &item{Kind: kind}
+		switch kind {
+		case kindHash:
+			it.Hash = make(map[string]string)
+		case kindSet:
+			it.Set = make(map[string]bool)
+		case kindZSet:
+			it.ZSet = make(map[string]float64)
+		case kindStream:
+			it.Stream = &stream{}
+		}
+		n.db[key] = it
+	}
+	return it, nil
+}
+
+// dropIfEmpty deletes key when its collection became empty, as Redis does
+func (n *Node) dropIfEmpty(key string, it *item) {
+	empty := false
+	switch it.Kind {
+	case kindHash:
+		empty = len(it.Hash) == 0
+	case kindSet:
+		empty = len(it.Set) == 0
+	case kindZSet:
+		empty = len(it.ZSet) == 0
+	case kindList:
+		empty = len(it.List) == 0
+	}
+	if empty {
+		delete(n.db, key)
+	}
+}
+
+// zmember is a sorted set member with its score
+type zmember struct {
+	Member string
+	Score  float64
+}
+
+// sortedZSet returns the members ordered by score, then member
+func sortedZSet(z map[string]float64) []zmember {
+	out := make([]zmember, 0, len(z))
+	for m, s := range z {
+		out = append(out, zmember{m, s})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score < out[j].Score
+		}
+		return out[i].Member < out[j].Member
+	})
+	return out
+}
+
+// streamID is a stream entry ID (ms-seq)
+type streamID struct {
+	Ms  uint64 `json:"ms"`
+	Seq uint64 `json:"seq"`
+}
+
+func (id streamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+func (id streamID) less(o streamID) bool {
+	return id.Ms < o.Ms || id.Ms == o.Ms && id.Seq < o.Seq
+}
+
+// parseStreamID parses "ms-seq" or "ms"; missingSeq is used for a bare ms
+func parseStreamID(s string, missingSeq uint64) (streamID, bool) {
+	switch s {
+	case "-":
+		return streamID{}, true
+	case "+":
+		return streamID{^uint64(0), ^uint64(0)}, true
+	}
+	ms, seq := s, ""
+	for i := 0; i < len(s); i++ {
+		if s[i] == '-' {
+			ms, seq = s[:i], s[i+1:]
+			break
+		}
+	}
+	var id streamID
+	var err error
+	if id.Ms, err = strconv.ParseUint(ms, 10, 64); err != nil {
+		return id, false
+	}
+	if seq == "" {
+		id.Seq = missingSeq
+		return id, true
+	}
+	id.Seq, err = strconv.ParseUint(seq, 10, 64)
+	return id, err == nil
+}
+
+// streamEntry is one stream message
+type streamEntry struct {
+	ID     streamID `json:"id"`
+	Fields []string `json:"fields"` // field, value, field, value, ...
+}
+
+// stream is an append-only log of entries in ID order
+type stream struct {
+	Entries []streamEntry `json:"entries"`
+	LastID  streamID      `json:"last_id"`
+}
+
+func (s *stream) reply(e streamEntry) []interface{} {
+	return []interface{}{e.ID.String(), append([]string(nil), e.Fields...)}
+}
+
+// after returns up to count entries with an ID greater than id (0 = all)
+func (s *stream) after(id streamID, count int) []interface{} {
+	var out []interface{}
+	for _, e := range s.Entries {
+		if id.less(e.ID) {
+			out = append(out, s.reply(e))
+			if count > 0 && len(out) == count {
+				break
+			}
+		}
+	}
+	return out
+}
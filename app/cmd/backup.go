@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// eventKeyPattern matches every hash-tagged key of an event (metadata, seats,
+// stats, reservations set, waitlist); they all live in one slot
+func eventKeyPattern(eventID string) string {
+	return fmt.Sprintf("{event:%s}*", eventID)
+}
+
+// interruptContext returns a context cancelled by Ctrl+C or SIGTERM
+func interruptContext(msg string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-sigChan:
+			fmt.Println("\n" + msg)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sigChan)
+		cancel()
+	}
+}
+
+// Backup writes a compressed, checksummed logical backup of the cluster:
+// every master is scanned and its keys are saved with DUMP and PTTL
+func Backup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	out := fs.String("out", "", "Archive file to write (required)")
+	pattern := fs.String("pattern", "", "Only back up keys matching this glob pattern")
+	eventID := fs.String("event", "", "Only back up this event's keys and its reservations")
+	batch := fs.Int("batch", 500, "Keys per SCAN page and checkpointed batch")
+	progressPath := fs.String("progress", "", "Progress file (default: <out>.progress)")
+	resume := fs.Bool("resume", false, "Continue an interrupted backup")
+	fs.Parse(args)
+
+	if *out == "" {
+		return fmt.Errorf("--out is required")
+	}
+	if *pattern != "" && *eventID != "" {
+		return fmt.Errorf("--pattern and --event cannot be combined")
+	}
+	if *progressPath == "" {
+		*progressPath = *out + ".progress"
+	}
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var st *cluster.BackupState
+	if *resume {
+		st, err = cluster.LoadBackupState(*progressPath)
+		if err != nil {
+			return err
+		}
+		if st.Out != *out {
+			return fmt.Errorf("progress file %s belongs to %s, not %s", *progressPath, st.Out, *out)
+		}
+	} else {
+		if _, err := os.Stat(*progressPath); err == nil {
+			return fmt.Errorf("progress file %s exists: use --resume to continue that backup or delete it", *progressPath)
+		}
+		opts := cluster.BackupOptions{Match: *pattern, BatchSize: *batch}
+		if *eventID != "" {
+			opts.Match = eventKeyPattern(*eventID)
+			// Legacy reservation records are not hash-tagged with the event;
+			// take their IDs from the event's reservations set (newer IDs
+			// have no such key and are skipped)
+			ids, err := client.Redis().SMembers(client.Context(), fmt.Sprintf("{event:%s}:reservations", *eventID)).Result()
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				opts.Keys = append(opts.Keys, "reservation:"+id)
+			}
+		}
+		st = cluster.NewBackupState(*out, opts)
+	}
+
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                        CLUSTER BACKUP                            ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Archive:  %s\n", st.Out)
+	if st.Options.Match != "" {
+		fmt.Printf("  Match:    %s\n", st.Options.Match)
+	}
+	if len(st.Options.Keys) > 0 {
+		fmt.Printf("  Extra:    %d reservation keys\n", len(st.Options.Keys))
+	}
+	if *resume {
+		fmt.Printf("  Resuming: %d keys in %d batches already written\n", st.Entries, st.Batches)
+	}
+	fmt.Printf("  Progress: %s (resume with --resume)\n\n", *progressPath)
+
+	ctx, stop := interruptContext("Interrupt received, finishing current batch...")
+	defer stop()
+
+	start := time.Now()
+	err = client.Backup(ctx, st, *progressPath, func(p cluster.BackupProgress) {
+		node := p.Node
+		if node == "" {
+			node = "(explicit keys)"
+		}
+		fmt.Printf("\r  %-22s %8d keys  %6d batches  %10s", node, p.Entries, p.Batches, formatBytes(p.Bytes))
+	})
+	fmt.Println()
+
+	if err == context.Canceled {
+		fmt.Printf("Stopped after %d keys. Resume with: backup --out %s --resume\n", st.Entries, st.Out)
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("Progress saved to %s. Fix the error and resume with --resume.\n", *progressPath)
+		return err
+	}
+
+	os.Remove(*progressPath)
+	fmt.Printf("\nBacked up %d keys in %d batches (%s) in %v\n",
+		st.Entries, st.Batches, formatBytes(st.Offset), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// Restore replays a backup archive into the cluster with RESTORE REPLACE,
+// routing each key by slot so the target topology may differ from the source
+func Restore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	in := fs.String("in", "", "Archive file to restore (required)")
+	pattern := fs.String("pattern", "", "Only restore keys matching this glob pattern")
+	eventID := fs.String("event", "", "Only restore this event's keys and its reservations")
+	progressPath := fs.String("progress", "", "Progress file (default: <in>.restore-progress)")
+	resume := fs.Bool("resume", false, "Continue an interrupted restore")
+	yes := fs.Bool("yes", false, "Do not ask before overwriting existing keys")
+	fs.Parse(args)
+
+	if *in == "" {
+		return fmt.Errorf("--in is required")
+	}
+	if *pattern != "" && *eventID != "" {
+		return fmt.Errorf("--pattern and --event cannot be combined")
+	}
+	if *progressPath == "" {
+		*progressPath = *in + ".restore-progress"
+	}
+
+	ar, err := cluster.OpenArchive(*in)
+	if err != nil {
+		return err
+	}
+	hdr := ar.Header
+	ar.Close()
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	st := &cluster.RestoreState{In: *in}
+	if *resume {
+		st, err = cluster.LoadRestoreState(*progressPath)
+		if err != nil {
+			return err
+		}
+		if st.In != *in {
+			return fmt.Errorf("progress file %s belongs to %s, not %s", *progressPath, st.In, *in)
+		}
+	} else if _, err := os.Stat(*progressPath); err == nil {
+		return fmt.Errorf("progress file %s exists: use --resume to continue that restore or delete it", *progressPath)
+	}
+
+	match := func(string) bool { return true }
+	filter := "(all keys)"
+	switch {
+	case *pattern != "":
+		filter = *pattern
+		match = func(key string) bool { return cluster.MatchPattern(*pattern, key) }
+	case *eventID != "":
+		filter = "event " + *eventID
+		match = func(key string) bool { return cluster.MatchPattern(eventKeyPattern(*eventID), key) }
+	}
+
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                        CLUSTER RESTORE                           ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Archive:  %s (created %s from %s)\n", *in, hdr.CreatedAt.Format(time.RFC3339), strings.Join(hdr.Seeds, ","))
+	fmt.Printf("  Filter:   %s\n", filter)
+	if *resume {
+		fmt.Printf("  Resuming: %d batches already restored\n", st.Batches)
+	}
+	fmt.Printf("  Progress: %s (resume with --resume)\n\n", *progressPath)
+
+	if !*yes && !*resume && !confirm("Existing keys with the same names will be overwritten. Continue?") {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	ctx, stop := interruptContext("Interrupt received, finishing current batch...")
+	defer stop()
+
+	start := time.Now()
+	printProgress := func(p cluster.RestoreProgress) {
+		fmt.Printf("\r  batch %6d  %8d restored  %8d skipped", p.Batch, p.Restored, p.Skipped)
+	}
+	err = client.Restore(ctx, st, *progressPath, match, printProgress)
+	fmt.Println()
+
+	// Legacy reservation records are not hash-tagged with the event: restore
+	// the ones listed in the event's (now restored) reservations set
+	if err == nil && *eventID != "" {
+		var ids []string
+		ids, err = client.Redis().SMembers(ctx, fmt.Sprintf("{event:%s}:reservations", *eventID)).Result()
+		if err == nil && len(ids) > 0 {
+			wanted := make(map[string]bool, len(ids))
+			for _, id := range ids {
+				wanted["reservation:"+id] = true
+			}
+			fmt.Printf("  Restoring %d reservation records...\n", len(ids))
+			pass := &cluster.RestoreState{In: *in}
+			err = client.Restore(ctx, pass, "", func(key string) bool { return wanted[key] }, nil)
+			st.Restored += pass.Restored
+		}
+	}
+
+	if err == context.Canceled {
+		fmt.Printf("Stopped after %d batches. Resume with: restore --in %s --resume\n", st.Batches, *in)
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("Progress saved to %s. Fix the error and resume with --resume.\n", *progressPath)
+		return err
+	}
+
+	os.Remove(*progressPath)
+	fmt.Printf("\nRestored %d keys (%d skipped by the filter) in %v\n",
+		st.Restored, st.Skipped, time.Since(start).Round(time.Millisecond))
+	return nil
+}
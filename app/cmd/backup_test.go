@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+)
+
+// useFakeCluster points every command at a fresh in-process cluster
+func useFakeCluster(t *testing.T, masters int) *clustertest.Cluster {
+	t.Helper()
+	t.Setenv("PG_DSN", "")
+	fc := clustertest.New(t, masters)
+	prev := clusterConfig
+	SetClusterConfig(fc.Config())
+	t.Cleanup(func() { SetClusterConfig(prev) })
+	return fc
+}
+
+// snapshot returns every key with its value as reported by DUMP
+func snapshot(t *testing.T, fc *clustertest.Cluster) map[string]string {
+	t.Helper()
+	ctx := context.Background()
+	out := make(map[string]string)
+	for _, n := range fc.Nodes() {
+		rdb := redis.NewClient(&redis.Options{Addr: n.Addr()})
+		for _, key := range n.Keys() {
+			v, err := rdb.Dump(ctx, key).Result()
+			if err != nil {
+				t.Fatalf("DUMP %s: %v", key, err)
+			}
+			out[key] = v
+		}
+		rdb.Close()
+	}
+	return out
+}
+
+func keysOf(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	fc := useFakeCluster(t, 3)
+	for _, name := range []string{"Gala", "Opera", "Derby"} {
+		if err := CreateEvent([]string{"--name", name, "--rows", "2", "--seats", "3"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := snapshot(t, fc)
+	if len(before) != 9 {
+		t.Fatalf("expected 9 keys for 3 events, got %v", keysOf(before))
+	}
+
+	archive := filepath.Join(t.TempDir(), "events.rbak")
+	if err := Backup([]string{"--out", archive, "--batch", "2"}); err != nil {
+		t.Fatal(err)
+	}
+	fc.FlushAll()
+	if err := Restore([]string{"--in", archive, "--yes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := snapshot(t, fc)
+	if !reflect.DeepEqual(before, after) {
+		t.Fatalf("restored keys differ:\n before %v\n after  %v", keysOf(before), keysOf(after))
+	}
+}
+
+func TestBackupSingleEvent(t *testing.T) {
+	fc := useFakeCluster(t, 2)
+	for _, name := range []string{"Gala", "Opera"} {
+		if err := CreateEvent([]string{"--name", name, "--rows", "1", "--seats", "2"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var eventKey string
+	for key := range snapshot(t, fc) {
+		if eventKey == "" || key < eventKey {
+			eventKey = key
+		}
+	}
+	// Keys are "{event:ID}", "{event:ID}:seats", ...; the shortest sorts first
+	eventID := eventKey[len("{event:") : len(eventKey)-1]
+
+	archive := filepath.Join(t.TempDir(), "one.rbak")
+	if err := Backup([]string{"--out", archive, "--event", eventID}); err != nil {
+		t.Fatal(err)
+	}
+	fc.FlushAll()
+	if err := Restore([]string{"--in", archive, "--yes"}); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := keysOf(snapshot(t, fc))
+	for _, key := range restored {
+		if cluster.HashTag(key) != "event:"+eventID {
+			t.Fatalf("restored key %s of another event (restored %v)", key, restored)
+		}
+	}
+	if len(restored) != 3 {
+		t.Fatalf("restored %v, want the event's 3 keys", restored)
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// ChaosProxy runs a fault-injecting TCP proxy in front of every node. Point
+// the app at it with the printed --remap rules; faults come from a scenario
+// file and are reported until Ctrl+C.
+func ChaosProxy(args []string) error {
+	fs := flag.NewFlagSet("chaos-proxy", flag.ExitOnError)
+	scenarioPath := fs.String("scenario", "", "Chaos scenario file (required)")
+	listenHost := fs.String("listen-host", "127.0.0.1", "Host the proxy listens on")
+	basePort := fs.Int("base-port", 17001, "Port for the first node; the others follow")
+	interval := fs.Duration("interval", 5*time.Second, "Status report interval")
+	fs.Parse(args)
+
+	if *scenarioPath == "" {
+		return fmt.Errorf("--scenario is required")
+	}
+	scenario, err := cluster.LoadChaosScenario(*scenarioPath)
+	if err != nil {
+		return err
+	}
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	proxy, err := client.StartChaosProxy(cluster.NewChaos(scenario), *listenHost, *basePort)
+	if err != nil {
+		return err
+	}
+	defer proxy.Close()
+
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                        CHAOS PROXY                               ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Scenario: %s\n\n", scenario.Name)
+	for _, r := range proxy.Routes() {
+		fmt.Printf("  %-22s -> %s\n", r.Listen, r.Node)
+	}
+	fmt.Println("\n  Send clients through the proxy with:")
+	fmt.Printf("    ./ticket-reservation --remap %s <command>\n", proxy.RemapRules())
+	fmt.Println("\n  Press Ctrl+C to stop.")
+
+	ctx, stop := interruptContext("Stopping chaos proxy...")
+	defer stop()
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			printChaosStatus(proxy)
+			return nil
+		case <-ticker.C:
+			printChaosStatus(proxy)
+		}
+	}
+}
+
+func printChaosStatus(proxy *cluster.ChaosProxy) {
+	chaos := proxy.Chaos()
+	stats := make(map[string]cluster.ChaosNodeStats)
+	for _, s := range chaos.Stats() {
+		stats[s.Addr] = s
+	}
+
+	fmt.Printf("\n[%s] t=%s\n", time.Now().Format("15:04:05"), chaos.Elapsed().Round(time.Second))
+	fmt.Printf("  %-22s %6s %6s %6s %6s %10s %10s  %s\n", "NODE", "CONNS", "DIALF", "RESETS", "DROPS", "IN", "OUT", "FAULTS")
+	for _, r := range proxy.Routes() {
+		s := stats[r.Node]
+		fmt.Printf("  %-22s %6d %6d %6d %6d %10s %10s  %s\n",
+			r.Node, s.Conns, s.DialFails, s.Resets, s.Drops,
+			formatBytes(s.BytesIn), formatBytes(s.BytesOut), chaos.FaultFor(r.Node, r.Listen))
+	}
+}
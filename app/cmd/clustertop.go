@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// topSortKeys maps --sort values to "less" functions (descending except addr)
+var topSortKeys = map[string]func(a, b *cluster.NodeInfo) bool{
+	"addr":    func(a, b *cluster.NodeInfo) bool { return a.Addr < b.Addr },
+	"ops":     func(a, b *cluster.NodeInfo) bool { return a.Stats.OpsPerSec > b.Stats.OpsPerSec },
+	"mem":     func(a, b *cluster.NodeInfo) bool { return a.Memory.Used > b.Memory.Used },
+	"clients": func(a, b *cluster.NodeInfo) bool { return a.Clients.Connected > b.Clients.Connected },
+	"hits":    func(a, b *cluster.NodeInfo) bool { return a.Stats.KeyspaceHits > b.Stats.KeyspaceHits },
+	"misses":  func(a, b *cluster.NodeInfo) bool { return a.Stats.KeyspaceMisses > b.Stats.KeyspaceMisses },
+	"evicted": func(a, b *cluster.NodeInfo) bool { return a.Stats.EvictedKeys > b.Stats.EvictedKeys },
+	"keys":    func(a, b *cluster.NodeInfo) bool { return a.Keyspace.Keys > b.Keyspace.Keys },
+	"lag":     func(a, b *cluster.NodeInfo) bool { return a.LagBytes > b.LagBytes },
+}
+
+// ClusterTop shows a live per-node dashboard built from INFO
+func ClusterTop(args []string) error {
+	fs := flag.NewFlagSet("cluster-top", flag.ExitOnError)
+	interval := fs.Duration("interval", time.Second, "Refresh interval")
+	sortBy := fs.String("sort", "ops", "Sort by: addr, ops, mem, clients, hits, misses, evicted, keys, lag")
+	count := fs.Int("count", 0, "Stop after this many refreshes (0 = until Ctrl+C)")
+	jsonOut := fs.Bool("json", false, "Stream one JSON snapshot per line instead of drawing")
+	fs.Parse(args)
+
+	less, ok := topSortKeys[*sortBy]
+	if !ok {
+		return fmt.Errorf("unknown --sort %q", *sortBy)
+	}
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	enc := json.NewEncoder(os.Stdout)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for n := 1; ; n++ {
+		infos, err := client.NodeInfos(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		sort.SliceStable(infos, func(i, j int) bool { return less(infos[i], infos[j]) })
+
+		if *jsonOut {
+			if err := enc.Encode(struct {
+				Time  time.Time           `json:"time"`
+				Nodes []*cluster.NodeInfo `json:"nodes"`
+			}{time.Now(), infos}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Print("\033[H\033[2J")
+			printClusterTop(infos, *sortBy, *interval)
+		}
+
+		if *count > 0 && n >= *count {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// printClusterTop draws one dashboard frame
+func printClusterTop(infos []*cluster.NodeInfo, sortBy string, interval time.Duration) {
+	var ops, clients, used, evicted int64
+	for _, n := range infos {
+		ops += n.Stats.OpsPerSec
+		clients += n.Clients.Connected
+		used += n.Memory.Used
+		evicted += n.Stats.EvictedKeys
+	}
+
+	fmt.Printf("cluster-top  %s  every %v  sorted by %s  (Ctrl+C to quit)\n",
+		time.Now().Format("15:04:05"), interval, sortBy)
+	fmt.Printf("nodes: %d  ops/s: %d  clients: %d  memory: %s  evicted: %d\n\n",
+		len(infos), ops, clients, formatBytes(used), evicted)
+
+	fmt.Printf("%-22s %-7s %8s %20s %7s %10s %10s %6s %8s %9s  %s\n",
+		"NODE", "ROLE", "OPS/S", "MEMORY (USED/MAX)", "CLIENTS", "HITS", "MISSES", "HIT%", "EVICTED", "KEYS", "REPLICATION")
+	fmt.Println(strings.Repeat("─", 132))
+	for _, n := range infos {
+		if n.Error != "" {
+			fmt.Printf("%-22s %-7s %s\n", n.Addr, "DOWN", n.Error)
+			continue
+		}
+		role := "master"
+		if n.Replication.IsReplica() {
+			role = "replica"
+		}
+		mem := formatBytes(n.Memory.Used) + "/"
+		if pct := n.Memory.UsedPercent(); pct >= 0 {
+			mem += fmt.Sprintf("%s %3.0f%%", formatBytes(n.Memory.MaxMemory), pct)
+		} else {
+			mem += "∞"
+		}
+		hitRate := "-"
+		if r := n.Stats.HitRate(); r >= 0 {
+			hitRate = fmt.Sprintf("%.1f", r)
+		}
+		fmt.Printf("%-22s %-7s %8d %20s %7d %10d %10d %6s %8d %9d  %s\n",
+			n.Addr, role, n.Stats.OpsPerSec, mem, n.Clients.Connected,
+			n.Stats.KeyspaceHits, n.Stats.KeyspaceMisses, hitRate,
+			n.Stats.EvictedKeys, n.Keyspace.Keys, formatReplication(n))
+	}
+}
+
+// formatReplication summarizes a node's replication state in one column
+func formatReplication(n *cluster.NodeInfo) string {
+	r := n.Replication
+	if r.IsReplica() {
+		lag := "lag ?"
+		if n.LagBytes >= 0 {
+			lag = "lag " + formatBytes(n.LagBytes)
+		}
+		return fmt.Sprintf("of %s link %s, %s", r.MasterAddr, r.MasterLinkStatus, lag)
+	}
+	if len(r.Replicas) == 0 {
+		return fmt.Sprintf("offset %d, no replicas", r.MasterReplOffset)
+	}
+	online := 0
+	var maxLag int64
+	for _, rep := range r.Replicas {
+		if rep.State == "online" {
+			online++
+		}
+		if rep.LagBytes > maxLag {
+			maxLag = rep.LagBytes
+		}
+	}
+	return fmt.Sprintf("offset %d, %d/%d replicas online, max lag %s",
+		r.MasterReplOffset, online, len(r.Replicas), formatBytes(maxLag))
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%dB", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
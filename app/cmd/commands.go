@@ -24,9 +24,18 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+// clusterConfig is the connection configuration shared by every command.
+// main replaces it with the result of the global flags before dispatching.
+var clusterConfig = cluster.DefaultConfig()
+
+// SetClusterConfig sets the cluster connection configuration used by all commands
+func SetClusterConfig(cfg *cluster.ClusterConfig) {
+	clusterConfig = cfg
+}
+
 // ClusterInfo displays Redis cluster status
 func ClusterInfo() error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -50,7 +59,7 @@ func CreateEvent(args []string) error {
 		return fmt.Errorf("event name is required")
 	}
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -105,7 +114,7 @@ func CreateEvent(args []string) error {
 
 // ListEvents lists all events (scans cluster)
 func ListEvents() error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -184,7 +193,7 @@ func GetAvailability(args []string) error {
 	}
 	eventID := positional[0]
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -286,7 +295,7 @@ func ShowSeatMap(args []string) error {
 	}
 	eventID := positional[0]
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -350,27 +359,45 @@ func ReserveSeats(args []string) error {
 	eventID := fs.String("event", "", "Event ID")
 	userID := fs.String("user", "", "User ID")
 	seatsStr := fs.String("seats", "", "Comma-separated seat IDs")
+	count := fs.Int("count", 0, "Hold the best N available seats instead of --seats")
+	rows := fs.String("rows", "", "Row range for --count, e.g. A-C or B")
+	maxPrice := fs.Float64("max-price", 0, "With --count, refuse the event if its seat price is higher (0 = any); prices are per event, not per row")
+	centered := fs.Bool("centered", false, "With --count, prefer seats near the middle of the row")
+	together := fs.Bool("together", false, "With --count, never split the group across rows")
 	name := fs.String("name", "", "Customer name")
 	email := fs.String("email", "", "Customer email")
 	fs.Parse(args)
 
-	if *eventID == "" || *userID == "" || *seatsStr == "" {
-		return fmt.Errorf("event, user, and seats are required")
+	if *eventID == "" || *userID == "" || (*seatsStr == "" && *count <= 0) {
+		return fmt.Errorf("event, user, and seats or count are required")
 	}
 
-	seats := strings.Split(*seatsStr, ",")
-	for i, s := range seats {
-		seats[i] = strings.TrimSpace(strings.ToUpper(s))
+	var seats []string
+	if *seatsStr != "" {
+		seats = strings.Split(*seatsStr, ",")
+		for i, s := range seats {
+			seats[i] = strings.TrimSpace(strings.ToUpper(s))
+		}
+	}
+	prefs := service.SeatPreferences{MaxPrice: *maxPrice, Centered: *centered, Together: *together}
+	prefs.MinRow, prefs.MaxRow, _ = strings.Cut(*rows, "-")
+	if !strings.Contains(*rows, "-") {
+		prefs.MaxRow = prefs.MinRow
 	}
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
 
 	svc := createServiceWithPG(client.Redis(), 15*time.Minute, "")
-	reservation, err := svc.ReserveSeats(*eventID, *userID, seats, *name, *email)
+	var reservation *models.Reservation
+	if len(seats) > 0 {
+		reservation, err = svc.ReserveSeats(*eventID, *userID, seats, *name, *email)
+	} else {
+		reservation, err = svc.ReserveBestAvailable(*eventID, *userID, *count, prefs, *name, *email)
+	}
 	if err != nil {
 		return err
 	}
@@ -406,7 +433,7 @@ func ConfirmReservation(args []string) error {
 		*paymentID = fmt.Sprintf("pay_%d", time.Now().Unix())
 	}
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -437,7 +464,7 @@ func CancelReservation(args []string) error {
 		return fmt.Errorf("reservation ID required")
 	}
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -472,7 +499,7 @@ func JoinWaitlist(args []string) error {
 		return fmt.Errorf("event, user, and email are required")
 	}
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -503,7 +530,7 @@ func RunDemo() error {
 	fmt.Println("   TICKET RESERVATION SYSTEM DEMO")
 	fmt.Println("========================================")
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -619,7 +646,7 @@ func GetKey(args []string) error {
 	}
 	key := args[0]
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -716,7 +743,7 @@ func LoadTest(args []string) error {
 	seatsPerUser := fs.Int("seats", 2, "Seats per user")
 	fs.Parse(args)
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -844,18 +871,48 @@ func RunServer(args []string) error {
 	addr := fs.String("addr", ":8080", "Server address")
 	ttl := fs.Duration("ttl", 15*time.Minute, "Reservation TTL")
 	pgDSN := fs.String("pg-dsn", "", "PostgreSQL DSN (or set PG_DSN env var)")
+	policy := service.DefaultRoutingPolicy()
+	fs.DurationVar(&policy.MaxStaleness, "max-staleness", policy.MaxStaleness, "Max replica lag for replica-allowed reads")
+	fs.DurationVar(&policy.RecentWriteWindow, "ryw-window", policy.RecentWriteWindow, "Read recently written keys from masters for this long")
+	masterOnly := fs.Bool("master-reads", false, "Send every read to masters")
+	logRedirects := fs.Bool("log-redirects", false, "Log every MOVED/ASK redirect")
+	hotKeyRate := fs.Float64("hotkey-sample-rate", 0, "Fraction of Redis commands sampled for /metrics/hotkeys (0 = off)")
+	lagInterval := fs.Duration("lag-interval", 5*time.Second, "Replication lag check interval (0 = no monitor)")
+	lagFlags := registerReplLagFlags(fs, "log")
+	reapInterval := fs.Duration("hold-reap-interval", 5*time.Second, "How often expired holds release their seats (0 = never)")
+	holdNotify := fs.Bool("hold-notify", false, "Also release holds as soon as masters report an expired reservation (keyspace notifications)")
 	fs.Parse(args)
 
 	dsn := *pgDSN
 	if dsn == "" {
 		dsn = os.Getenv("PG_DSN")
 	}
+	if *masterOnly {
+		for class := range policy.Routes {
+			policy.Routes[class] = service.RouteMaster
+		}
+	}
 
-	server, err := api.NewServer(*addr, *ttl, dsn)
+	sinks, err := lagFlags.sinks()
 	if err != nil {
 		return err
 	}
 
+	server, err := api.NewServer(*addr, *ttl, dsn, clusterConfig, policy)
+	if err != nil {
+		return err
+	}
+	server.SetRedirectLogging(*logRedirects)
+	server.SetHotKeySampleRate(*hotKeyRate)
+	if *lagInterval > 0 {
+		server.EnableReplLagMonitor(*lagInterval, lagFlags.thresholds, sinks...)
+	}
+	if *reapInterval > 0 {
+		if err := server.EnableHoldReaper(*reapInterval, *holdNotify); err != nil {
+			return err
+		}
+	}
+
 	// Handle graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -882,7 +939,7 @@ func Reconcile(args []string) error {
 		return fmt.Errorf("PG_DSN environment variable is required for reconciliation")
 	}
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -924,7 +981,7 @@ func PGDemo() error {
 	fmt.Println("  Part 7: Redis + PostgreSQL Patterns")
 	fmt.Println("========================================")
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
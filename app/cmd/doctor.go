@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"ticket-reservation/cluster"
+)
+
+// Doctor runs consistency and risk checks against every node. It returns an
+// error (non-zero exit) when a check fails, or warns with --strict, so it can
+// gate CI jobs.
+func Doctor(args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	minReplicas := fs.Int("min-replicas", 1, "Healthy replicas required per master")
+	strict := fs.Bool("strict", false, "Exit non-zero on warnings too")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	report, err := client.Doctor(client.Context(), cluster.DoctorOptions{MinReplicas: *minReplicas})
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printDoctorReport(report)
+	}
+
+	fails, warns := report.Count(cluster.CheckFail), report.Count(cluster.CheckWarn)
+	if fails > 0 {
+		return fmt.Errorf("doctor: %d checks failed, %d warnings", fails, warns)
+	}
+	if *strict && warns > 0 {
+		return fmt.Errorf("doctor: %d warnings (--strict)", warns)
+	}
+	return nil
+}
+
+// printDoctorReport prints one line per check with its details indented
+func printDoctorReport(report *cluster.DoctorReport) {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                        CLUSTER DOCTOR                            ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Nodes checked: %d\n\n", report.Nodes)
+
+	for _, res := range report.Results {
+		icon := "✓"
+		switch res.Status {
+		case cluster.CheckWarn:
+			icon = "⚠"
+		case cluster.CheckFail:
+			icon = "✗"
+		}
+		fmt.Printf("  %s [%s] %-30s %s\n", icon, res.Status, res.Name, res.Message)
+		for _, d := range res.Details {
+			fmt.Printf("             - %s\n", d)
+		}
+	}
+
+	fmt.Printf("\n  Summary: %d passed, %d warnings, %d failed\n",
+		report.Count(cluster.CheckPass), report.Count(cluster.CheckWarn), report.Count(cluster.CheckFail))
+}
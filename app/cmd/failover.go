@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// Failover promotes a replica with CLUSTER FAILOVER while a probe writes and
+// reads through the cluster client, then reports the client-visible impact
+func Failover(args []string) error {
+	fs := flag.NewFlagSet("failover", flag.ExitOnError)
+	replicaRef := fs.String("replica", "", "Replica to promote: address, node ID or ID prefix (required)")
+	force := fs.Bool("force", false, "CLUSTER FAILOVER FORCE (master unreachable)")
+	takeover := fs.Bool("takeover", false, "CLUSTER FAILOVER TAKEOVER (no quorum needed)")
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for promotion and convergence")
+	interval := fs.Duration("probe-interval", 10*time.Millisecond, "Delay between probe writes")
+	settle := fs.Duration("settle", 2*time.Second, "Keep probing this long before and after the failover")
+	jsonOut := fs.Bool("json", false, "Print the result as JSON")
+	fs.Parse(args)
+
+	if *replicaRef == "" {
+		return fmt.Errorf("--replica is required")
+	}
+	if *force && *takeover {
+		return fmt.Errorf("--force and --takeover are mutually exclusive")
+	}
+	mode := cluster.FailoverDefault
+	if *force {
+		mode = cluster.FailoverForce
+	} else if *takeover {
+		mode = cluster.FailoverTakeover
+	}
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	replica, err := client.ResolveNode(*replicaRef)
+	if err != nil {
+		return err
+	}
+	master, err := client.ResolveNode(replica.MasterID)
+	if err != nil {
+		return fmt.Errorf("cannot find master of %s: %w", replica.Address, err)
+	}
+
+	keys := cluster.ProbeKeysForNode(master, 16)
+	if len(keys) == 0 {
+		return fmt.Errorf("master %s owns no slots; nothing to probe", master.Address)
+	}
+
+	if !*jsonOut {
+		fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+		fmt.Println("║                      MANUAL FAILOVER                             ║")
+		fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+		fmt.Printf("  Replica:  %s (%s)\n", replica.Address, cluster.ShortID(replica.ID))
+		fmt.Printf("  Master:   %s (%s)\n", master.Address, cluster.ShortID(master.ID))
+		fmt.Printf("  Mode:     CLUSTER FAILOVER %s\n", mode)
+		fmt.Printf("  Probe:    %d keys on the master's slots, every %v\n\n", len(keys), *interval)
+	}
+
+	probe := cluster.NewWriteProbe(client, keys, *interval)
+	probe.Start()
+	defer probe.Cleanup()
+	time.Sleep(*settle)
+
+	result, ferr := client.Failover(client.Context(), replica.ID, mode, *timeout)
+
+	time.Sleep(*settle)
+	stats := probe.Stop()
+
+	if *jsonOut {
+		out := struct {
+			Failover *cluster.FailoverResult `json:"failover"`
+			Probe    cluster.ProbeStats      `json:"probe"`
+			Error    string                  `json:"error,omitempty"`
+		}{Failover: result, Probe: stats}
+		if ferr != nil {
+			out.Error = ferr.Error()
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+		return ferr
+	}
+
+	if ferr != nil {
+		fmt.Printf("  ✗ Failover did not complete: %v\n", ferr)
+	} else {
+		fmt.Printf("  ✓ Replica promoted after %v\n", result.Promoted.Round(time.Millisecond))
+		fmt.Printf("  ✓ All nodes agree after  %v\n", result.Converged.Round(time.Millisecond))
+	}
+	printProbeStats(stats)
+	return ferr
+}
+
+// printProbeStats prints the client-impact section of a failover report
+func printProbeStats(s cluster.ProbeStats) {
+	fmt.Println("\n┌── CLIENT IMPACT ──────────────────────────────────────────────────┐")
+	fmt.Printf("│  Probe duration:       %v\n", s.Duration.Round(time.Millisecond))
+	fmt.Printf("│  Writes:               %d (%d failed)\n", s.Writes, s.WriteErrors)
+	fmt.Printf("│  Reads:                %d (%d failed, %d stale)\n", s.Reads, s.ReadErrors, s.StaleReads)
+	fmt.Printf("│  MOVED redirects:      %d\n", s.Moved)
+	fmt.Printf("│  Write unavailability: %v (longest outage %v)\n", s.Unavailable.Round(time.Millisecond), s.LongestOutage.Round(time.Millisecond))
+	if s.LostWrites > 0 {
+		fmt.Printf("│  ✗ Lost acknowledged writes on %d keys: %v\n", s.LostWrites, s.LostKeys)
+	} else {
+		fmt.Println("│  ✓ No acknowledged writes were lost")
+	}
+	if len(s.Errors) > 0 {
+		msgs := make([]string, 0, len(s.Errors))
+		for msg := range s.Errors {
+			msgs = append(msgs, msg)
+		}
+		sort.Slice(msgs, func(i, j int) bool { return s.Errors[msgs[i]] > s.Errors[msgs[j]] })
+		fmt.Println("│  Errors:")
+		for i, msg := range msgs {
+			if i == 5 {
+				fmt.Printf("│    ... %d more kinds\n", len(msgs)-i)
+				break
+			}
+			fmt.Printf("│    %5d × %s\n", s.Errors[msg], msg)
+		}
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+}
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// FixMigrations finds slots stuck in IMPORTING/MIGRATING state (or owned
+// differently depending on which master you ask) and repairs them
+func FixMigrations(args []string) error {
+	fs := flag.NewFlagSet("fix-migrations", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Apply fixes without asking for confirmation")
+	dryRun := fs.Bool("dry-run", false, "Only report what would be fixed")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON instead of a table")
+	reportPath := fs.String("report", "", "Also write the JSON report to this file")
+	batch := fs.Int("batch", 100, "Keys per MIGRATE batch")
+	timeout := fs.Duration("timeout", 5*time.Second, "MIGRATE timeout")
+	replace := fs.Bool("replace", false, "Overwrite keys that already exist on the target")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	ctx := client.Context()
+
+	report, err := client.FindStuckMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !*jsonOut {
+		printMigrationIssues(report)
+	}
+
+	fixable := 0
+	for _, issue := range report.Issues {
+		if !issue.Manual {
+			fixable++
+		}
+	}
+
+	apply := fixable > 0 && !*dryRun
+	if apply && !*yes {
+		apply = confirm(fmt.Sprintf("Apply %d fixes?", fixable))
+	}
+
+	if apply {
+		opts := cluster.MigrateOptions{BatchSize: *batch, Timeout: *timeout, Replace: *replace}
+		for _, issue := range report.Issues {
+			if issue.Manual {
+				continue
+			}
+			result := client.FixMigration(ctx, issue, opts)
+			report.Fixes = append(report.Fixes, result)
+			if *jsonOut {
+				continue
+			}
+			if result.Applied {
+				fmt.Printf("  ✓ slot %5d %-15s %s\n", result.Slot, result.Kind, result.Action)
+			} else {
+				fmt.Printf("  ✗ slot %5d %-15s %s\n", result.Slot, result.Kind, result.Error)
+			}
+		}
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	}
+	if *reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*reportPath, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		if !*jsonOut {
+			fmt.Printf("\nReport written to %s\n", *reportPath)
+		}
+	}
+
+	for _, f := range report.Fixes {
+		if !f.Applied {
+			return fmt.Errorf("some fixes failed; see the report")
+		}
+	}
+	return nil
+}
+
+// printMigrationIssues prints the detected issues and their planned remediation
+func printMigrationIssues(report *cluster.MigrationReport) {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                    STUCK MIGRATION CHECK                         ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Masters queried: %d\n", report.Masters)
+	for _, addr := range report.Unreachable {
+		fmt.Printf("  ⚠ Could not query %s; its view is not included\n", addr)
+	}
+
+	if len(report.Issues) == 0 {
+		fmt.Println("\nNo open migrations and all masters agree on slot ownership.")
+		return
+	}
+
+	fmt.Println("\n┌── ISSUES ─────────────────────────────────────────────────────────┐")
+	for _, issue := range report.Issues {
+		switch issue.Kind {
+		case cluster.IssueOwnerMismatch:
+			var views []string
+			for master, owner := range issue.Owners {
+				views = append(views, cluster.ShortID(master)+"→"+cluster.ShortID(owner))
+			}
+			fmt.Printf("│  slot %5d  %-15s views: %s\n", issue.Slot, issue.Kind, strings.Join(views, " "))
+		default:
+			fmt.Printf("│  slot %5d  %-15s %s -> %s  keys %d/%d\n", issue.Slot, issue.Kind,
+				cluster.ShortID(issue.SourceID), cluster.ShortID(issue.TargetID), issue.SourceKeys, issue.TargetKeys)
+		}
+		marker := "fix:"
+		if issue.Manual {
+			marker = "MANUAL:"
+		}
+		fmt.Printf("│              %s %s\n", marker, issue.Action)
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+}
+
+// confirm asks a yes/no question on stdin (default no). The prompt goes to
+// stderr so it never mixes with --json output.
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "\n%s [y/N]: ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
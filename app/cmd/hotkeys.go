@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/service"
+)
+
+// HotKeys finds the keys and slots that take the most traffic. It samples
+// this process's commands through the client hook, reads OBJECT FREQ on
+// masters with an LFU policy and compares both with each node's ops/sec.
+// --load drives a skewed availability workload over the existing events so
+// there is client traffic to sample.
+func HotKeys(args []string) error {
+	fs := flag.NewFlagSet("hotkeys", flag.ExitOnError)
+	duration := fs.Duration("duration", 10*time.Second, "Sampling window (Ctrl+C ends it early)")
+	rate := fs.Float64("sample-rate", 1, "Fraction of client commands sampled")
+	pattern := fs.String("pattern", "*", "Key pattern checked with OBJECT FREQ")
+	keysPerNode := fs.Int("keys-per-node", 1000, "Keys checked with OBJECT FREQ per LFU master (0 = none)")
+	top := fs.Int("top", 20, "Keys and slots to list")
+	load := fs.Int("load", 0, "Concurrent readers querying event availability during the window (0 = only observe)")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	ctx, stop := interruptContext("Ending the sampling window...")
+	defer stop()
+
+	var wg sync.WaitGroup
+	loadCtx, stopLoad := context.WithCancel(ctx)
+	defer stopLoad()
+	if *load > 0 {
+		events, err := eventIDs(client)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return fmt.Errorf("--load needs at least one event (see create-event)")
+		}
+		svc := service.NewReservationService(client.Redis(), 0)
+		for i := 0; i < *load; i++ {
+			wg.Add(1)
+			go func(seed int64) {
+				defer wg.Done()
+				// Zipf-skewed like real demand: a few events get most reads
+				zipf := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.2, 1, uint64(len(events)-1))
+				for loadCtx.Err() == nil {
+					svc.GetAvailability(events[zipf.Uint64()])
+				}
+			}(time.Now().UnixNano() + int64(i))
+		}
+		fmt.Fprintf(os.Stderr, "Driving %d readers over %d events\n", *load, len(events))
+	}
+
+	fmt.Fprintf(os.Stderr, "Sampling for %v...\n", *duration)
+	report, err := client.DetectHotKeys(ctx, cluster.HotKeyOptions{
+		Window:      *duration,
+		SampleRate:  *rate,
+		Match:       *pattern,
+		KeysPerNode: *keysPerNode,
+		Top:         *top,
+	})
+	stopLoad()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printHotKeyReport(report)
+	return nil
+}
+
+// eventIDs returns the IDs of every event in the cluster, sorted
+func eventIDs(client *cluster.Client) ([]string, error) {
+	ctx := client.Context()
+	var mu sync.Mutex
+	var ids []string
+	err := client.ForEachMaster(func(c *redis.Client) error {
+		iter := c.Scan(ctx, 0, "{event:*}", 100).Iterator()
+		for iter.Next(ctx) {
+			key := iter.Val()
+			mu.Lock()
+			ids = append(ids, key[len("{event:"):len(key)-1])
+			mu.Unlock()
+		}
+		return iter.Err()
+	})
+	sort.Strings(ids)
+	return ids, err
+}
+
+func printHotKeyReport(r *cluster.HotKeyReport) {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                          HOT KEYS                                ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Window: %v   Sample rate: %.2f   Sampled accesses: %d\n", time.Duration(r.Window), r.SampleRate, r.Sampled)
+
+	fmt.Println("\n┌── NODES ──────────────────────────────────────────────────────────┐")
+	fmt.Printf("│  %-22s %10s %12s  %s\n", "NODE", "OPS/SEC", "CLIENT OPS/S", "POLICY")
+	for _, n := range r.Nodes {
+		policy := n.Policy
+		if n.LFU {
+			policy += " (OBJECT FREQ)"
+		}
+		if n.Error != "" {
+			policy = "error: " + n.Error
+		}
+		fmt.Printf("│  %-22s %10.0f %12.0f  %s\n", n.Addr, n.OpsPerSec, n.ClientRate, policy)
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	fmt.Println("\n┌── HOT KEYS ───────────────────────────────────────────────────────┐")
+	if len(r.Keys) == 0 {
+		fmt.Println("│  No key traffic sampled (try --load, or an LFU maxmemory-policy)")
+	} else {
+		fmt.Printf("│  %-36s %9s %6s %5s %6s  %-22s %s\n", "KEY", "OPS/SEC", "SHARE", "LFU", "SLOT", "NODE", "EVENT")
+	}
+	for _, k := range r.Keys {
+		lfu := "-"
+		if k.LFUFreq >= 0 {
+			lfu = fmt.Sprint(k.LFUFreq)
+		}
+		event := "-"
+		if k.Event != "" {
+			event = k.Event
+		}
+		fmt.Printf("│  %-36s %9.1f %5.1f%% %5s %6d  %-22s %s\n", k.Key, k.Rate, k.Share, lfu, k.Slot, k.Node, event)
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	if len(r.Slots) > 0 {
+		fmt.Println("\n┌── HOT SLOTS ──────────────────────────────────────────────────────┐")
+		for _, s := range r.Slots {
+			bar := strings.Repeat("█", int(s.Share/5)) + strings.Repeat("░", 20-int(s.Share/5))
+			fmt.Printf("│  Slot %5d: %9.1f ops/s %5.1f%% %s  %3d keys  %s\n", s.Slot, s.Rate, s.Share, bar, s.Keys, s.Node)
+		}
+		fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+	}
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/service"
+)
+
+// MemoryReport samples key sizes on every master and reports memory by key
+// prefix, slot, hash tag and node, with the largest keys and warnings for
+// hash tags that pile up on one master
+func MemoryReport(args []string) error {
+	fs := flag.NewFlagSet("memory-report", flag.ExitOnError)
+	pattern := fs.String("pattern", "*", "Key pattern to sample")
+	keysPerNode := fs.Int("keys-per-node", 10000, "Keys sampled per master (0 = all)")
+	samples := fs.Int("samples", 0, "MEMORY USAGE SAMPLES for nested values (0 = server default)")
+	top := fs.Int("top", 20, "Largest keys, slots and hash tags to list")
+	tagShare := fs.Float64("tag-share", 25, "Warn when one hash tag holds more than this percent of a master's sampled memory")
+	tagMin := fs.Int64("tag-min-bytes", 1<<20, "Ignore hash tags smaller than this for the concentration warning")
+	bigKey := fs.Int64("big-key", 10<<20, "Warn about keys larger than this many bytes (0 = off)")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var classes []cluster.KeyClass
+	for _, p := range service.KeyPatterns() {
+		classes = append(classes, cluster.KeyClass{Name: p.Name, Pattern: p.Glob()})
+	}
+
+	report, err := client.MemoryReport(client.Context(), cluster.MemoryReportOptions{
+		Match:       *pattern,
+		KeysPerNode: *keysPerNode,
+		Samples:     *samples,
+		Top:         *top,
+		Classes:     classes,
+		TagShare:    *tagShare,
+		TagMinBytes: *tagMin,
+		BigKey:      *bigKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printMemoryReport(report, classes)
+	return nil
+}
+
+func printMemoryReport(r *cluster.MemoryReport, classes []cluster.KeyClass) {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                        MEMORY REPORT                             ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Pattern: %s   Sampled: %d keys, %s\n", r.Match, r.Sampled, formatBytes(r.Bytes))
+
+	if r.Sampled == 0 {
+		fmt.Println("\nNo keys found matching pattern.")
+		return
+	}
+
+	fmt.Println("\n┌── BY NODE ────────────────────────────────────────────────────────┐")
+	fmt.Printf("│  %-22s %10s %10s %10s %10s %10s\n", "NODE", "KEYS", "SAMPLED", "BYTES", "ESTIMATED", "USED")
+	for _, n := range r.Nodes {
+		fmt.Printf("│  %-22s %10d %10d %10s %10s %10s\n",
+			n.Addr, n.Keys, n.Sampled, formatBytes(n.Bytes), formatBytes(n.Estimated), formatBytes(n.UsedMemory))
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	patterns := make(map[string]string, len(classes))
+	for _, c := range classes {
+		patterns[c.Name] = c.Pattern
+	}
+	fmt.Println("\n┌── BY KEY PREFIX ──────────────────────────────────────────────────┐")
+	fmt.Printf("│  %-28s %8s %10s %6s %10s %10s  %s\n", "PREFIX", "KEYS", "BYTES", "SHARE", "AVG", "ELEMENTS", "ENCODINGS")
+	for _, g := range r.Classes {
+		name := g.Name
+		if p, ok := patterns[name]; ok {
+			name = p
+		}
+		pct := float64(g.Bytes) / float64(r.Bytes) * 100
+		fmt.Printf("│  %-28s %8d %10s %5.1f%% %10s %10d  %s\n",
+			name, g.Keys, formatBytes(g.Bytes), pct, formatBytes(g.Bytes/g.Keys), g.Elements, formatEncodings(g.Encodings))
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	fmt.Println("\n┌── LARGEST KEYS ───────────────────────────────────────────────────┐")
+	fmt.Printf("│  %-40s %-6s %-10s %10s %10s  %s\n", "KEY", "TYPE", "ENCODING", "BYTES", "ELEMENTS", "NODE")
+	for _, k := range r.TopKeys {
+		fmt.Printf("│  %-40s %-6s %-10s %10s %10d  %s\n", k.Key, k.Type, k.Encoding, formatBytes(k.Bytes), k.Elements, k.Node)
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	fmt.Println("\n┌── HEAVIEST HASH TAGS ─────────────────────────────────────────────┐")
+	for _, t := range r.Tags {
+		bar := strings.Repeat("█", int(t.Share/5)) + strings.Repeat("░", 20-int(t.Share/5))
+		fmt.Printf("│  %-30s %6d keys %10s %5.1f%% %s  %s\n", "{"+t.Tag+"}", t.Keys, formatBytes(t.Bytes), t.Share, bar, t.Node)
+	}
+	if len(r.Tags) == 0 {
+		fmt.Println("│  No hash-tagged keys sampled")
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	fmt.Println("\n┌── HEAVIEST SLOTS ─────────────────────────────────────────────────┐")
+	for _, s := range r.Slots {
+		fmt.Printf("│  Slot %5d: %6d keys %10s  %s\n", s.Slot, s.Keys, formatBytes(s.Bytes), s.Node)
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	if len(r.Warnings) > 0 {
+		fmt.Println("\n⚠ Warnings:")
+		for _, w := range r.Warnings {
+			fmt.Printf("  - %s\n", w)
+		}
+	}
+}
+
+// formatEncodings renders "listpack:12 hashtable:1", most used first
+func formatEncodings(counts map[string]int64) string {
+	encodings := make([]string, 0, len(counts))
+	for e := range counts {
+		encodings = append(encodings, e)
+	}
+	sort.Slice(encodings, func(i, j int) bool {
+		a, b := encodings[i], encodings[j]
+		return counts[a] > counts[b] || counts[a] == counts[b] && a < b
+	})
+	parts := make([]string, len(encodings))
+	for i, e := range encodings {
+		parts[i] = fmt.Sprintf("%s:%d", e, counts[e])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// MigrateData copies every key from a source cluster to a target cluster,
+// keeps the target in sync from keyspace notifications until Ctrl+C (cutover)
+// and then verifies key counts and sampled values
+func MigrateData(args []string) error {
+	fs := flag.NewFlagSet("migrate-data", flag.ExitOnError)
+	sourceAddrs := fs.String("source", "", "Source cluster seed addresses (required)")
+	targetAddrs := fs.String("target", "", "Target cluster seed addresses (required)")
+	sourceRemap := fs.String("source-remap", "", "Remap rules for the source (default: global --remap)")
+	targetRemap := fs.String("target-remap", "", "Remap rules for the target (default: global --remap)")
+	pattern := fs.String("pattern", "", "Only migrate keys matching this glob pattern")
+	batch := fs.Int("batch", 500, "Keys per SCAN page / pipeline")
+	syncInterval := fs.Duration("sync-interval", 100*time.Millisecond, "How often changed keys are copied")
+	noSync := fs.Bool("no-sync", false, "Copy and verify once, without live sync")
+	samples := fs.Int("samples", 100, "Keys whose values are compared during verification")
+	yes := fs.Bool("yes", false, "Do not ask before overwriting keys on the target")
+	fs.Parse(args)
+
+	if *sourceAddrs == "" || *targetAddrs == "" {
+		return fmt.Errorf("--source and --target are required")
+	}
+	sourceCfg, err := clusterConfig.WithAddrs(*sourceAddrs, *sourceRemap)
+	if err != nil {
+		return fmt.Errorf("--source: %w", err)
+	}
+	targetCfg, err := clusterConfig.WithAddrs(*targetAddrs, *targetRemap)
+	if err != nil {
+		return fmt.Errorf("--target: %w", err)
+	}
+
+	source, err := cluster.NewClient(sourceCfg)
+	if err != nil {
+		return fmt.Errorf("source: %w", err)
+	}
+	defer source.Close()
+	target, err := cluster.NewClient(targetCfg)
+	if err != nil {
+		return fmt.Errorf("target: %w", err)
+	}
+	defer target.Close()
+
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                    CLUSTER DATA MIGRATION                        ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Source:  %s\n", *sourceAddrs)
+	fmt.Printf("  Target:  %s\n", *targetAddrs)
+	if *pattern != "" {
+		fmt.Printf("  Match:   %s\n", *pattern)
+	}
+	fmt.Println()
+
+	if !*yes && !confirm("Keys on the target with the same names will be overwritten. Continue?") {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	m := cluster.NewDataMigration(source, target, cluster.DataMigrationOptions{
+		Match:        *pattern,
+		BatchSize:    *batch,
+		SyncInterval: *syncInterval,
+	})
+
+	ctx, stop := interruptContext("Cutover requested, copying the remaining changes...")
+	defer stop()
+
+	// Capture changes before copying so writes made during the copy are replayed
+	if !*noSync {
+		if err := m.StartCapture(ctx); err != nil {
+			return err
+		}
+		defer m.Stop()
+	}
+
+	fmt.Println("Phase 1: initial copy")
+	start := time.Now()
+	err = m.Copy(ctx, func(p cluster.CopyProgress) {
+		fmt.Printf("\r  %-22s %10d keys copied", p.Node, p.Copied)
+	})
+	fmt.Println()
+	if err == context.Canceled {
+		fmt.Printf("Stopped during the initial copy after %d keys; the target is incomplete.\n", m.Stats().Copied)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  Copied %d keys in %v\n", m.Stats().Copied, time.Since(start).Round(time.Millisecond))
+
+	if !*noSync {
+		fmt.Println("\nPhase 2: live sync from keyspace notifications")
+		fmt.Println("  Stop writes to the source, then press Ctrl+C to cut over.")
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					s := m.Stats()
+					fmt.Printf("\r  events %8d  synced %8d  deleted %6d  pending %6d", s.Events, s.Synced, s.Deleted, s.Pending)
+				}
+			}
+		}()
+		if err := m.Sync(ctx); err != nil {
+			return err
+		}
+		fmt.Println()
+
+		// Drain what is pending, stop capturing, then drain in-flight events
+		flushCtx := context.Background()
+		if _, err := m.Flush(flushCtx); err != nil {
+			return err
+		}
+		m.Stop()
+		if _, err := m.Flush(flushCtx); err != nil {
+			return err
+		}
+		s := m.Stats()
+		fmt.Printf("  Synced %d changed keys, deleted %d (from %d notifications)\n", s.Synced, s.Deleted, s.Events)
+	}
+
+	fmt.Println("\nVerification")
+	v, err := m.Verify(context.Background(), *samples)
+	if err != nil {
+		return err
+	}
+	countStatus := "✓"
+	if v.SourceKeys != v.TargetKeys {
+		countStatus = "✗"
+	}
+	fmt.Printf("  %s key count: source %d, target %d\n", countStatus, v.SourceKeys, v.TargetKeys)
+	sampleStatus := "✓"
+	if len(v.Mismatches) > 0 {
+		sampleStatus = "✗"
+	}
+	fmt.Printf("  %s %d/%d sampled values identical (%s)\n", sampleStatus, v.Sampled-len(v.Mismatches), v.Sampled, v.Method)
+	for i, key := range v.Mismatches {
+		if i == 10 {
+			fmt.Printf("      ... and %d more\n", len(v.Mismatches)-i)
+			break
+		}
+		fmt.Printf("      - %s\n", key)
+	}
+
+	if !v.OK() {
+		return fmt.Errorf("migrate-data: verification failed")
+	}
+	fmt.Println("\nMigration verified. Point clients at the target cluster.")
+	return nil
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// Node dispatches the node add / add-replica / remove / replicate subcommands
+func Node(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: node <add|add-replica|remove|replicate> ...")
+	}
+
+	switch args[0] {
+	case "add":
+		return nodeAdd(args[1:])
+	case "add-replica":
+		return nodeAddReplica(args[1:])
+	case "remove":
+		return nodeRemove(args[1:])
+	case "replicate":
+		return nodeReplicate(args[1:])
+	}
+	return fmt.Errorf("unknown node subcommand %q (use add, add-replica, remove or replicate)", args[0])
+}
+
+// nodeAdd joins an empty node as a master, optionally rebalancing slots onto it
+func nodeAdd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("address of the new node required (e.g. 172.30.0.17:7007)")
+	}
+
+	fs := flag.NewFlagSet("node add", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for gossip convergence")
+	rebalance := fs.Bool("rebalance", false, "Move an equal share of slots to the new master")
+	fs.Parse(args[1:])
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	ctx := client.Context()
+
+	printNodeOpHeader("ADD MASTER NODE")
+	node, err := client.AddNode(ctx, args[0], nodeOpOptions(*timeout))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n✓ %s joined as master %s\n", node.Address, node.ID)
+
+	if !*rebalance {
+		fmt.Println("\nThe new master has no slots yet. Give it some with:")
+		fmt.Println("  ticket-reservation rebalance --execute")
+		return nil
+	}
+
+	plan, err := client.PlanRebalance(ctx, cluster.RebalanceOptions{Mode: cluster.RebalanceBySlots, Threshold: 2})
+	if err != nil {
+		return err
+	}
+	printRebalancePlan(plan)
+	for _, rp := range plan.ReshardPlans(cluster.MigrateOptions{}) {
+		fmt.Printf("\n→ Moving %d slots %s -> %s\n", len(rp.Slots), cluster.ShortID(rp.SourceID), cluster.ShortID(rp.TargetID))
+		if err := client.ExecuteReshard(ctx, rp, "", nil); err != nil {
+			return err
+		}
+	}
+	if err := client.VerifyHealth(); err != nil {
+		return err
+	}
+	fmt.Println("\n✓ Rebalanced, cluster healthy")
+	return nil
+}
+
+// nodeAddReplica joins an empty node and attaches it to a master
+func nodeAddReplica(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("address of the new node required (e.g. 172.30.0.18:7008)")
+	}
+
+	fs := flag.NewFlagSet("node add-replica", flag.ExitOnError)
+	master := fs.String("master", "", "Master to replicate: node ID, ID prefix or address (required)")
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for gossip convergence")
+	fs.Parse(args[1:])
+
+	if *master == "" {
+		return fmt.Errorf("--master is required")
+	}
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	printNodeOpHeader("ADD REPLICA NODE")
+	node, err := client.AddReplica(client.Context(), args[0], *master, nodeOpOptions(*timeout))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n✓ %s (%s) now replicates %s\n", node.Address, cluster.ShortID(node.ID), cluster.ShortID(node.MasterID))
+	return nil
+}
+
+// nodeRemove drains (if needed) and removes a node from the cluster
+func nodeRemove(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("node to remove required (node ID, ID prefix or address)")
+	}
+
+	fs := flag.NewFlagSet("node remove", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "Do not ask for confirmation")
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for gossip convergence")
+	batch := fs.Int("batch", 100, "Keys per MIGRATE batch while draining")
+	fs.Parse(args[1:])
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	node, err := client.ResolveNode(args[0])
+	if err != nil {
+		return err
+	}
+
+	printNodeOpHeader("REMOVE NODE")
+	fmt.Printf("  Node:  %s (%s)\n", node.Address, node.ID)
+	fmt.Printf("  Role:  %s\n", node.Role)
+	if node.Role == "master" {
+		fmt.Printf("  Slots: %d (will be moved to the other masters first)\n", node.SlotCount())
+	}
+	if !*yes && !confirm("Remove this node from the cluster?") {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	opts := nodeOpOptions(*timeout)
+	opts.Migrate = cluster.MigrateOptions{BatchSize: *batch}
+	if err := client.RemoveNode(client.Context(), node.ID, opts); err != nil {
+		return err
+	}
+	fmt.Printf("\n✓ %s removed. Stop its container/process; it no longer belongs to the cluster.\n", node.Address)
+	return nil
+}
+
+// nodeReplicate makes an existing node a replica of a master
+func nodeReplicate(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("node required (node ID, ID prefix or address)")
+	}
+
+	fs := flag.NewFlagSet("node replicate", flag.ExitOnError)
+	master := fs.String("master", "", "Master to replicate: node ID, ID prefix or address (required)")
+	timeout := fs.Duration("timeout", 30*time.Second, "How long to wait for gossip convergence")
+	fs.Parse(args[1:])
+
+	if *master == "" {
+		return fmt.Errorf("--master is required")
+	}
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	printNodeOpHeader("SET REPLICA")
+	node, err := client.Replicate(client.Context(), args[0], *master, nodeOpOptions(*timeout))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n✓ %s (%s) now replicates %s\n", node.Address, cluster.ShortID(node.ID), cluster.ShortID(node.MasterID))
+	return nil
+}
+
+// nodeOpOptions prints each step with a timestamp
+func nodeOpOptions(timeout time.Duration) cluster.NodeOpOptions {
+	return cluster.NodeOpOptions{
+		Timeout: timeout,
+		Progress: func(step string) {
+			fmt.Printf("  [%s] %s\n", time.Now().Format("15:04:05"), step)
+		},
+	}
+}
+
+func printNodeOpHeader(title string) {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Printf("║  %-64s║\n", title)
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// Rebalance plans (and optionally runs) slot moves that even out masters by
+// slot count, key count or memory, honouring per-node weights
+func Rebalance(args []string) error {
+	fs := flag.NewFlagSet("rebalance", flag.ExitOnError)
+	by := fs.String("by", "slots", "What to balance: slots, keys or memory")
+	weightsFlag := fs.String("weights", "", "Per-node weights, e.g. <node>=2,<node>=0 (0 drains a node)")
+	threshold := fs.Float64("threshold", 2, "Tolerated deviation from the target in percent")
+	samples := fs.Int("samples", 5, "Keys sampled per slot with MEMORY USAGE (--by memory)")
+	jsonOut := fs.Bool("json", false, "Print the plan as JSON")
+	execute := fs.Bool("execute", false, "Run the plan after printing it")
+	batch := fs.Int("batch", 100, "Keys per MIGRATE batch")
+	timeout := fs.Duration("timeout", 5*time.Second, "MIGRATE timeout")
+	fs.Parse(args)
+
+	mode, err := cluster.ParseRebalanceMode(*by)
+	if err != nil {
+		return err
+	}
+	weights, err := parseWeights(*weightsFlag)
+	if err != nil {
+		return err
+	}
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	plan, err := client.PlanRebalance(client.Context(), cluster.RebalanceOptions{
+		Mode:      mode,
+		Weights:   weights,
+		Threshold: *threshold,
+		Samples:   *samples,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(plan); err != nil {
+			return err
+		}
+	} else {
+		printRebalancePlan(plan)
+	}
+
+	if !*execute || len(plan.Moves) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		<-sigChan
+		fmt.Fprintln(os.Stderr, "\nInterrupt received, finishing current slot...")
+		cancel()
+	}()
+
+	// Progress goes to stderr so --json output stays parseable
+	start := time.Now()
+	groups := plan.ReshardPlans(cluster.MigrateOptions{BatchSize: *batch, Timeout: *timeout})
+	for i, rp := range groups {
+		fmt.Fprintf(os.Stderr, "\n[%d/%d] %s -> %s: %d slots\n", i+1, len(groups),
+			cluster.ShortID(rp.SourceID), cluster.ShortID(rp.TargetID), len(rp.Slots))
+		err := client.ExecuteReshard(ctx, rp, "", func(p cluster.SlotProgress) {
+			fmt.Fprintf(os.Stderr, "  [%5d/%d] slot %5d  %6d keys (%v)\n", p.Index, p.Total, p.Slot, p.KeysMoved, p.Elapsed.Round(time.Millisecond))
+		})
+		if err == context.Canceled {
+			fmt.Fprintln(os.Stderr, "Stopped. Run rebalance again to plan the remaining moves from the current layout.")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRebalance complete: %d slots moved in %v\n", len(plan.Moves), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// parseWeights parses "<node>=<weight>,..." into a map
+func parseWeights(s string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	if s == "" {
+		return weights, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		ref, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || ref == "" {
+			return nil, fmt.Errorf("invalid weight %q (want <node>=<weight>)", part)
+		}
+		w, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight %q: %w", part, err)
+		}
+		weights[ref] = w
+	}
+	return weights, nil
+}
+
+// printRebalancePlan prints per-node loads and the moves grouped by source/target
+func printRebalancePlan(plan *cluster.RebalancePlan) {
+	unit := map[cluster.RebalanceMode]string{
+		cluster.RebalanceBySlots:  "slots",
+		cluster.RebalanceByKeys:   "keys",
+		cluster.RebalanceByMemory: "bytes",
+	}[plan.Mode]
+
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                       REBALANCE PLAN                             ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Balance by: %s (threshold %.1f%%)\n", plan.Mode, plan.Threshold)
+
+	fmt.Println("\n┌── MASTERS ────────────────────────────────────────────────────────┐")
+	fmt.Printf("│  %-8s %-20s %6s %6s %10s %12s %12s %12s\n", "NODE", "ADDRESS", "WEIGHT", "SLOTS", "KEYS", "LOAD", "TARGET", "AFTER")
+	for _, n := range plan.Nodes {
+		fmt.Printf("│  %-8s %-20s %6.2f %6d %10d %12.0f %12.0f %12.0f\n",
+			cluster.ShortID(n.ID), n.Address, n.Weight, n.Slots, n.Keys, n.Load, n.Target, n.After)
+	}
+	fmt.Printf("│  (load, target and after are in %s)\n", unit)
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+
+	if len(plan.Moves) == 0 {
+		fmt.Println("\nCluster is balanced within the threshold. Nothing to move.")
+		return
+	}
+
+	var keys int64
+	for _, m := range plan.Moves {
+		keys += m.Keys
+	}
+
+	fmt.Println("\n┌── MOVES ──────────────────────────────────────────────────────────┐")
+	for _, rp := range plan.ReshardPlans(cluster.MigrateOptions{}) {
+		var groupKeys int64
+		for _, m := range plan.Moves {
+			if m.SourceID == rp.SourceID && m.TargetID == rp.TargetID {
+				groupKeys += m.Keys
+			}
+		}
+		fmt.Printf("│  %s -> %s  %5d slots %8d keys  %s\n", cluster.ShortID(rp.SourceID), cluster.ShortID(rp.TargetID),
+			len(rp.Slots), groupKeys, formatRanges(rp.SlotRanges()))
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+	fmt.Printf("\nTotal: %d slot moves, %d keys\n", len(plan.Moves), keys)
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// RedirectTrace runs a read/write workload and reports the MOVED and ASK
+// redirects the cluster client followed. With --from/--to/--slots it also
+// reshards while the workload runs, so the redirects can be watched live.
+func RedirectTrace(args []string) error {
+	fs := flag.NewFlagSet("redirect-trace", flag.ExitOnError)
+	duration := fs.Duration("duration", 30*time.Second, "How long to run the workload")
+	workers := fs.Int("workers", 4, "Concurrent workload goroutines")
+	numKeys := fs.Int("keys", 200, "Number of workload keys")
+	from := fs.String("from", "", "Reshard from this master while tracing (optional)")
+	to := fs.String("to", "", "Reshard to this master while tracing")
+	count := fs.Int("slots", 0, "Number of slots to reshard while tracing")
+	batch := fs.Int("batch", 10, "Keys per MIGRATE batch (small batches keep slots migrating longer)")
+	logEach := fs.Bool("log", false, "Log every redirect as it happens")
+	jsonOut := fs.Bool("json", false, "Print the statistics as JSON")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var plan *cluster.ReshardPlan
+	if *from != "" || *to != "" || *count > 0 {
+		if *from == "" || *to == "" || *count <= 0 {
+			return fmt.Errorf("--from, --to and --slots must be given together")
+		}
+		plan, err = client.NewReshardPlan(*from, *to, *count)
+		if err != nil {
+			return err
+		}
+		plan.BatchSize = *batch
+	}
+
+	// Put the workload on the slots being moved, otherwise spread it out
+	keys := traceKeys(plan, *numKeys)
+	if len(keys) == 0 {
+		return fmt.Errorf("could not generate workload keys")
+	}
+
+	rdb := client.Redis()
+	ctx := client.Context()
+	for _, key := range keys {
+		if err := rdb.Set(ctx, key, 0, 0).Err(); err != nil {
+			return fmt.Errorf("seed %s: %w", key, err)
+		}
+	}
+	defer func() {
+		for _, key := range keys {
+			rdb.Del(context.Background(), key)
+		}
+	}()
+
+	tracer := client.Redirects()
+	tracer.Reset()
+	tracer.SetLogging(*logEach)
+
+	if !*jsonOut {
+		fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+		fmt.Println("║                      REDIRECT TRACE                              ║")
+		fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+		fmt.Printf("  Workload:  %d workers, %d keys, GET+INCR for %v\n", *workers, len(keys), *duration)
+		if plan != nil {
+			fmt.Printf("  Reshard:   %d slots %s -> %s (batch %d)\n", len(plan.Slots),
+				cluster.ShortID(plan.SourceID), cluster.ShortID(plan.TargetID), plan.BatchSize)
+		}
+		fmt.Println()
+	}
+
+	runCtx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	var ops, opErrors atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < *workers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for runCtx.Err() == nil {
+				key := keys[rng.Intn(len(keys))]
+				if err := rdb.Get(runCtx, key).Err(); err != nil && runCtx.Err() == nil {
+					opErrors.Add(1)
+				}
+				if err := rdb.Incr(runCtx, key).Err(); err != nil && runCtx.Err() == nil {
+					opErrors.Add(1)
+				}
+				ops.Add(2)
+			}
+		}(time.Now().UnixNano() + int64(w))
+	}
+
+	var reshardErr error
+	reshardDone := make(chan struct{})
+	if plan != nil {
+		go func() {
+			defer close(reshardDone)
+			// Let the workload warm up so the redirects stand out
+			select {
+			case <-time.After(time.Second):
+			case <-runCtx.Done():
+				return
+			}
+			reshardErr = client.ExecuteReshard(runCtx, plan, "", func(p cluster.SlotProgress) {
+				if !*jsonOut {
+					fmt.Printf("  [%5d/%d] moved slot %5d (%d keys)\n", p.Index, p.Total, p.Slot, p.KeysMoved)
+				}
+			})
+		}()
+	} else {
+		close(reshardDone)
+	}
+
+	wg.Wait()
+	<-reshardDone
+	stats := tracer.Snapshot()
+
+	if *jsonOut {
+		out := struct {
+			Ops      int64                 `json:"ops"`
+			Errors   int64                 `json:"errors"`
+			Pending  int                   `json:"reshard_pending,omitempty"`
+			Redirect cluster.RedirectStats `json:"redirects"`
+		}{Ops: ops.Load(), Errors: opErrors.Load(), Redirect: stats}
+		if plan != nil {
+			out.Pending = len(plan.Pending())
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	}
+
+	fmt.Printf("\n  Operations: %d (%d failed)\n", ops.Load(), opErrors.Load())
+	if plan != nil {
+		if pending := len(plan.Pending()); pending > 0 {
+			fmt.Printf("  Reshard stopped at the end of the trace with %d slots pending\n", pending)
+		} else {
+			fmt.Println("  Reshard complete")
+		}
+		if reshardErr != nil && reshardErr != context.DeadlineExceeded {
+			fmt.Printf("  Reshard error: %v\n", reshardErr)
+		}
+	}
+	printRedirectStats(stats)
+	return nil
+}
+
+// traceKeys returns n keys, restricted to the plan's slots when there is one
+func traceKeys(plan *cluster.ReshardPlan, n int) []string {
+	var inPlan map[int]bool
+	if plan != nil {
+		inPlan = make(map[int]bool, len(plan.Slots))
+		for _, s := range plan.Slots {
+			inPlan[s] = true
+		}
+	}
+	var keys []string
+	for i := 0; len(keys) < n && i < 10000000; i++ {
+		key := fmt.Sprintf("trace:redirect:%d", i)
+		if inPlan == nil || inPlan[cluster.KeySlot(key)] {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// printRedirectStats prints redirect totals and breakdowns
+func printRedirectStats(s cluster.RedirectStats) {
+	fmt.Println("\n┌── REDIRECTS ──────────────────────────────────────────────────────┐")
+	fmt.Printf("│  Commands sent to nodes: %d\n", s.Commands)
+	fmt.Printf("│  MOVED: %d   ASK: %d\n", s.Total.Moved, s.Total.Ask)
+	if s.Total.Total() == 0 {
+		fmt.Println("│  No redirects observed")
+		fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+		return
+	}
+
+	fmt.Println("│")
+	fmt.Println("│  By node:")
+	nodes := make([]string, 0, len(s.ByNode))
+	for n := range s.ByNode {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	for _, n := range nodes {
+		fmt.Printf("│    %-22s MOVED %6d   ASK %6d\n", n, s.ByNode[n].Moved, s.ByNode[n].Ask)
+	}
+
+	fmt.Println("│")
+	fmt.Printf("│  Top slots (%d slots redirected):\n", len(s.BySlot))
+	for _, slot := range s.TopSlots(10) {
+		fmt.Printf("│    slot %-17d MOVED %6d   ASK %6d\n", slot, s.BySlot[slot].Moved, s.BySlot[slot].Ask)
+	}
+
+	fmt.Println("│")
+	fmt.Println("│  By command:")
+	cmds := make([]string, 0, len(s.ByCommand))
+	for c := range s.ByCommand {
+		cmds = append(cmds, c)
+	}
+	sort.Strings(cmds)
+	for _, c := range cmds {
+		fmt.Printf("│    %-22s MOVED %6d   ASK %6d\n", c, s.ByCommand[c].Moved, s.ByCommand[c].Ask)
+	}
+
+	if len(s.Recent) > 0 {
+		fmt.Println("│")
+		fmt.Println("│  Most recent:")
+		recent := s.Recent
+		if len(recent) > 5 {
+			recent = recent[len(recent)-5:]
+		}
+		for _, ev := range recent {
+			fmt.Printf("│    %s %-5s slot %5d %-6s %s -> %s\n",
+				ev.Time.Format("15:04:05.000"), ev.Kind, ev.Slot, ev.Command, ev.Node, ev.Target)
+		}
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+}
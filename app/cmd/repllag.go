@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// replLagFlags are the threshold and alert flags shared by repl-lag and server
+type replLagFlags struct {
+	thresholds cluster.ReplLagThresholds
+	alerts     *string
+	webhook    *string
+}
+
+func registerReplLagFlags(fs *flag.FlagSet, defaultAlerts string) *replLagFlags {
+	f := &replLagFlags{}
+	fs.Int64Var(&f.thresholds.MaxBytes, "lag-max-bytes", 1<<20, "Replica offset lag that counts as lagging (0 = off)")
+	fs.DurationVar(&f.thresholds.MaxDelay, "lag-max-delay", 10*time.Second, "Time since last replica ack that counts as lagging (0 = off)")
+	f.alerts = fs.String("lag-alerts", defaultAlerts, "Comma-separated alert sinks: log, json, webhook")
+	f.webhook = fs.String("lag-webhook", "", "URL the webhook sink POSTs alerts to")
+	return f
+}
+
+// sinks builds the alert sinks named by --lag-alerts
+func (f *replLagFlags) sinks() ([]cluster.AlertSink, error) {
+	var sinks []cluster.AlertSink
+	for _, name := range strings.Split(*f.alerts, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "log":
+			sinks = append(sinks, cluster.LogSink{})
+		case "json":
+			sinks = append(sinks, cluster.NewJSONSink(os.Stdout))
+		case "webhook":
+			if *f.webhook == "" {
+				return nil, fmt.Errorf("--lag-alerts webhook requires --lag-webhook")
+			}
+			sinks = append(sinks, cluster.NewWebhookSink(*f.webhook))
+		default:
+			return nil, fmt.Errorf("unknown alert sink %q", name)
+		}
+	}
+	return sinks, nil
+}
+
+// ReplLag compares each replica's offset and link status with its master.
+// Without --watch it prints one report and fails when a replica is lagging;
+// with --watch it keeps checking and sends alerts to the configured sinks.
+func ReplLag(args []string) error {
+	fs := flag.NewFlagSet("repl-lag", flag.ExitOnError)
+	lagFlags := registerReplLagFlags(fs, "log")
+	watch := fs.Bool("watch", false, "Keep monitoring and alert on changes until Ctrl+C")
+	interval := fs.Duration("interval", 5*time.Second, "Check interval with --watch")
+	jsonOut := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if !*watch {
+		report, err := client.CheckReplLag(client.Context(), lagFlags.thresholds)
+		if err != nil {
+			return err
+		}
+		if *jsonOut {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printReplLagReport(report, lagFlags.thresholds)
+		}
+		if n := len(report.Lagging()); n > 0 {
+			return fmt.Errorf("repl-lag: %d replicas lagging", n)
+		}
+		return nil
+	}
+
+	sinks, err := lagFlags.sinks()
+	if err != nil {
+		return err
+	}
+	monitor := cluster.NewReplLagMonitor(client, *interval, lagFlags.thresholds, sinks...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Monitoring replication lag every %v (Ctrl+C to stop)...\n", *interval)
+	monitor.Start()
+	<-ctx.Done()
+	monitor.Stop()
+	return nil
+}
+
+// printReplLagReport prints one line per replica
+func printReplLagReport(report *cluster.ReplLagReport, t cluster.ReplLagThresholds) {
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                      REPLICATION LAG                             ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Thresholds: %d bytes, %v since last ack\n\n", t.MaxBytes, t.MaxDelay)
+
+	if len(report.Replicas) == 0 {
+		fmt.Println("  No replicas found")
+		return
+	}
+
+	fmt.Printf("  %-22s %-22s %-5s %12s %8s  %s\n", "REPLICA", "MASTER", "LINK", "LAG", "LAST ACK", "STATUS")
+	for _, rl := range report.Replicas {
+		link := "down"
+		if rl.LinkUp {
+			link = "up"
+		}
+		lag := "?"
+		if rl.LagBytes >= 0 {
+			lag = formatBytes(rl.LagBytes)
+		}
+		status := "✓ ok"
+		if rl.Breach != "" {
+			status = "✗ " + rl.Breach
+		}
+		fmt.Printf("  %-22s %-22s %-5s %12s %7ds  %s\n", rl.Replica, rl.Master, link, lag, rl.LagSeconds, status)
+	}
+
+	fmt.Printf("\n  Summary: %d replicas, %d lagging\n", len(report.Replicas), len(report.Lagging()))
+}
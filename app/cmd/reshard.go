@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/models"
+)
+
+// Reshard moves slots between two masters using the native resharding engine
+func Reshard(args []string) error {
+	fs := flag.NewFlagSet("reshard", flag.ExitOnError)
+	from := fs.String("from", "", "Source master (node ID, ID prefix or address)")
+	to := fs.String("to", "", "Target master (node ID, ID prefix or address)")
+	count := fs.Int("slots", 0, "Number of slots to move")
+	dryRun := fs.Bool("dry-run", false, "Show the plan and key counts without moving anything")
+	planPath := fs.String("plan", "reshard-plan.json", "Plan file used to persist progress")
+	resume := fs.Bool("resume", false, "Resume the plan in --plan after an interruption")
+	batch := fs.Int("batch", 100, "Keys per MIGRATE batch")
+	timeout := fs.Duration("timeout", 5*time.Second, "MIGRATE timeout")
+	replace := fs.Bool("replace", false, "Overwrite keys that already exist on the target")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var plan *cluster.ReshardPlan
+	if *resume {
+		plan, err = cluster.LoadReshardPlan(*planPath)
+		if err != nil {
+			return err
+		}
+	} else {
+		if *from == "" || *to == "" || *count <= 0 {
+			return fmt.Errorf("--from, --to and --slots are required (or --resume)")
+		}
+		if _, err := os.Stat(*planPath); err == nil && !*dryRun {
+			return fmt.Errorf("plan file %s already exists: use --resume to continue it or delete it", *planPath)
+		}
+		plan, err = client.NewReshardPlan(*from, *to, *count)
+		if err != nil {
+			return err
+		}
+		plan.BatchSize = *batch
+		plan.Timeout = cluster.Duration(*timeout)
+		plan.Replace = *replace
+	}
+
+	source, _ := client.ResolveNode(plan.SourceID)
+	target, _ := client.ResolveNode(plan.TargetID)
+	pending := plan.Pending()
+
+	fmt.Println("\n╔══════════════════════════════════════════════════════════════════╗")
+	fmt.Println("║                       SLOT RESHARDING                            ║")
+	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
+	fmt.Printf("  Source:  %s (%s)\n", source.Address, cluster.ShortID(plan.SourceID))
+	fmt.Printf("  Target:  %s (%s)\n", target.Address, cluster.ShortID(plan.TargetID))
+	fmt.Printf("  Slots:   %d planned, %d done, %d pending\n", len(plan.Slots), len(plan.Done), len(pending))
+	fmt.Printf("  Ranges:  %s\n", formatRanges(cluster.CompactSlots(pending)))
+
+	if *dryRun {
+		ctx := client.Context()
+		var total int64
+		fmt.Println("\n┌── DRY RUN: keys per slot range ───────────────────────────────────┐")
+		for _, r := range cluster.CompactSlots(pending) {
+			var keys int64
+			for s := r.Start; s <= r.End; s++ {
+				n, err := client.CountKeysInSlot(ctx, source, s)
+				if err != nil {
+					return err
+				}
+				keys += n
+			}
+			total += keys
+			fmt.Printf("│  slots %-12s %6d keys\n", r, keys)
+		}
+		fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+		fmt.Printf("\nWould move %d slots and %d keys. Nothing was changed.\n", len(pending), total)
+		return nil
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("\nNothing to do: every slot in the plan has been moved.")
+		os.Remove(*planPath)
+		return nil
+	}
+
+	if err := plan.Save(*planPath); err != nil {
+		return err
+	}
+	fmt.Printf("  Plan:    %s (resume with --resume --plan %s)\n\n", *planPath, *planPath)
+
+	// Stop between slots on Ctrl+C; the slot in progress is always finished
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+	go func() {
+		<-sigChan
+		fmt.Println("\nInterrupt received, finishing current slot...")
+		cancel()
+	}()
+
+	start := time.Now()
+	runKeys := 0
+	err = client.ExecuteReshard(ctx, plan, *planPath, func(p cluster.SlotProgress) {
+		runKeys += p.KeysMoved
+		status := fmt.Sprintf("%d keys", p.KeysMoved)
+		if p.Skipped {
+			status = "already moved"
+		}
+		fmt.Printf("  [%5d/%d] slot %5d  %-14s (%v)\n", p.Index, p.Total, p.Slot, status, p.Elapsed.Round(time.Millisecond))
+	})
+
+	fmt.Printf("\nMoved %d keys in %v (plan total: %d keys)\n", runKeys, time.Since(start).Round(time.Millisecond), plan.KeysMoved)
+	if err == context.Canceled {
+		fmt.Printf("Stopped with %d slots pending. Resume with: reshard --resume --plan %s\n", len(plan.Pending()), *planPath)
+		return nil
+	}
+	if err != nil {
+		fmt.Printf("Progress saved to %s. Fix the error and resume with --resume.\n", *planPath)
+		return err
+	}
+
+	os.Remove(*planPath)
+	fmt.Println("Resharding complete.")
+	return nil
+}
+
+// formatRanges joins slot ranges for display, eliding long lists
+func formatRanges(ranges []models.SlotRange) string {
+	if len(ranges) == 0 {
+		return "(none)"
+	}
+	out := ""
+	for i, r := range ranges {
+		if i == 8 {
+			return out + fmt.Sprintf(" ... (+%d ranges)", len(ranges)-i)
+		}
+		if i > 0 {
+			out += " "
+		}
+		out += r.String()
+	}
+	return out
+}
@@ -17,7 +17,7 @@ import (
 
 // SlotInfo displays slot distribution across the cluster
 func SlotInfo() error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -47,27 +47,9 @@ func SlotInfo() error {
 			continue
 		}
 
-		slotCount := 0
-		if node.Slots != "" {
-			ranges := strings.Fields(node.Slots)
-			for _, r := range ranges {
-				if strings.Contains(r, "-") {
-					parts := strings.Split(r, "-")
-					if len(parts) == 2 {
-						var start, end int
-						fmt.Sscanf(parts[0], "%d", &start)
-						fmt.Sscanf(parts[1], "%d", &end)
-						slotCount += end - start + 1
-					}
-				} else {
-					slotCount++
-				}
-			}
-		}
-
 		masters = append(masters, nodeSlots{
 			address: node.Address,
-			slots:   slotCount,
+			slots:   node.SlotCount(),
 			ranges:  node.Slots,
 		})
 	}
@@ -111,7 +93,7 @@ func KeySlot(args []string) error {
 		return fmt.Errorf("key required")
 	}
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -126,7 +108,7 @@ func KeySlot(args []string) error {
 		nodeAddr, _ := client.GetNodeForSlot(slot)
 
 		// Calculate hash tag if present
-		hashPart := extractHashTag(key)
+		hashPart := cluster.HashTag(key)
 
 		fmt.Printf("│  Key: %-50s 	   │\n", key)
 		if hashPart != key {
@@ -140,26 +122,9 @@ func KeySlot(args []string) error {
 	return nil
 }
 
-// extractHashTag extracts the hash tag portion from a key
-func extractHashTag(key string) string {
-	start := strings.Index(key, "{")
-	if start == -1 {
-		return key
-	}
-	end := strings.Index(key[start:], "}")
-	if end == -1 {
-		return key
-	}
-	tag := key[start+1 : start+end]
-	if tag == "" {
-		return key
-	}
-	return tag
-}
-
 // HashTagDemo demonstrates how hash tags work
 func HashTagDemo() error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -251,7 +216,7 @@ func HashTagDemo() error {
 
 // CrossSlotDemo demonstrates cross-slot operation limitations
 func CrossSlotDemo() error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -354,7 +319,7 @@ func AnalyzeDistribution(args []string) error {
 	limit := fs.Int("limit", 1000, "Maximum keys to scan")
 	fs.Parse(args)
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -484,7 +449,7 @@ func truncate(s string, maxLen int) string {
 
 // ShardingDemo runs a comprehensive sharding demonstration
 func ShardingDemo() error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -504,7 +469,7 @@ func ShardingDemo() error {
 
 	testKeys := []string{"user:1", "user:2", "user:1000", "order:1", "product:abc"}
 	for _, key := range testKeys {
-		crc := crc16Hash(key)
+		crc := cluster.CRC16(key)
 		slot := crc % 16384
 		node, _ := client.GetNodeForSlot(int(slot))
 		fmt.Printf("│  %-15s CRC16=%5d  Slot=%5d  Node=%s\n", key, crc, slot, node)
@@ -643,26 +608,9 @@ func ShardingDemo() error {
 	return nil
 }
 
-// crc16Hash calculates CRC16-CCITT hash (same algorithm Redis uses)
-// This is the XMODEM variant used by Redis
-func crc16Hash(key string) uint16 {
-	crc := uint16(0)
-	for i := 0; i < len(key); i++ {
-		crc ^= uint16(key[i]) << 8
-		for j := 0; j < 8; j++ {
-			if crc&0x8000 != 0 {
-				crc = (crc << 1) ^ 0x1021
-			} else {
-				crc <<= 1
-			}
-		}
-	}
-	return crc
-}
-
 // ReshardDemo demonstrates manual resharding
 func ReshardDemo(args []string) error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -694,14 +642,13 @@ func ReshardDemo(args []string) error {
 	fmt.Println("│  - Reads: handled by source (ASKING redirect if needed)")
 	fmt.Println("│  - Writes: new keys go to target (MOVED redirect)")
 	fmt.Println("│")
-	fmt.Println("│  The redis-cli --cluster reshard command automates this!")
+	fmt.Println("│  The reshard command runs exactly this sequence for you:")
 	fmt.Println("│")
 	fmt.Println("│  Example command:")
-	fmt.Println("│  redis-cli --cluster reshard localhost:7001 \\")
-	fmt.Println("│    --cluster-from <source-id> \\")
-	fmt.Println("│    --cluster-to <target-id> \\")
-	fmt.Println("│    --cluster-slots 1000 \\")
-	fmt.Println("│    --cluster-yes")
+	fmt.Println("│  ticket-reservation reshard \\")
+	fmt.Println("│    --from <source-id> \\")
+	fmt.Println("│    --to <target-id> \\")
+	fmt.Println("│    --slots 1000 --dry-run")
 	fmt.Println("│")
 	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
 
@@ -723,8 +670,8 @@ func ReshardDemo(args []string) error {
 	fmt.Println("│  To move 500 slots from first to second master:")
 	fmt.Println("│  make reshard-slots FROM=<id1> TO=<id2> SLOTS=500")
 	fmt.Println("│")
-	fmt.Println("│  Or use redis-cli directly:")
-	fmt.Println("│  docker exec redis-1 redis-cli --cluster reshard redis-1:7001")
+	fmt.Println("│  Or run the engine directly (resumable, with progress):")
+	fmt.Println("│  ticket-reservation reshard --from <id1> --to <id2> --slots 500")
 	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
 
 	return nil
@@ -736,7 +683,7 @@ func SimulateHotKey(args []string) error {
 	duration := fs.Int("duration", 5, "Duration in seconds")
 	fs.Parse(args)
 
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -798,6 +745,8 @@ func SimulateHotKey(args []string) error {
 	fmt.Println("│  2. Local caching: Cache in application memory")
 	fmt.Println("│  3. Key splitting: {product:popular}:shard:1, :shard:2, etc.")
 	fmt.Println("│  4. Client-side caching: Redis 6.0+ RESP3 protocol")
+	fmt.Println("│")
+	fmt.Println("│  Find the real hot keys: ticket-reservation hotkeys --load 20")
 	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
 
 	// Cleanup
@@ -808,7 +757,7 @@ func SimulateHotKey(args []string) error {
 
 // MigrationDemo shows what happens during key migration
 func MigrationDemo() error {
-	client, err := cluster.NewClient(nil)
+	client, err := cluster.NewClient(clusterConfig)
 	if err != nil {
 		return err
 	}
@@ -818,7 +767,7 @@ func MigrationDemo() error {
 	fmt.Println("║              KEY MIGRATION DURING RESHARDING                      ║")
 	fmt.Println("╚══════════════════════════════════════════════════════════════════╝")
 
-	fmt.Println(`
+	fmt.Print(`
   When slots are being migrated between nodes:
 
   ┌─────────────┐                      ┌─────────────┐
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"ticket-reservation/cluster"
+)
+
+// WatchTopology streams topology change events (failovers, slot moves, migrations)
+func WatchTopology(args []string) error {
+	fs := flag.NewFlagSet("watch-topology", flag.ExitOnError)
+	interval := fs.Duration("interval", time.Second, "Polling interval")
+	jsonOut := fs.Bool("json", false, "Emit one JSON event per line")
+	fs.Parse(args)
+
+	client, err := cluster.NewClient(clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	watcher := cluster.NewTopologyWatcher(client, *interval)
+	if err := watcher.Start(); err != nil {
+		return err
+	}
+
+	if !*jsonOut {
+		printTopologySummary(watcher.Snapshot())
+		fmt.Printf("Watching topology every %v (Ctrl+C to stop)...\n\n", *interval)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		watcher.Stop()
+	}()
+
+	enc := json.NewEncoder(os.Stdout)
+	for event := range watcher.Events() {
+		if *jsonOut {
+			enc.Encode(event)
+		} else {
+			fmt.Println(event)
+		}
+	}
+
+	return nil
+}
+
+// printTopologySummary prints the baseline the watcher diffs against
+func printTopologySummary(topo *cluster.Topology) {
+	ids := make([]string, 0, len(topo.Nodes))
+	for id := range topo.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return topo.Nodes[ids[i]].Address < topo.Nodes[ids[j]].Address
+	})
+
+	fmt.Println("\n┌── BASELINE TOPOLOGY ─────────────────────────────────────────────┐")
+	for _, id := range ids {
+		n := topo.Nodes[id]
+		state := ""
+		if n.Fail {
+			state = " FAIL"
+		} else if n.PFail {
+			state = " PFAIL"
+		}
+		if n.Role == "master" {
+			fmt.Printf("│  [MASTER]  %-20s %s  %5d slots%s\n", n.Address, cluster.ShortID(id), n.SlotCount(), state)
+		} else {
+			fmt.Printf("│  [REPLICA] %-20s %s  -> %s%s\n", n.Address, cluster.ShortID(id), cluster.ShortID(n.MasterID), state)
+		}
+	}
+	if migrations := topo.Migrations(); len(migrations) > 0 {
+		fmt.Printf("│  Open migrations: %d\n", len(migrations))
+	}
+	fmt.Println("└───────────────────────────────────────────────────────────────────┘")
+}
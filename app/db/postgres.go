@@ -370,7 +370,7 @@ func (pg *PostgresDB) GetReservation(reservationID string) (*models.Reservation,
 // GetConfirmedSeatsSince returns seats confirmed after a given time (for reconciliation)
 func (pg *PostgresDB) GetConfirmedSeatsSince(eventID string, since time.Time) ([]SeatSync, error) {
 	rows, err := pg.DB.Query(`
-		SELECT s.seat_id, s.status, r.id as reservation_id
+		SELECT s.seat_id, s.status, r.id as reservation_id, r.user_id
 		FROM seats s
 		JOIN reservation_seats rs ON rs.event_id = s.event_id AND rs.seat_id = s.seat_id
 		JOIN reservations r ON r.id = rs.reservation_id
@@ -387,7 +387,7 @@ func (pg *PostgresDB) GetConfirmedSeatsSince(eventID string, since time.Time) ([
 	var results []SeatSync
 	for rows.Next() {
 		var ss SeatSync
-		if err := rows.Scan(&ss.SeatID, &ss.Status, &ss.ReservationID); err != nil {
+		if err := rows.Scan(&ss.SeatID, &ss.Status, &ss.ReservationID, &ss.UserID); err != nil {
 			return nil, err
 		}
 		results = append(results, ss)
@@ -400,6 +400,7 @@ type SeatSync struct {
 	SeatID        string
 	Status        string
 	ReservationID string
+	UserID        string
 }
 
 // GetEventStats returns event statistics from PostgreSQL (fallback)
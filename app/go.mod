@@ -4,7 +4,9 @@ go 1.21
 
 require (
 	github.com/google/uuid v1.5.0
+	github.com/lib/pq v1.12.3
 	github.com/redis/go-redis/v9 v9.3.1
+	github.com/yuin/gopher-lua v1.1.1
 )
 
 require (
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -50,6 +51,8 @@ type Seat struct {
 	HeldAt   *time.Time `json:"held_at,omitempty"`
 	SoldTo   string     `json:"sold_to,omitempty"`
 	SoldAt   *time.Time `json:"sold_at,omitempty"`
+
+	ReservationID string `json:"reservation_id,omitempty"` // reservation holding or owning the seat
 }
 
 // Reservation represents a ticket reservation
@@ -92,23 +95,95 @@ type EventStats struct {
 	Revenue        float64 `json:"revenue"`
 }
 
-// ClusterNode represents a Redis cluster node
+// SlotRange is an inclusive range of hash slots
+type SlotRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Count returns the number of slots in the range
+func (r SlotRange) Count() int {
+	return r.End - r.Start + 1
+}
+
+// String formats the range the way CLUSTER NODES does ("0-5460" or "42")
+func (r SlotRange) String() string {
+	if r.Start == r.End {
+		return fmt.Sprintf("%d", r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// ClusterNode represents a Redis cluster node as reported by CLUSTER NODES
 type ClusterNode struct {
-	ID       string `json:"id"`
-	Address  string `json:"address"`
-	Role     string `json:"role"` // master or slave
-	MasterID string `json:"master_id,omitempty"`
-	Slots    string `json:"slots,omitempty"`
+	ID       string   `json:"id"`
+	Address  string   `json:"address"`
+	BusPort  int      `json:"bus_port,omitempty"`
+	Hostname string   `json:"hostname,omitempty"`
+	Role     string   `json:"role"` // master or replica
+	MasterID string   `json:"master_id,omitempty"`
+	Flags    []string `json:"flags"`
+
+	// Decoded flags
+	Myself     bool `json:"myself,omitempty"`
+	PFail      bool `json:"pfail,omitempty"` // fail? - unreachable from the reporting node
+	Fail       bool `json:"fail,omitempty"`  // majority of masters agree it is down
+	Handshake  bool `json:"handshake,omitempty"`
+	NoAddr     bool `json:"noaddr,omitempty"`
+	NoFailover bool `json:"nofailover,omitempty"`
+
+	PingSent    int64  `json:"ping_sent"` // unix ms of the pending PING, 0 if none
+	PongRecv    int64  `json:"pong_recv"` // unix ms of the last PONG
+	ConfigEpoch int64  `json:"config_epoch"`
+	LinkState   string `json:"link_state"` // connected or disconnected
+
+	Slots      string         `json:"slots,omitempty"` // owned ranges, e.g. "0-5460 5462"
+	SlotRanges []SlotRange    `json:"slot_ranges,omitempty"`
+	Migrating  map[int]string `json:"migrating,omitempty"` // slot -> target node ID
+	Importing  map[int]string `json:"importing,omitempty"` // slot -> source node ID
+}
+
+// SlotCount returns the number of slots the node owns
+func (n ClusterNode) SlotCount() int {
+	count := 0
+	for _, r := range n.SlotRanges {
+		count += r.Count()
+	}
+	return count
+}
+
+// OwnsSlot reports whether slot is in one of the node's ranges
+func (n ClusterNode) OwnsSlot(slot int) bool {
+	for _, r := range n.SlotRanges {
+		if slot >= r.Start && slot <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFailing reports whether the node is flagged fail, pfail or has a broken link
+func (n ClusterNode) IsFailing() bool {
+	return n.Fail || n.PFail || (n.LinkState == "disconnected" && !n.Myself)
+}
+
+// SlotMigration is a slot in flight between two masters
+type SlotMigration struct {
+	Slot     int    `json:"slot"`
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
 }
 
 // ClusterInfo provides cluster status information
 type ClusterInfo struct {
-	State         string        `json:"state"`
-	SlotsAssigned int           `json:"slots_assigned"`
-	SlotsOK       int           `json:"slots_ok"`
-	SlotsPFail    int           `json:"slots_pfail"`
-	SlotsFail     int           `json:"slots_fail"`
-	KnownNodes    int           `json:"known_nodes"`
-	Size          int           `json:"size"`
-	Nodes         []ClusterNode `json:"nodes"`
+	State         string          `json:"state"`
+	SlotsAssigned int             `json:"slots_assigned"`
+	SlotsOK       int             `json:"slots_ok"`
+	SlotsPFail    int             `json:"slots_pfail"`
+	SlotsFail     int             `json:"slots_fail"`
+	KnownNodes    int             `json:"known_nodes"`
+	Size          int             `json:"size"`
+	Nodes         []ClusterNode   `json:"nodes"`
+	FailingNodes  []ClusterNode   `json:"failing_nodes,omitempty"`
+	Migrations    []SlotMigration `json:"migrations,omitempty"`
 }
@@ -0,0 +1,361 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"ticket-reservation/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNoSeatsMatch is returned when no free seats satisfy a best-available
+// request
+var ErrNoSeatsMatch = errors.New("no seats match the request")
+
+// ErrInvalidSeatRequest is returned for a best-available request that can
+// never be met: a seat count the search cannot place or rows outside the
+// event
+var ErrInvalidSeatRequest = errors.New("invalid seat request")
+
+// SeatPreferences narrows and orders the search of ReserveBestAvailable.
+// Seats are priced per event, not per row, so MaxPrice cannot steer the
+// search towards cheaper rows: it only accepts or refuses the whole event.
+type SeatPreferences struct {
+	MinRow   string  `json:"min_row,omitempty"`   // first row considered, "" = front row
+	MaxRow   string  `json:"max_row,omitempty"`   // last row considered, "" = back row
+	MaxPrice float64 `json:"max_price,omitempty"` // cap on the event's seat price, 0 = any
+	Centered bool    `json:"centered,omitempty"`  // prefer seats near the middle of the row
+	Together bool    `json:"together,omitempty"`  // never split the group across two rows
+}
+
+// bestAvailableScript finds and holds ARGV[7] seats in one go. It takes the
+// front-most row (within ARGV[9]..ARGV[10]) with that many contiguous free
+// seats, leftmost or, with ARGV[11] = 1, closest to the middle. If no row
+// has room it splits the group across two adjacent rows, keeping the two
+// blocks aligned and as even as possible, unless ARGV[12] = 1.
+//
+// The seats are only known here, so the record is assembled from the JSON
+// before (ARGV[5]) and after (ARGV[6]) its seat list, and so is the
+// idempotent response around it. Returns {1, seats} or {0} when nothing
+// fits, and {-1, stored} for a replayed idempotency key.
+var bestAvailableScript = redis.NewScript(`
+	local seats_key = KEYS[1]
+	local stats_key = KEYS[2]
+	local holds_key = KEYS[3]
+	local hold_seats_key = KEYS[4]
+	local reservations_key = KEYS[5]
+	local reservation_key = KEYS[6]
+	local owners_key = KEYS[7]
+	local idem_key = KEYS[8]
+	local reservation_id = ARGV[1]
+	local owner = ARGV[2]
+	local expires_at = ARGV[3]
+	local ttl_ms = ARGV[4]
+	local record_head = ARGV[5]
+	local record_tail = ARGV[6]
+	local count = tonumber(ARGV[7])
+	local cols = tonumber(ARGV[8])
+	local min_row = tonumber(ARGV[9])
+	local max_row = tonumber(ARGV[10])
+	local centered = ARGV[11] == '1'
+	local together = ARGV[12] == '1'
+
+	-- A retry gets the first response back
+	if idem_key then
+		local stored = redis.call('GET', idem_key)
+		if stored then
+			return {-1, stored}
+		end
+	end
+
+	-- run[r][c] is the number of free seats from c rightwards, so a block
+	-- fits in O(1)
+	local run = {}
+	for r = min_row, max_row do
+		local ids = {}
+		for c = 1, cols do
+			ids[c] = string.char(64 + r) .. c
+		end
+		local statuses = redis.call('HMGET', seats_key, unpack(ids))
+		run[r] = {[cols + 1] = 0}
+		for c = cols, 1, -1 do
+			run[r][c] = statuses[c] == 'available' and run[r][c + 1] + 1 or 0
+		end
+	end
+
+	local function fits(r, start, n)
+		return run[r][start] >= n
+	end
+
+	-- Rank of a block within its row: twice its distance from the middle
+	-- when centered, else its position from the left
+	local function rank(start, n)
+		if centered then
+			return math.abs(2 * start + n - cols - 2)
+		end
+		return start
+	end
+
+	local best, best_score
+	local function consider(score, blocks)
+		if not best_score or score < best_score then
+			best, best_score = blocks, score
+		end
+	end
+
+	for r = min_row, max_row do
+		for start = 1, cols - count + 1 do
+			if fits(r, start, count) then
+				consider((r - min_row) * 10000 + rank(start, count), {{r, start, count}})
+			end
+		end
+	end
+
+	if not best and not together then
+		for r = min_row, max_row - 1 do
+			for a = math.max(1, count - cols), math.min(count - 1, cols) do
+				local b = count - a
+				local last_sb = cols - b + 1
+
+				-- Nearest start at or before / at or after each column where
+				-- the second block fits, found once per row pair and split
+				local before, after = {}, {}
+				local seen
+				for c = 1, last_sb do
+					if fits(r + 1, c, b) then
+						seen = c
+					end
+					before[c] = seen
+				end
+				seen = nil
+				for c = last_sb, 1, -1 do
+					if fits(r + 1, c, b) then
+						seen = c
+					end
+					after[c] = seen
+				end
+
+				-- Aligned blocks first (twice the offset between their
+				-- middles), then even splits, then front rows
+				local function try(sa, sb)
+					if sb then
+						local gap = math.abs((2 * sa + a) - (2 * sb + b))
+						consider(gap * 100000000 + math.abs(a - b) * 1000000 + (r - min_row) * 10000 + rank(sa, a) + rank(sb, b),
+							{{r, sa, a}, {r + 1, sb, b}})
+					end
+				end
+
+				for sa = 1, cols - a + 1 do
+					if fits(r, sa, a) then
+						-- The gap outweighs the rest of the score, so only the
+						-- starts closest to alignment on either side can win
+						local twice = 2 * sa + a - b
+						local lo = math.floor(twice / 2)
+						local hi = twice - lo
+						if lo >= 1 then
+							try(sa, before[math.min(lo, last_sb)])
+						end
+						if hi <= last_sb then
+							try(sa, after[math.max(hi, 1)])
+						end
+					end
+				end
+			end
+		end
+	end
+
+	if not best then
+		return {0}
+	end
+
+	-- Hold the seats, recording who holds them
+	local held = {}
+	local quoted = {}
+	for _, block in ipairs(best) do
+		local letter = string.char(64 + block[1])
+		for c = block[2], block[2] + block[3] - 1 do
+			local seat_id = letter .. c
+			redis.call('HSET', seats_key, seat_id, 'pending')
+			redis.call('HSET', owners_key, seat_id, owner)
+			held[#held + 1] = seat_id
+			quoted[#quoted + 1] = '"' .. seat_id .. '"'
+		end
+	end
+
+	-- Write the record; it expires with the hold
+	local record = record_head .. '[' .. table.concat(quoted, ',') .. ']' .. record_tail
+	redis.call('SET', reservation_key, record, 'PX', ttl_ms)
+	redis.call('SADD', reservations_key, reservation_id)
+
+	-- Index the hold so the reaper releases it when it expires
+	redis.call('ZADD', holds_key, expires_at, reservation_id)
+	redis.call('HSET', hold_seats_key, reservation_id, table.concat(held, ','))
+
+	-- Update stats
+	redis.call('HINCRBY', stats_key, 'available_seats', -count)
+	redis.call('HINCRBY', stats_key, 'pending_seats', count)
+
+	if idem_key then
+		-- The record is the response, between the stored value's head and tail
+		redis.call('SET', idem_key, ARGV[#ARGV - 2] .. record .. ARGV[#ARGV - 1], 'PX', ARGV[#ARGV])
+	end
+	return {1, table.concat(held, ',')}
+`)
+
+// ReserveBestAvailable finds and holds count seats for a user in one
+// atomic script: contiguous seats in one row if there are any, otherwise
+// the closest split across two adjacent rows.
+func (s *ReservationService) ReserveBestAvailable(eventID, userID string, count int, prefs SeatPreferences, customerName, customerEmail string) (*models.Reservation, error) {
+	if count <= 0 {
+		return nil, fmt.Errorf("%w: seat count must be positive", ErrInvalidSeatRequest)
+	}
+
+	event, err := s.GetEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+	if prefs.MaxPrice > 0 && event.PricePerSeat > prefs.MaxPrice {
+		return nil, fmt.Errorf("%w: seats cost $%.2f, above $%.2f", ErrNoSeatsMatch, event.PricePerSeat, prefs.MaxPrice)
+	}
+	minRow, err := rowNumber(prefs.MinRow, 1, event.Rows)
+	if err != nil {
+		return nil, err
+	}
+	maxRow, err := rowNumber(prefs.MaxRow, event.Rows, event.Rows)
+	if err != nil {
+		return nil, err
+	}
+	if minRow > maxRow {
+		return nil, fmt.Errorf("%w: row range %s-%s is empty", ErrInvalidSeatRequest, prefs.MinRow, prefs.MaxRow)
+	}
+
+	// The search places one row's worth, or two adjacent rows' when it may
+	// split; refuse more before the script spends time looking
+	maxCount := event.SeatsPerRow
+	if !prefs.Together && maxRow > minRow {
+		maxCount *= 2
+	}
+	if count > maxCount {
+		return nil, fmt.Errorf("%w: at most %d seats fit the rows searched, not %d", ErrInvalidSeatRequest, maxCount, count)
+	}
+
+	reservationID := newReservationID(eventID)
+	now := time.Now()
+	expiresAt := now.Add(s.reservationTTL)
+
+	// Everything but the seats, which the script picks
+	reservation := &models.Reservation{
+		ID:            reservationID,
+		EventID:       eventID,
+		UserID:        userID,
+		Status:        models.ReservationPending,
+		TotalAmount:   float64(count) * event.PricePerSeat,
+		CreatedAt:     now,
+		ExpiresAt:     expiresAt,
+		CustomerName:  customerName,
+		CustomerEmail: customerEmail,
+	}
+	resJSON, _ := json.Marshal(reservation)
+	recordHead, recordTail, ok := strings.Cut(string(resJSON), `"seats":null`)
+	if !ok {
+		return nil, fmt.Errorf("failed to prepare reservation record")
+	}
+	recordHead += `"seats":`
+
+	prefsJSON, _ := json.Marshal(prefs)
+	idem := s.idempotent(eventID, "reserve-best", eventID, userID, strconv.Itoa(count), string(prefsJSON), customerName, customerEmail)
+
+	args := []interface{}{
+		reservationID,
+		reservationID + seatOwnerSep + userID,
+		expiresAt.UnixMilli(),
+		s.reservationTTL.Milliseconds(),
+		recordHead,
+		recordTail,
+		count,
+		event.SeatsPerRow,
+		minRow,
+		maxRow,
+		boolArg(prefs.Centered),
+		boolArg(prefs.Together),
+	}
+	keys := []string{
+		fmt.Sprintf(seatsKeyPattern, eventID),
+		fmt.Sprintf(statsKeyPattern, eventID),
+		fmt.Sprintf(holdsKeyPattern, eventID),
+		fmt.Sprintf(holdSeatsKeyPattern, eventID),
+		fmt.Sprintf(reservationsKeyPattern, eventID),
+		reservationKey(reservationID),
+		fmt.Sprintf(seatOwnersKeyPattern, eventID),
+	}
+	keys, args = idem.scriptAround(keys, args)
+
+	result, err := bestAvailableScript.Run(s.ctx, s.rdb, keys, args...).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve seats: %w", err)
+	}
+	switch result[0].(int64) {
+	case 0:
+		if prefs.Together {
+			return nil, fmt.Errorf("%w: %d seats together in one row are not available", ErrNoSeatsMatch, count)
+		}
+		return nil, fmt.Errorf("%w: %d seats in one row or split across two adjacent rows are not available", ErrNoSeatsMatch, count)
+	case -1:
+		var first models.Reservation
+		if err := idem.replay(result[1].(string), &first); err != nil {
+			return nil, err
+		}
+		return &first, nil
+	}
+	reservation.Seats = strings.Split(result[1].(string), ",")
+	s.noteWrite(keys...)
+
+	// The hold index and the user's list live in other slots, as in
+	// ReserveSeats
+	if err := s.rdb.SAdd(s.ctx, holdEventsKey, eventID).Err(); err != nil {
+		log.Printf("[Holds] WARNING: failed to index event %s: %v", eventID, err)
+	}
+	userResKey := fmt.Sprintf(userReservationsKey, userID)
+	if err := s.rdb.SAdd(s.ctx, userResKey, reservationID).Err(); err != nil {
+		log.Printf("WARNING: failed to add reservation %s to user %s: %v", reservationID, userID, err)
+	} else {
+		s.noteWrite(userResKey)
+	}
+
+	// === Write-Through: Record pending reservation in PostgreSQL ===
+	if s.postgres != nil {
+		if pgErr := s.postgres.InsertReservation(reservation); pgErr != nil {
+			log.Printf("[Write-Through] WARNING: PG write failed for reservation %s: %v", reservationID, pgErr)
+		} else {
+			log.Printf("[Write-Through] Reservation %s written to PostgreSQL", reservationID)
+		}
+	}
+
+	return reservation, nil
+}
+
+// rowNumber turns a row letter into its 1-based number, or def when empty
+func rowNumber(row string, def, rows int) (int, error) {
+	row = strings.ToUpper(strings.TrimSpace(row))
+	if row == "" {
+		return def, nil
+	}
+	n := int(row[0]-'A') + 1
+	if len(row) != 1 || n < 1 || n > rows {
+		return 0, fmt.Errorf("%w: row %s is not in A-%c", ErrInvalidSeatRequest, row, rune('A'+rows-1))
+	}
+	return n, nil
+}
+
+func boolArg(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReserveBestAvailable(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Premiere", "Cinema", time.Now().Add(time.Hour), 3, 6, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserve := func(count int, prefs SeatPreferences, want ...string) {
+		t.Helper()
+		res, err := s.ReserveBestAvailable(event.ID, "user-1", count, prefs, "Ann", "ann@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(res.Seats, want) {
+			t.Fatalf("seats = %v, want %v", res.Seats, want)
+		}
+		if got, err := s.GetReservation(res.ID); err != nil || !reflect.DeepEqual(got.Seats, want) || got.TotalAmount != float64(10*count) {
+			t.Fatalf("record = %+v, %v", got, err)
+		}
+	}
+
+	reserve(2, SeatPreferences{Centered: true}, "A3", "A4")
+	// Row A only has pairs left
+	reserve(3, SeatPreferences{}, "B1", "B2", "B3")
+	// No row in A-B has four together: split evenly, aligned
+	reserve(4, SeatPreferences{MaxRow: "B"}, "A5", "A6", "B5", "B6")
+	checkStats(t, s, event.ID, 9, 9, 0, 0)
+
+	_, err = s.ReserveBestAvailable(event.ID, "user-2", 3, SeatPreferences{MaxRow: "B", Together: true}, "", "")
+	if !errors.Is(err, ErrNoSeatsMatch) || !strings.Contains(err.Error(), "together in one row") {
+		t.Fatalf("together in A-B: got %v, want ErrNoSeatsMatch for seats together", err)
+	}
+	if _, err := s.ReserveBestAvailable(event.ID, "user-2", 2, SeatPreferences{MaxPrice: 5}, "", ""); !errors.Is(err, ErrNoSeatsMatch) {
+		t.Fatalf("max price 5: got %v, want ErrNoSeatsMatch", err)
+	}
+	if _, err := s.ReserveBestAvailable(event.ID, "user-2", 2, SeatPreferences{MinRow: "D"}, "", ""); !errors.Is(err, ErrInvalidSeatRequest) {
+		t.Fatalf("row outside the event: got %v, want ErrInvalidSeatRequest", err)
+	}
+	// Counts the search cannot place are refused before the script runs
+	for _, tc := range []struct {
+		count int
+		prefs SeatPreferences
+	}{
+		{1000000000, SeatPreferences{}},
+		{13, SeatPreferences{}},
+		{7, SeatPreferences{Together: true}},
+		{7, SeatPreferences{MinRow: "C", MaxRow: "C"}},
+	} {
+		if _, err := s.ReserveBestAvailable(event.ID, "user-2", tc.count, tc.prefs, "", ""); !errors.Is(err, ErrInvalidSeatRequest) {
+			t.Fatalf("count %d with %+v: got %v, want ErrInvalidSeatRequest", tc.count, tc.prefs, err)
+		}
+	}
+	checkStats(t, s, event.ID, 9, 9, 0, 0)
+
+	// A retry with the same key gets the same seats
+	first, err := s.WithIdempotencyKey("b-1").ReserveBestAvailable(event.ID, "user-3", 2, SeatPreferences{MinRow: "C", Centered: true}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := s.WithIdempotencyKey("b-1").ReserveBestAvailable(event.ID, "user-3", 2, SeatPreferences{MinRow: "C", Centered: true}, "", "")
+	if err != nil || retry.ID != first.ID || !reflect.DeepEqual(retry.Seats, []string{"C3", "C4"}) {
+		t.Fatalf("retry = %+v, %v; want %+v", retry, err, first)
+	}
+	checkStats(t, s, event.ID, 7, 11, 0, 0)
+}
+
+// bruteForceBest is the best-available search tried one block pair at a
+// time, as a reference for the script's indexed search
+func bruteForceBest(free [][]bool, count int, centered bool) []string {
+	rows, cols := len(free), len(free[0])
+	fits := func(r, start, n int) bool {
+		for c := start; c < start+n; c++ {
+			if !free[r][c-1] {
+				return false
+			}
+		}
+		return true
+	}
+	rank := func(start, n int) int {
+		if centered {
+			return abs(2*start + n - cols - 2)
+		}
+		return start
+	}
+	type block struct{ row, start, n int }
+	var best []block
+	bestScore := -1
+	consider := func(score int, blocks ...block) {
+		if bestScore < 0 || score < bestScore {
+			best, bestScore = blocks, score
+		}
+	}
+	for r := 0; r < rows; r++ {
+		for start := 1; start+count-1 <= cols; start++ {
+			if fits(r, start, count) {
+				consider(r*10000+rank(start, count), block{r, start, count})
+			}
+		}
+	}
+	if best == nil {
+		for r := 0; r+1 < rows; r++ {
+			for a := 1; a < count; a++ {
+				b := count - a
+				for sa := 1; sa+a-1 <= cols; sa++ {
+					for sb := 1; sb+b-1 <= cols; sb++ {
+						if fits(r, sa, a) && fits(r+1, sb, b) {
+							gap := abs((2*sa + a) - (2*sb + b))
+							consider(gap*100000000+abs(a-b)*1000000+r*10000+rank(sa, a)+rank(sb, b),
+								block{r, sa, a}, block{r + 1, sb, b})
+						}
+					}
+				}
+			}
+		}
+	}
+	var seats []string
+	for _, bl := range best {
+		for c := bl.start; c < bl.start+bl.n; c++ {
+			seats = append(seats, fmt.Sprintf("%c%d", 'A'+bl.row, c))
+		}
+	}
+	return seats
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// The script picks the same seats as trying every block pair
+func TestReserveBestAvailableMatchesBruteForce(t *testing.T) {
+	s, _ := newTestService(t)
+	rng := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 40; trial++ {
+		event, err := s.CreateEvent("Gala", "Hall", time.Now().Add(time.Hour), 4, 8, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		free := make([][]bool, event.Rows)
+		var taken []string
+		for r := range free {
+			free[r] = make([]bool, event.SeatsPerRow)
+			for c := range free[r] {
+				if rng.Intn(10) < 2 {
+					taken = append(taken, fmt.Sprintf("%c%d", 'A'+r, c+1))
+				} else {
+					free[r][c] = true
+				}
+			}
+		}
+		if len(taken) > 0 {
+			if _, err := s.ReserveSeats(event.ID, "user-1", taken, "", ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Mostly more than the widest free run, so the split search runs
+		count, centered := 3+rng.Intn(event.SeatsPerRow), rng.Intn(2) == 0
+		want := bruteForceBest(free, count, centered)
+		res, err := s.ReserveBestAvailable(event.ID, "user-2", count, SeatPreferences{Centered: centered}, "", "")
+		switch {
+		case want == nil && !errors.Is(err, ErrNoSeatsMatch):
+			t.Fatalf("trial %d (%d seats, centered %v): got %v, want ErrNoSeatsMatch", trial, count, centered, err)
+		case want != nil && err != nil:
+			t.Fatalf("trial %d (%d seats, centered %v): %v, want %v", trial, count, centered, err, want)
+		case want != nil && !reflect.DeepEqual(res.Seats, want):
+			t.Fatalf("trial %d (%d seats, centered %v): seats = %v, want %v", trial, count, centered, res.Seats, want)
+		}
+	}
+}
+
+// A split across wide rows stays cheap enough to run inside one script
+func TestReserveBestAvailableWideSplit(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Stadium", "Arena", time.Now().Add(time.Hour), 26, 50, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every split from 10+50 to 50+10 fits in every pair of rows
+	start := time.Now()
+	res, err := s.ReserveBestAvailable(event.ID, "user-1", 60, SeatPreferences{Centered: true}, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("search took %v, want well under a second", elapsed)
+	}
+	if len(res.Seats) != 60 || res.Seats[0] != "A11" || res.Seats[29] != "A40" || res.Seats[30] != "B11" || res.Seats[59] != "B40" {
+		t.Fatalf("seats = %v, want A11-A40 and B11-B40", res.Seats)
+	}
+}
@@ -28,7 +28,7 @@ func (s *ReservationService) GetEventCacheAside(eventID string) (*models.Event,
 	eventKey := fmt.Sprintf(eventKeyPattern, eventID)
 
 	// Step 1: Check Redis cache
-	eventJSON, err := s.rdb.Get(s.ctx, eventKey).Result()
+	eventJSON, err := s.reader(ReadEvent, eventKey).Get(s.ctx, eventKey).Result()
 	if err == nil {
 		// CACHE HIT — deserialize and return
 		var event models.Event
@@ -64,7 +64,7 @@ func (s *ReservationService) GetSeatsCacheAside(eventID string) (map[string]stri
 	seatsKey := fmt.Sprintf(seatsKeyPattern, eventID)
 
 	// Step 1: Check Redis
-	seatsMap, err := s.rdb.HGetAll(s.ctx, seatsKey).Result()
+	seatsMap, err := s.reader(ReadSeatMap, seatsKey).HGetAll(s.ctx, seatsKey).Result()
 	if err == nil && len(seatsMap) > 0 {
 		log.Printf("[Cache-Aside] HIT — %d seats from Redis", len(seatsMap))
 		return seatsMap, nil
@@ -88,6 +88,7 @@ func (s *ReservationService) GetSeatsCacheAside(eventID string) (map[string]stri
 	}
 	s.rdb.HSet(s.ctx, seatsKey, seatData)
 	s.rdb.Expire(s.ctx, seatsKey, 30*time.Minute)
+	s.noteWrite(seatsKey)
 
 	return seats, nil
 }
@@ -110,6 +111,7 @@ func (s *ReservationService) UpdateEventCacheAside(eventID string, name string)
 	// Step 2: DELETE from cache (next read will re-populate)
 	eventKey := fmt.Sprintf(eventKeyPattern, eventID)
 	s.rdb.Del(s.ctx, eventKey)
+	s.noteWrite(eventKey)
 	log.Printf("[Cache-Aside] Invalidated cache for event %s", eventID)
 
 	return nil
@@ -219,7 +221,8 @@ func (s *ReservationService) GetEventRefreshAhead(eventID string) (*models.Event
 	eventKey := fmt.Sprintf(eventKeyPattern, eventID)
 
 	// Step 1: Get value from Redis
-	eventJSON, err := s.rdb.Get(s.ctx, eventKey).Result()
+	reader := s.reader(ReadEvent, eventKey)
+	eventJSON, err := reader.Get(s.ctx, eventKey).Result()
 	if err == redis.Nil {
 		// Cold miss — load from PostgreSQL
 		return s.loadEventIntoCache(eventID, eventKey)
@@ -229,7 +232,7 @@ func (s *ReservationService) GetEventRefreshAhead(eventID string) (*models.Event
 	}
 
 	// Step 2: Check remaining TTL
-	ttl, _ := s.rdb.TTL(s.ctx, eventKey).Result()
+	ttl, _ := reader.TTL(s.ctx, eventKey).Result()
 	if ttl > 0 {
 		remainingRatio := float64(ttl) / float64(eventCacheTTL)
 
@@ -507,6 +510,7 @@ func (s *ReservationService) ReserveSeatWriteBehind(eventID, seatID, userID stri
 	if err != nil {
 		return err
 	}
+	s.noteWrite(seatsKey)
 
 	// Step 2: Publish to Redis Stream (durable queue)
 	s.rdb.XAdd(s.ctx, &redis.XAddArgs{
@@ -590,6 +594,7 @@ func (s *ReservationService) CreateEventWriteAround(event *models.Event) error {
 	// Optionally invalidate any stale cache
 	eventKey := fmt.Sprintf(eventKeyPattern, event.ID)
 	s.rdb.Del(s.ctx, eventKey)
+	s.noteWrite(eventKey)
 
 	// Redis will be populated on the FIRST READ (via Cache-Aside)
 	return nil
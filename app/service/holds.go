@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"ticket-reservation/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// reapBatch is how many expired holds one script run releases
+const reapBatch = 100
+
+// ExpiredHold is a pending reservation whose hold ran out and whose seats
+// were released
+type ExpiredHold struct {
+	ReservationID string   `json:"reservation_id"`
+	EventID       string   `json:"event_id"`
+	Seats         []string `json:"seats"`
+	Released      int      `json:"released"` // seats that were still pending
+}
+
+// releaseExpiredScript releases up to ARGV[2] holds that expired at or
+// before ARGV[1] (unix ms): seats still pending under the hold go back to
+// available, the stats follow and the holds leave the index. Returns
+// {id, seats, released, ...}.
+var releaseExpiredScript = redis.NewScript(`
+	local seats_key = KEYS[1]
+	local stats_key = KEYS[2]
+	local holds_key = KEYS[3]
+	local hold_seats_key = KEYS[4]
+	local reservations_key = KEYS[5]
+	local owners_key = KEYS[6]
+
+	local out = {}
+	local total = 0
+	local ids = redis.call('ZRANGEBYSCORE', holds_key, '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+	for _, id in ipairs(ids) do
+		local seats = redis.call('HGET', hold_seats_key, id) or ''
+		local released = 0
+		for seat_id in string.gmatch(seats, '[^,]+') do
+			local owner = redis.call('HGET', owners_key, seat_id)
+			local owned = not owner or string.match(owner, '^[^|]*') == id
+			if owned and redis.call('HGET', seats_key, seat_id) == 'pending' then
+				redis.call('HSET', seats_key, seat_id, 'available')
+				redis.call('HDEL', owners_key, seat_id)
+				released = released + 1
+			end
+		end
+		redis.call('ZREM', holds_key, id)
+		redis.call('HDEL', hold_seats_key, id)
+		redis.call('SREM', reservations_key, id)
+		total = total + released
+		out[#out + 1] = id
+		out[#out + 1] = seats
+		out[#out + 1] = released
+	end
+
+	if total > 0 then
+		redis.call('HINCRBY', stats_key, 'pending_seats', -total)
+		redis.call('HINCRBY', stats_key, 'available_seats', total)
+	end
+	return out
+`)
+
+// ReleaseExpiredHolds releases every hold of the event that expired by now,
+// marks the reservations expired and offers the seats to the waitlist
+func (s *ReservationService) ReleaseExpiredHolds(eventID string, now time.Time) ([]ExpiredHold, error) {
+	keys := []string{
+		fmt.Sprintf(seatsKeyPattern, eventID),
+		fmt.Sprintf(statsKeyPattern, eventID),
+		fmt.Sprintf(holdsKeyPattern, eventID),
+		fmt.Sprintf(holdSeatsKeyPattern, eventID),
+		fmt.Sprintf(reservationsKeyPattern, eventID),
+		fmt.Sprintf(seatOwnersKeyPattern, eventID),
+	}
+
+	var expired []ExpiredHold
+	for {
+		result, err := releaseExpiredScript.Run(s.ctx, s.rdb, keys, now.UnixMilli(), reapBatch).Slice()
+		if err != nil {
+			return expired, fmt.Errorf("failed to release expired holds of event %s: %w", eventID, err)
+		}
+		for i := 0; i+2 < len(result); i += 3 {
+			hold := ExpiredHold{
+				ReservationID: result[i].(string),
+				EventID:       eventID,
+				Released:      int(result[i+2].(int64)),
+			}
+			if seats := result[i+1].(string); seats != "" {
+				hold.Seats = strings.Split(seats, ",")
+			}
+			expired = append(expired, hold)
+		}
+		if len(result) > 0 {
+			s.noteWrite(keys...)
+		}
+		if len(result) < 3*reapBatch {
+			break
+		}
+	}
+
+	released := 0
+	for _, hold := range expired {
+		s.markExpired(hold)
+		released += hold.Released
+	}
+	if released > 0 {
+		s.ProcessWaitlist(eventID, released)
+	}
+	return expired, nil
+}
+
+// markExpired flags a released reservation's record, if it has not expired
+// yet, and writes the outcome through to PostgreSQL
+func (s *ReservationService) markExpired(hold ExpiredHold) {
+	resKey := reservationKey(hold.ReservationID)
+	if resJSON, err := s.rdb.Get(s.ctx, resKey).Result(); err == nil {
+		var reservation models.Reservation
+		if json.Unmarshal([]byte(resJSON), &reservation) == nil && reservation.Status == models.ReservationPending {
+			reservation.Status = models.ReservationExpired
+			resJSON2, _ := json.Marshal(reservation)
+			s.rdb.Set(s.ctx, resKey, resJSON2, 24*time.Hour) // Keep expired for 24h, like cancelled
+			s.noteWrite(resKey)
+		}
+	}
+
+	if s.postgres != nil {
+		if pgErr := s.postgres.UpdateReservationStatus(hold.ReservationID, models.ReservationExpired, ""); pgErr != nil {
+			log.Printf("[Write-Through] WARNING: PG update failed for expired %s: %v", hold.ReservationID, pgErr)
+		}
+		if pgErr := s.postgres.UpdateSeatStatuses(hold.EventID, hold.Seats, models.SeatAvailable, ""); pgErr != nil {
+			log.Printf("[Write-Through] WARNING: PG seat update failed for expired %s: %v", hold.ReservationID, pgErr)
+		}
+	}
+}
+
+// ReapExpiredHolds releases the expired holds of every indexed event and
+// drops events without holds from the index
+func (s *ReservationService) ReapExpiredHolds(now time.Time) ([]ExpiredHold, error) {
+	eventIDs, err := s.rdb.SMembers(s.ctx, holdEventsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events with holds: %w", err)
+	}
+
+	var expired []ExpiredHold
+	var firstErr error
+	for _, eventID := range eventIDs {
+		released, err := s.ReleaseExpiredHolds(eventID, now)
+		expired = append(expired, released...)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		// Remove first, then re-add if a reservation slipped in meanwhile:
+		// ReserveSeats adds to the index after creating the hold
+		holdsKey := fmt.Sprintf(holdsKeyPattern, eventID)
+		if n, err := s.rdb.ZCard(s.ctx, holdsKey).Result(); err != nil || n > 0 {
+			continue
+		}
+		s.rdb.SRem(s.ctx, holdEventsKey, eventID)
+		if n, err := s.rdb.ZCard(s.ctx, holdsKey).Result(); err != nil || n > 0 {
+			s.rdb.SAdd(s.ctx, holdEventsKey, eventID)
+		}
+	}
+	return expired, firstErr
+}
+
+// HoldReaperStats counts what a HoldReaper did
+type HoldReaperStats struct {
+	Runs          int64     `json:"runs"`
+	Reservations  int64     `json:"reservations"` // expired holds released
+	Seats         int64     `json:"seats"`        // seats returned to available
+	Notifications int64     `json:"notifications"`
+	LastRun       time.Time `json:"last_run"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// HoldReaper releases expired holds in the background. It reaps every
+// interval; with notifications enabled it also reaps as soon as a node
+// reports that a reservation record expired.
+type HoldReaper struct {
+	svc      *ReservationService
+	interval time.Duration
+	wake     chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	stats HoldReaperStats
+	subs  []*redis.PubSub
+}
+
+// NewHoldReaper creates a reaper that runs every interval (default 5s)
+func NewHoldReaper(svc *ReservationService, interval time.Duration) *HoldReaper {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HoldReaper{
+		svc:      svc,
+		interval: interval,
+		wake:     make(chan struct{}, 1),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start reaps once and then in the background
+func (r *HoldReaper) Start() {
+	r.Reap()
+	r.wg.Add(1)
+	go r.loop()
+}
+
+// Stop stops the background reaping and the notification listeners
+func (r *HoldReaper) Stop() {
+	r.cancel()
+	r.mu.Lock()
+	for _, sub := range r.subs {
+		sub.Close()
+	}
+	r.subs = nil
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// Stats returns a copy of the counters
+func (r *HoldReaper) Stats() HoldReaperStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stats
+}
+
+func (r *HoldReaper) loop() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Reap()
+		case <-r.wake:
+			r.Reap()
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// Reap releases every hold that has expired by now
+func (r *HoldReaper) Reap() {
+	expired, err := r.svc.ReapExpiredHolds(time.Now())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats.Runs++
+	r.stats.LastRun = time.Now()
+	r.stats.LastError = ""
+	if err != nil {
+		r.stats.LastError = err.Error()
+		log.Printf("[Holds] reap failed: %v", err)
+	}
+	for _, hold := range expired {
+		r.stats.Reservations++
+		r.stats.Seats += int64(hold.Released)
+		log.Printf("[Holds] reservation %s expired, released %d seats of event %s", hold.ReservationID, hold.Released, hold.EventID)
+	}
+}
+
+// expiredChannel carries the names of keys that expired on a node
+const expiredChannel = "__keyevent@0__:expired"
+
+// EnableNotifications subscribes to expired-key events on every master so
+// holds are released right when their reservation record expires, instead
+// of on the next tick. It adds the "Ex" flags to notify-keyspace-events on
+// each master and leaves them set. Masters added later are only covered by
+// the periodic reaping.
+func (r *HoldReaper) EnableNotifications() error {
+	rdb := r.svc.rdb
+	return rdb.ForEachMaster(r.ctx, func(ctx context.Context, node *redis.Client) error {
+		addr := node.Options().Addr
+		cfg, err := node.ConfigGet(ctx, "notify-keyspace-events").Result()
+		if err != nil {
+			return fmt.Errorf("%s: CONFIG GET: %w", addr, err)
+		}
+		if flags := notifyFlagsWithExpired(cfg["notify-keyspace-events"]); flags != cfg["notify-keyspace-events"] {
+			if err := node.ConfigSet(ctx, "notify-keyspace-events", flags).Err(); err != nil {
+				return fmt.Errorf("%s: enable expired notifications: %w", addr, err)
+			}
+		}
+
+		// Notifications are local to the node where the key expired
+		sub := node.Subscribe(r.ctx, expiredChannel)
+		if _, err := sub.Receive(ctx); err != nil {
+			sub.Close()
+			return fmt.Errorf("%s: subscribe: %w", addr, err)
+		}
+		r.mu.Lock()
+		r.subs = append(r.subs, sub)
+		r.mu.Unlock()
+		r.wg.Add(1)
+		go r.listen(sub)
+		return nil
+	})
+}
+
+// notifyFlagsWithExpired adds keyevent (E) and expired (x) notifications to
+// a notify-keyspace-events value
+func notifyFlagsWithExpired(flags string) string {
+	if !strings.Contains(flags, "E") {
+		flags += "E"
+	}
+	if !strings.ContainsAny(flags, "xA") {
+		flags += "x"
+	}
+	return flags
+}
+
+// listen wakes the reaper whenever a reservation record expires
+func (r *HoldReaper) listen(sub *redis.PubSub) {
+	defer r.wg.Done()
+	for msg := range sub.Channel() {
+		if !isReservationKey(msg.Payload) {
+			continue
+		}
+		r.mu.Lock()
+		r.stats.Notifications++
+		r.mu.Unlock()
+		select {
+		case r.wake <- struct{}{}:
+		default: // a reap is already pending
+		}
+	}
+}
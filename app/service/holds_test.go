@@ -0,0 +1,92 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+	"ticket-reservation/models"
+)
+
+func TestHoldReaperReleasesExpiredSeats(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	s := NewReservationService(client.Redis(), 150*time.Millisecond)
+
+	event, err := s.CreateEvent("Matinee", "Studio", time.Now().Add(24*time.Hour), 2, 5, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	held, err := s.ReserveSeats(event.ID, "user-1", []string{"A1", "A2"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paid, err := s.ReserveSeats(event.ID, "user-2", []string{"A3"}, "Bob", "bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ConfirmReservation(paid.ID, "pay-2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.JoinWaitlist(event.ID, "user-3", "cy@example.com", 2); err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, s, event.ID, 7, 2, 1, 10)
+
+	time.Sleep(200 * time.Millisecond)
+	reaper := NewHoldReaper(s, 50*time.Millisecond)
+	reaper.Start()
+	defer reaper.Stop()
+
+	// Only the unpaid hold is released; the confirmed one left the index
+	checkStats(t, s, event.ID, 9, 0, 1, 10)
+	if st := reaper.Stats(); st.Reservations != 1 || st.Seats != 2 || st.LastError != "" {
+		t.Fatalf("reaper stats = %+v", st)
+	}
+	if _, err := s.ConfirmReservation(held.ID, "pay-1"); err == nil {
+		t.Fatal("confirmed a reservation whose hold expired")
+	}
+	if n, _ := client.Redis().ZCard(s.ctx, "{event:"+event.ID+"}:waitlist").Result(); n != 0 {
+		t.Fatalf("waitlist still has %d entries after seats were released", n)
+	}
+	if isMember, _ := client.Redis().SIsMember(s.ctx, holdEventsKey, event.ID).Result(); isMember {
+		t.Fatal("event without holds still indexed")
+	}
+}
+
+// A hold released before its record expires must not be confirmable
+func TestConfirmAfterHoldReleased(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Recital", "Hall", time.Now().Add(24*time.Hour), 1, 4, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.ReserveSeats(event.ID, "user-1", []string{"A1", "A2"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := s.ReleaseExpiredHolds(event.ID, res.ExpiresAt.Add(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 1 || expired[0].ReservationID != res.ID || expired[0].Released != 2 || len(expired[0].Seats) != 2 {
+		t.Fatalf("expired = %+v", expired)
+	}
+	checkStats(t, s, event.ID, 4, 0, 0, 0)
+
+	got, err := s.GetReservation(res.ID)
+	if err != nil || got.Status != models.ReservationExpired {
+		t.Fatalf("reservation = %+v, %v; want expired", got, err)
+	}
+	if _, err := s.ConfirmReservation(res.ID, "pay-1"); err == nil || !strings.Contains(err.Error(), "not pending") {
+		t.Fatalf("confirm after release: got %v", err)
+	}
+	checkStats(t, s, event.ID, 4, 0, 0, 0)
+}
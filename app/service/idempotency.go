@@ -0,0 +1,125 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// IdempotencyTTL is how long the first response to an idempotent request
+// is replayed
+const IdempotencyTTL = 24 * time.Hour
+
+// ErrIdempotencyKeyReused is returned when a key is sent again with a
+// different request
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// WithIdempotencyKey returns a view of the service whose reserve, confirm
+// and cancel calls are idempotent under key: the first successful response
+// is stored next to the event and a retry gets it back instead of running
+// again. Failed calls change nothing and are not stored. An empty key
+// returns the service itself.
+func (s *ReservationService) WithIdempotencyKey(key string) *ReservationService {
+	if key == "" {
+		return s
+	}
+	svc := *s
+	svc.idempotencyKey = key
+	return &svc
+}
+
+// storedResponse is the value kept under an idempotency key
+type storedResponse struct {
+	Fingerprint string          `json:"fingerprint"`
+	Response    json.RawMessage `json:"response"`
+}
+
+// idempotentWrite is the response a script stores under the service's
+// idempotency key along with its own writes
+type idempotentWrite struct {
+	key         string
+	fingerprint string
+	value       string
+}
+
+// idempotent prepares the write for op on the event, or returns nil when
+// the service has no idempotency key. request identifies the payload.
+func (s *ReservationService) idempotent(eventID, op string, request ...string) *idempotentWrite {
+	if s.idempotencyKey == "" {
+		return nil
+	}
+	sum := sha256.Sum256([]byte(op + "\x00" + strings.Join(request, "\x00")))
+	return &idempotentWrite{
+		key:         fmt.Sprintf(idempotencyKeyPattern, eventID, s.idempotencyKey),
+		fingerprint: hex.EncodeToString(sum[:]),
+	}
+}
+
+// respond sets the response to store
+func (w *idempotentWrite) respond(response interface{}) {
+	if w == nil {
+		return
+	}
+	raw, _ := json.Marshal(response)
+	value, _ := json.Marshal(storedResponse{Fingerprint: w.fingerprint, Response: raw})
+	w.value = string(value)
+}
+
+// script adds the key and the stored value to a script's KEYS and ARGV.
+// Scripts take the key after their own and read the value and its TTL
+// from the last two ARGV.
+func (w *idempotentWrite) script(keys []string, args []interface{}) ([]string, []interface{}) {
+	if w == nil {
+		return keys, args
+	}
+	return append(keys, w.key), append(args, w.value, IdempotencyTTL.Milliseconds())
+}
+
+// scriptAround is script for a script that builds the response itself.
+// In place of the stored value it adds the JSON before and after the
+// response, so the last three ARGV are head, tail and TTL.
+func (w *idempotentWrite) scriptAround(keys []string, args []interface{}) ([]string, []interface{}) {
+	if w == nil {
+		return keys, args
+	}
+	value, _ := json.Marshal(storedResponse{Fingerprint: w.fingerprint, Response: json.RawMessage("null")})
+	head, tail, _ := strings.Cut(string(value), `"response":null`)
+	return append(keys, w.key), append(args, head+`"response":`, tail, IdempotencyTTL.Milliseconds())
+}
+
+// lookup returns the stored response, if there is one
+func (w *idempotentWrite) lookup(s *ReservationService) (string, bool, error) {
+	if w == nil {
+		return "", false, nil
+	}
+	stored, err := s.rdb.Get(s.ctx, w.key).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	return stored, true, nil
+}
+
+// replay decodes a stored response into response, refusing one stored for
+// a different request
+func (w *idempotentWrite) replay(stored string, response interface{}) error {
+	var sr storedResponse
+	if err := json.Unmarshal([]byte(stored), &sr); err != nil {
+		return fmt.Errorf("failed to unmarshal stored response: %w", err)
+	}
+	if sr.Fingerprint != w.fingerprint {
+		return ErrIdempotencyKeyReused
+	}
+	if response == nil {
+		return nil
+	}
+	return json.Unmarshal(sr.Response, response)
+}
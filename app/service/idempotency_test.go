@@ -0,0 +1,78 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"ticket-reservation/models"
+)
+
+func TestIdempotentReserveAndConfirm(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Festival", "Park", time.Now().Add(time.Hour), 1, 4, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.WithIdempotencyKey("k-1").ReserveSeats(event.ID, "user-1", []string{"A1", "A2"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry, err := s.WithIdempotencyKey("k-1").ReserveSeats(event.ID, "user-1", []string{"A1", "A2"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatalf("retried reserve: %v", err)
+	}
+	if retry.ID != first.ID || retry.Status != models.ReservationPending {
+		t.Fatalf("retry = %+v, want the first reservation %s", retry, first.ID)
+	}
+	checkStats(t, s, event.ID, 2, 2, 0, 0)
+
+	// Same key, other seats
+	if _, err := s.WithIdempotencyKey("k-1").ReserveSeats(event.ID, "user-1", []string{"A3"}, "Ann", "ann@example.com"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("reused key: got %v, want ErrIdempotencyKeyReused", err)
+	}
+	checkStats(t, s, event.ID, 2, 2, 0, 0)
+
+	confirmed, err := s.WithIdempotencyKey("k-2").ConfirmReservation(first.ID, "pay-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := s.WithIdempotencyKey("k-2").ConfirmReservation(first.ID, "pay-1")
+	if err != nil {
+		t.Fatalf("retried confirm: %v", err)
+	}
+	if again.Status != models.ReservationConfirmed || again.PaymentID != "pay-1" || !again.ConfirmedAt.Equal(*confirmed.ConfirmedAt) {
+		t.Fatalf("retried confirm = %+v, want %+v", again, confirmed)
+	}
+	if _, err := s.WithIdempotencyKey("k-2").ConfirmReservation(first.ID, "pay-2"); !errors.Is(err, ErrIdempotencyKeyReused) {
+		t.Fatalf("confirm with another payment: got %v, want ErrIdempotencyKeyReused", err)
+	}
+	// Without a key a second confirm still fails
+	if _, err := s.ConfirmReservation(first.ID, "pay-1"); err == nil {
+		t.Fatal("confirmed twice without an idempotency key")
+	}
+	checkStats(t, s, event.ID, 2, 0, 2, 80)
+}
+
+func TestIdempotentCancel(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Lecture", "Aula", time.Now().Add(time.Hour), 1, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.ReserveSeats(event.ID, "user-1", []string{"A1"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.WithIdempotencyKey("c-1").CancelReservation(res.ID); err != nil {
+			t.Fatalf("cancel %d: %v", i+1, err)
+		}
+	}
+	checkStats(t, s, event.ID, 3, 0, 0, 0)
+	if err := s.CancelReservation(res.ID); err == nil {
+		t.Fatal("cancelled twice without an idempotency key")
+	}
+}
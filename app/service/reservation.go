@@ -25,17 +25,82 @@ const (
 	seatsKeyPattern        = "{event:%s}:seats"     // Hash of seat statuses
 	reservationsKeyPattern = "{event:%s}:reservations" // Set of reservation IDs
 	waitlistKeyPattern     = "{event:%s}:waitlist"  // Sorted set for waitlist
-	reservationKeyPattern  = "reservation:%s"       // Individual reservation data
+	reservationKeyPattern  = "{event:%s}:res:%s"    // Individual reservation data, next to its event
 	userReservationsKey    = "user:%s:reservations" // User's reservations
 	statsKeyPattern        = "{event:%s}:stats"     // Event statistics
+	holdsKeyPattern        = "{event:%s}:holds"      // Sorted set of pending reservation IDs by expiry (unix ms)
+	holdSeatsKeyPattern    = "{event:%s}:hold_seats" // Hash of pending reservation ID -> comma-separated seats
+	holdEventsKey          = "holds:events"          // Set of event IDs that may have pending holds
+	seatOwnersKeyPattern   = "{event:%s}:seat_owners" // Hash of held or sold seat -> "<reservation ID>|<user ID>"
+	idempotencyKeyPattern  = "{event:%s}:idem:%s"     // First response to a request sent with an idempotency key
+
+	// Reservations made before records moved under the event's hash tag
+	legacyReservationKeyPattern = "reservation:%s"
 )
 
+// reservationIDSep separates the event ID from the random part of a
+// reservation ID, so the ID alone locates the record
+const reservationIDSep = "."
+
+// newReservationID returns a reservation ID that carries its event
+func newReservationID(eventID string) string {
+	return eventID + reservationIDSep + uuid.New().String()[:8]
+}
+
+// reservationKey returns the key of a reservation's record. IDs without an
+// event are legacy ones whose records live outside the event's slot.
+func reservationKey(reservationID string) string {
+	if i := strings.LastIndex(reservationID, reservationIDSep); i > 0 {
+		return fmt.Sprintf(reservationKeyPattern, reservationID[:i], reservationID)
+	}
+	return fmt.Sprintf(legacyReservationKeyPattern, reservationID)
+}
+
+// isReservationKey reports whether key holds a reservation record
+func isReservationKey(key string) bool {
+	return strings.HasPrefix(key, "reservation:") || strings.Contains(key, "}:res:")
+}
+
+// KeyPattern is one of the key layouts written by the service
+type KeyPattern struct {
+	Name   string
+	Format string // fmt pattern taking the event, reservation or user IDs, if any
+}
+
+// Glob returns the SCAN MATCH pattern covering every key of the layout
+func (p KeyPattern) Glob() string {
+	return strings.ReplaceAll(p.Format, "%s", "*")
+}
+
+// KeyPatterns lists the service's key layouts, most specific first so the
+// first matching glob names a key
+func KeyPatterns() []KeyPattern {
+	return []KeyPattern{
+		{"event seats", seatsKeyPattern},
+		{"event reservations", reservationsKeyPattern},
+		{"event waitlist", waitlistKeyPattern},
+		{"event stats", statsKeyPattern},
+		{"event holds", holdsKeyPattern},
+		{"event hold seats", holdSeatsKeyPattern},
+		{"event seat owners", seatOwnersKeyPattern},
+		{"idempotent response", idempotencyKeyPattern},
+		{"reservation", reservationKeyPattern},
+		{"event", eventKeyPattern},
+		{"legacy reservation", legacyReservationKeyPattern},
+		{"user reservations", userReservationsKey},
+		{"hold index", holdEventsKey},
+	}
+}
+
 // ReservationService handles ticket reservation operations
 type ReservationService struct {
 	rdb            *redis.ClusterClient
 	postgres       *db.PostgresDB // optional, nil = Redis-only mode
 	ctx            context.Context
 	reservationTTL time.Duration
+	router         *ReadRouter   // picks master/replica/latency clients per read
+	session        *writeTracker // set on Session copies for read-your-writes
+	idempotencyKey string        // set on WithIdempotencyKey copies
 }
 
 // NewReservationService creates a new reservation service
@@ -47,6 +112,7 @@ func NewReservationService(rdb *redis.ClusterClient, reservationTTL time.Duratio
 		rdb:            rdb,
 		ctx:            context.Background(),
 		reservationTTL: reservationTTL,
+		router:         masterOnlyRouter(rdb),
 	}
 }
 
@@ -57,6 +123,36 @@ func NewReservationServiceWithPG(rdb *redis.ClusterClient, pg *db.PostgresDB, re
 	return svc
 }
 
+// SetReadRouter enables per-operation read routing. Without a router every
+// read goes to the slot's master.
+func (s *ReservationService) SetReadRouter(router *ReadRouter) {
+	s.router = router
+}
+
+// Session returns a view of the service with read-your-writes: anything
+// written through the session is read back from masters for the policy's
+// SessionWindow, even when the read class allows replicas
+func (s *ReservationService) Session() *ReservationService {
+	session := *s
+	window := s.router.Policy().SessionWindow
+	if window <= 0 {
+		window = DefaultRoutingPolicy().SessionWindow
+	}
+	session.session = newWriteTracker(window)
+	return &session
+}
+
+// reader returns the client for a read of keys in class
+func (s *ReservationService) reader(class ReadClass, keys ...string) redis.Cmdable {
+	return s.router.reader(class, s.session, keys...)
+}
+
+// noteWrite records written keys for read-your-writes routing
+func (s *ReservationService) noteWrite(keys ...string) {
+	s.router.NoteWrite(keys...)
+	s.session.note(keys...)
+}
+
 // CreateEvent creates a new event with a seat grid
 // Pattern 1: Write-Through — writes to PostgreSQL first (source of truth), then Redis (cache)
 func (s *ReservationService) CreateEvent(name, venue string, eventDate time.Time, rows, seatsPerRow int, pricePerSeat float64) (*models.Event, error) {
@@ -124,6 +220,7 @@ func (s *ReservationService) CreateEvent(name, venue string, eventDate time.Time
 			return nil, fmt.Errorf("failed to create event: %w", err)
 		}
 	} else {
+		s.noteWrite(eventKey, seatsKey, statsKey)
 		log.Printf("[Write-Through] Event %s written to Redis cache", eventID)
 	}
 
@@ -134,7 +231,7 @@ func (s *ReservationService) CreateEvent(name, venue string, eventDate time.Time
 // Pattern 5: Fallback — tries Redis first, falls back to PostgreSQL
 func (s *ReservationService) GetEvent(eventID string) (*models.Event, error) {
 	eventKey := fmt.Sprintf(eventKeyPattern, eventID)
-	eventJSON, err := s.rdb.Get(s.ctx, eventKey).Result()
+	eventJSON, err := s.reader(ReadEvent, eventKey).Get(s.ctx, eventKey).Result()
 	if err == nil {
 		var event models.Event
 		if err := json.Unmarshal([]byte(eventJSON), &event); err != nil {
@@ -171,23 +268,57 @@ func (s *ReservationService) ReserveSeats(eventID, userID string, seatIDs []stri
 		return nil, err
 	}
 
-	reservationID := uuid.New().String()[:12]
+	reservationID := newReservationID(eventID)
 	now := time.Now()
 	expiresAt := now.Add(s.reservationTTL)
 	totalAmount := float64(len(seatIDs)) * event.PricePerSeat
 
+	reservation := &models.Reservation{
+		ID:            reservationID,
+		EventID:       eventID,
+		UserID:        userID,
+		Seats:         seatIDs,
+		Status:        models.ReservationPending,
+		TotalAmount:   totalAmount,
+		CreatedAt:     now,
+		ExpiresAt:     expiresAt,
+		CustomerName:  customerName,
+		CustomerEmail: customerEmail,
+	}
+	resJSON, _ := json.Marshal(reservation)
+
+	idem := s.idempotent(eventID, "reserve", eventID, userID, strings.Join(seatIDs, ","), customerName, customerEmail)
+	idem.respond(reservation)
+
 	// Lua script for atomic seat reservation
-	// All keys use the same hash tag {event:ID} so they're in the same slot
+	// All keys use the same hash tag {event:ID} so they're in the same slot:
+	// the seats are never held without a record and an index entry
 	reserveScript := redis.NewScript(`
 		local seats_key = KEYS[1]
 		local stats_key = KEYS[2]
+		local holds_key = KEYS[3]
+		local hold_seats_key = KEYS[4]
+		local reservations_key = KEYS[5]
+		local reservation_key = KEYS[6]
+		local owners_key = KEYS[7]
+		local idem_key = KEYS[8]
 		local reservation_id = ARGV[1]
-		local user_id = ARGV[2]
+		local owner = ARGV[2]
 		local expires_at = ARGV[3]
-		local seat_count = tonumber(ARGV[4])
+		local ttl_ms = ARGV[4]
+		local record = ARGV[5]
+		local seat_count = tonumber(ARGV[6])
+
+		-- A retry gets the first response back
+		if idem_key then
+			local stored = redis.call('GET', idem_key)
+			if stored then
+				return {-1, stored}
+			end
+		end
 
 		-- Check all seats are available
-		for i = 5, 4 + seat_count do
+		for i = 7, 6 + seat_count do
 			local seat_id = ARGV[i]
 			local status = redis.call('HGET', seats_key, seat_id)
 			if status ~= 'available' then
@@ -195,70 +326,85 @@ func (s *ReservationService) ReserveSeats(eventID, userID string, seatIDs []stri
 			end
 		end
 
-		-- Reserve all seats
-		for i = 5, 4 + seat_count do
+		-- Reserve all seats, recording who holds them
+		local held = {}
+		for i = 7, 6 + seat_count do
 			local seat_id = ARGV[i]
 			redis.call('HSET', seats_key, seat_id, 'pending')
+			redis.call('HSET', owners_key, seat_id, owner)
+			held[#held + 1] = seat_id
 		end
 
+		-- Write the record; it expires with the hold
+		redis.call('SET', reservation_key, record, 'PX', ttl_ms)
+		redis.call('SADD', reservations_key, reservation_id)
+
+		-- Index the hold so the reaper releases it when it expires
+		redis.call('ZADD', holds_key, expires_at, reservation_id)
+		redis.call('HSET', hold_seats_key, reservation_id, table.concat(held, ','))
+
 		-- Update stats
 		redis.call('HINCRBY', stats_key, 'available_seats', -seat_count)
 		redis.call('HINCRBY', stats_key, 'pending_seats', seat_count)
 
+		if idem_key then
+			redis.call('SET', idem_key, ARGV[#ARGV - 1], 'PX', ARGV[#ARGV])
+		end
 		return {1, reservation_id}
 	`)
 
 	// Build script arguments
 	args := []interface{}{
 		reservationID,
-		userID,
-		expiresAt.Unix(),
+		reservationID + seatOwnerSep + userID,
+		expiresAt.UnixMilli(),
+		s.reservationTTL.Milliseconds(),
+		string(resJSON),
 		len(seatIDs),
 	}
 	for _, seatID := range seatIDs {
 		args = append(args, seatID)
 	}
 
-	seatsKey := fmt.Sprintf(seatsKeyPattern, eventID)
-	statsKey := fmt.Sprintf(statsKeyPattern, eventID)
+	keys := []string{
+		fmt.Sprintf(seatsKeyPattern, eventID),
+		fmt.Sprintf(statsKeyPattern, eventID),
+		fmt.Sprintf(holdsKeyPattern, eventID),
+		fmt.Sprintf(holdSeatsKeyPattern, eventID),
+		fmt.Sprintf(reservationsKeyPattern, eventID),
+		reservationKey(reservationID),
+		fmt.Sprintf(seatOwnersKeyPattern, eventID),
+	}
+	keys, args = idem.script(keys, args)
 
-	result, err := reserveScript.Run(s.ctx, s.rdb, []string{seatsKey, statsKey}, args...).Slice()
+	result, err := reserveScript.Run(s.ctx, s.rdb, keys, args...).Slice()
 	if err != nil {
 		return nil, fmt.Errorf("failed to reserve seats: %w", err)
 	}
 
-	if result[0].(int64) == 0 {
+	switch result[0].(int64) {
+	case 0:
 		return nil, fmt.Errorf("seat %s is not available", result[2].(string))
+	case -1:
+		var first models.Reservation
+		if err := idem.replay(result[1].(string), &first); err != nil {
+			return nil, err
+		}
+		return &first, nil
 	}
+	s.noteWrite(keys...)
 
-	// Create reservation record
-	reservation := &models.Reservation{
-		ID:            reservationID,
-		EventID:       eventID,
-		UserID:        userID,
-		Seats:         seatIDs,
-		Status:        models.ReservationPending,
-		TotalAmount:   totalAmount,
-		CreatedAt:     now,
-		ExpiresAt:     expiresAt,
-		CustomerName:  customerName,
-		CustomerEmail: customerEmail,
+	// The hold index and the user's list live in other slots. A hold missing
+	// from the index is still found by the next reap that lists the event; a
+	// reservation missing from the user's list is still reachable by ID.
+	if err := s.rdb.SAdd(s.ctx, holdEventsKey, eventID).Err(); err != nil {
+		log.Printf("[Holds] WARNING: failed to index event %s: %v", eventID, err)
 	}
-
-	resJSON, _ := json.Marshal(reservation)
-	resKey := fmt.Sprintf(reservationKeyPattern, reservationID)
-	reservationsSetKey := fmt.Sprintf(reservationsKeyPattern, eventID)
-
-	pipe := s.rdb.Pipeline()
-	pipe.Set(s.ctx, resKey, resJSON, s.reservationTTL)
-	pipe.SAdd(s.ctx, reservationsSetKey, reservationID)
-	pipe.SAdd(s.ctx, fmt.Sprintf(userReservationsKey, userID), reservationID)
-
-	_, err = pipe.Exec(s.ctx)
-	if err != nil {
-		// Rollback seats on failure
-		s.releaseSeatsInternal(eventID, seatIDs)
-		return nil, fmt.Errorf("failed to store reservation: %w", err)
+	userResKey := fmt.Sprintf(userReservationsKey, userID)
+	if err := s.rdb.SAdd(s.ctx, userResKey, reservationID).Err(); err != nil {
+		log.Printf("WARNING: failed to add reservation %s to user %s: %v", reservationID, userID, err)
+	} else {
+		s.noteWrite(userResKey)
 	}
 
 	// === Write-Through: Record pending reservation in PostgreSQL ===
@@ -276,7 +422,7 @@ func (s *ReservationService) ReserveSeats(eventID, userID string, seatIDs []stri
 
 // ConfirmReservation confirms a pending reservation (simulates payment)
 func (s *ReservationService) ConfirmReservation(reservationID, paymentID string) (*models.Reservation, error) {
-	resKey := fmt.Sprintf(reservationKeyPattern, reservationID)
+	resKey := reservationKey(reservationID)
 	resJSON, err := s.rdb.Get(s.ctx, resKey).Result()
 	if err == redis.Nil {
 		return nil, fmt.Errorf("reservation not found or expired: %s", reservationID)
@@ -290,16 +436,71 @@ func (s *ReservationService) ConfirmReservation(reservationID, paymentID string)
 		return nil, fmt.Errorf("failed to unmarshal reservation: %w", err)
 	}
 
+	// A retry of a confirm that went through finds the reservation
+	// confirmed already: answer it with the first response
+	idem := s.idempotent(reservation.EventID, "confirm", reservationID, paymentID)
+	if stored, ok, err := idem.lookup(s); err != nil {
+		return nil, err
+	} else if ok {
+		var first models.Reservation
+		if err := idem.replay(stored, &first); err != nil {
+			return nil, err
+		}
+		return &first, nil
+	}
+
 	if reservation.Status != models.ReservationPending {
 		return nil, fmt.Errorf("reservation is not pending: %s", reservation.Status)
 	}
 
+	now := time.Now()
+	reservation.Status = models.ReservationConfirmed
+	reservation.ConfirmedAt = &now
+	reservation.PaymentID = paymentID
+	idem.respond(reservation)
+
 	// Confirm script - update seats to sold and update stats
 	confirmScript := redis.NewScript(`
 		local seats_key = KEYS[1]
 		local stats_key = KEYS[2]
+		local holds_key = KEYS[3]
+		local hold_seats_key = KEYS[4]
+		local owners_key = KEYS[5]
+		local idem_key = KEYS[6]
 		local seat_count = tonumber(ARGV[1])
 		local revenue = tonumber(ARGV[2])
+		local reservation_id = ARGV[3 + seat_count]
+
+		-- A concurrent retry may have confirmed it already
+		if idem_key then
+			local stored = redis.call('GET', idem_key)
+			if stored then
+				return {-1, stored}
+			end
+		end
+
+		-- Every seat must still be pending under this reservation: the
+		-- reaper may have released the hold and someone else taken the seat.
+		-- Seats held before owners were recorded have none.
+		for i = 3, 2 + seat_count do
+			local seat_id = ARGV[i]
+			local status = redis.call('HGET', seats_key, seat_id)
+			local owner = redis.call('HGET', owners_key, seat_id)
+			if owner and string.match(owner, '^[^|]*') ~= reservation_id then
+				if status == 'sold' then
+					return {0, seat_id, 'sold_to_other'}
+				end
+				return {0, seat_id, 'held_by_other'}
+			end
+			if status == 'sold' then
+				return {0, seat_id, 'already_sold'}
+			end
+			if status ~= 'pending' then
+				return {0, seat_id, 'released'}
+			end
+		end
+		redis.call('ZREM', holds_key, reservation_id)
+		redis.call('HDEL', hold_seats_key, reservation_id)
 
 		-- Update seats to sold
 		for i = 3, 2 + seat_count do
@@ -312,7 +513,10 @@ func (s *ReservationService) ConfirmReservation(reservationID, paymentID string)
 		redis.call('HINCRBY', stats_key, 'sold_seats', seat_count)
 		redis.call('HINCRBYFLOAT', stats_key, 'revenue', revenue)
 
-		return 1
+		if idem_key then
+			redis.call('SET', idem_key, ARGV[#ARGV - 1], 'PX', ARGV[#ARGV])
+		end
+		return {1}
 	`)
 
 	args := []interface{}{
@@ -322,23 +526,34 @@ func (s *ReservationService) ConfirmReservation(reservationID, paymentID string)
 	for _, seatID := range reservation.Seats {
 		args = append(args, seatID)
 	}
+	args = append(args, reservationID)
 
 	seatsKey := fmt.Sprintf(seatsKeyPattern, reservation.EventID)
 	statsKey := fmt.Sprintf(statsKeyPattern, reservation.EventID)
+	holdsKey := fmt.Sprintf(holdsKeyPattern, reservation.EventID)
+	holdSeatsKey := fmt.Sprintf(holdSeatsKeyPattern, reservation.EventID)
+	ownersKey := fmt.Sprintf(seatOwnersKeyPattern, reservation.EventID)
+	keys, args := idem.script([]string{seatsKey, statsKey, holdsKey, holdSeatsKey, ownersKey}, args)
 
-	_, err = confirmScript.Run(s.ctx, s.rdb, []string{seatsKey, statsKey}, args...).Result()
+	result, err := confirmScript.Run(s.ctx, s.rdb, keys, args...).Slice()
 	if err != nil {
 		return nil, fmt.Errorf("failed to confirm seats: %w", err)
 	}
+	switch result[0].(int64) {
+	case 0:
+		return nil, &SeatConflictError{ReservationID: reservationID, SeatConflict: seatConflicts(result[1:])[0]}
+	case -1:
+		var first models.Reservation
+		if err := idem.replay(result[1].(string), &first); err != nil {
+			return nil, err
+		}
+		return &first, nil
+	}
 
 	// Update reservation
-	now := time.Now()
-	reservation.Status = models.ReservationConfirmed
-	reservation.ConfirmedAt = &now
-	reservation.PaymentID = paymentID
-
 	resJSON2, _ := json.Marshal(reservation)
 	s.rdb.Set(s.ctx, resKey, resJSON2, 0) // No expiry for confirmed reservations
+	s.noteWrite(append(keys, resKey)...)
 
 	// === Write-Through: Update PostgreSQL ===
 	if s.postgres != nil {
@@ -356,7 +571,7 @@ func (s *ReservationService) ConfirmReservation(reservationID, paymentID string)
 
 // CancelReservation cancels a reservation and releases seats
 func (s *ReservationService) CancelReservation(reservationID string) error {
-	resKey := fmt.Sprintf(reservationKeyPattern, reservationID)
+	resKey := reservationKey(reservationID)
 	resJSON, err := s.rdb.Get(s.ctx, resKey).Result()
 	if err == redis.Nil {
 		return fmt.Errorf("reservation not found: %s", reservationID)
@@ -370,15 +585,30 @@ func (s *ReservationService) CancelReservation(reservationID string) error {
 		return fmt.Errorf("failed to unmarshal reservation: %w", err)
 	}
 
+	// A retry of a cancel that went through finds it cancelled already
+	idem := s.idempotent(reservation.EventID, "cancel", reservationID)
+	if stored, ok, err := idem.lookup(s); err != nil {
+		return err
+	} else if ok {
+		return idem.replay(stored, nil)
+	}
+
 	if reservation.Status == models.ReservationCancelled {
 		return fmt.Errorf("reservation already cancelled")
 	}
 
-	// Release seats
-	err = s.releaseSeatsInternal(reservation.EventID, reservation.Seats)
+	// Release seats; seats that moved on to other reservations stay theirs
+	idem.respond(nil)
+	conflicts, replayed, err := s.releaseSeatsInternal(reservation.EventID, reservationID, reservation.Seats, idem)
 	if err != nil {
 		return err
 	}
+	if replayed != "" {
+		return idem.replay(replayed, nil)
+	}
+	for _, c := range conflicts {
+		log.Printf("WARNING: cancelling %s left seat %s alone: %s", reservationID, c.SeatID, c.Reason)
+	}
 
 	// Update reservation status
 	now := time.Now()
@@ -387,6 +617,7 @@ func (s *ReservationService) CancelReservation(reservationID string) error {
 
 	resJSON2, _ := json.Marshal(reservation)
 	s.rdb.Set(s.ctx, resKey, resJSON2, 24*time.Hour) // Keep cancelled for 24h
+	s.noteWrite(resKey)
 
 	// === Write-Through: Update PostgreSQL ===
 	if s.postgres != nil {
@@ -405,19 +636,44 @@ func (s *ReservationService) CancelReservation(reservationID string) error {
 	return nil
 }
 
-// releaseSeatsInternal releases seats back to available
-func (s *ReservationService) releaseSeatsInternal(eventID string, seatIDs []string) error {
+// releaseSeatsInternal releases the seats the reservation still holds back
+// to available and drops its hold. It returns the seats it left alone
+// because other reservations own them. With idem, a release already done
+// under the same idempotency key is skipped and its stored response
+// returned instead.
+func (s *ReservationService) releaseSeatsInternal(eventID, reservationID string, seatIDs []string, idem *idempotentWrite) ([]SeatConflict, string, error) {
 	releaseScript := redis.NewScript(`
 		local seats_key = KEYS[1]
 		local stats_key = KEYS[2]
+		local holds_key = KEYS[3]
+		local hold_seats_key = KEYS[4]
+		local owners_key = KEYS[5]
+		local idem_key = KEYS[6]
 		local seat_count = tonumber(ARGV[1])
+		local reservation_id = ARGV[2 + seat_count]
 		local released = 0
+		local out = {0}
+
+		if idem_key then
+			local stored = redis.call('GET', idem_key)
+			if stored then
+				return {-1, stored}
+			end
+		end
+
+		redis.call('ZREM', holds_key, reservation_id)
+		redis.call('HDEL', hold_seats_key, reservation_id)
 
 		for i = 2, 1 + seat_count do
 			local seat_id = ARGV[i]
 			local status = redis.call('HGET', seats_key, seat_id)
-			if status == 'pending' then
+			local owner = redis.call('HGET', owners_key, seat_id)
+			if owner and string.match(owner, '^[^|]*') ~= reservation_id then
+				out[#out + 1] = seat_id
+				out[#out + 1] = status == 'sold' and 'sold_to_other' or 'held_by_other'
+			elseif status == 'pending' then
 				redis.call('HSET', seats_key, seat_id, 'available')
+				redis.call('HDEL', owners_key, seat_id)
 				released = released + 1
 			end
 		end
@@ -427,19 +683,35 @@ func (s *ReservationService) releaseSeatsInternal(eventID string, seatIDs []stri
 			redis.call('HINCRBY', stats_key, 'available_seats', released)
 		end
 
-		return released
+		if idem_key then
+			redis.call('SET', idem_key, ARGV[#ARGV - 1], 'PX', ARGV[#ARGV])
+		end
+		out[1] = released
+		return out
 	`)
 
 	args := []interface{}{len(seatIDs)}
 	for _, seatID := range seatIDs {
 		args = append(args, seatID)
 	}
+	args = append(args, reservationID)
 
 	seatsKey := fmt.Sprintf(seatsKeyPattern, eventID)
 	statsKey := fmt.Sprintf(statsKeyPattern, eventID)
+	holdsKey := fmt.Sprintf(holdsKeyPattern, eventID)
+	holdSeatsKey := fmt.Sprintf(holdSeatsKeyPattern, eventID)
+	ownersKey := fmt.Sprintf(seatOwnersKeyPattern, eventID)
+	keys, args := idem.script([]string{seatsKey, statsKey, holdsKey, holdSeatsKey, ownersKey}, args)
 
-	_, err := releaseScript.Run(s.ctx, s.rdb, []string{seatsKey, statsKey}, args...).Result()
-	return err
+	result, err := releaseScript.Run(s.ctx, s.rdb, keys, args...).Slice()
+	if err != nil {
+		return nil, "", err
+	}
+	if result[0].(int64) == -1 {
+		return nil, result[1].(string), nil
+	}
+	s.noteWrite(keys...)
+	return seatConflicts(result[1:]), "", nil
 }
 
 // GetAvailability returns event availability statistics
@@ -448,7 +720,7 @@ func (s *ReservationService) GetAvailability(eventID string) (*models.EventStats
 	statsKey := fmt.Sprintf(statsKeyPattern, eventID)
 	waitlistKey := fmt.Sprintf(waitlistKeyPattern, eventID)
 
-	pipe := s.rdb.Pipeline()
+	pipe := s.reader(ReadAnalytics, statsKey, waitlistKey).Pipeline()
 	statsCmd := pipe.HGetAll(s.ctx, statsKey)
 	waitlistCmd := pipe.ZCard(s.ctx, waitlistKey)
 
@@ -486,7 +758,7 @@ func (s *ReservationService) GetAvailability(eventID string) (*models.EventStats
 // GetAvailableSeats returns a list of available seat IDs
 func (s *ReservationService) GetAvailableSeats(eventID string) ([]string, error) {
 	seatsKey := fmt.Sprintf(seatsKeyPattern, eventID)
-	seatsMap, err := s.rdb.HGetAll(s.ctx, seatsKey).Result()
+	seatsMap, err := s.reader(ReadSeatMap, seatsKey).HGetAll(s.ctx, seatsKey).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get seats: %w", err)
 	}
@@ -525,6 +797,7 @@ func (s *ReservationService) JoinWaitlist(eventID, userID, email string, request
 	if err != nil {
 		return nil, fmt.Errorf("failed to join waitlist: %w", err)
 	}
+	s.noteWrite(waitlistKey)
 
 	return entry, nil
 }
@@ -556,6 +829,7 @@ func (s *ReservationService) ProcessWaitlist(eventID string, availableSeats int)
 
 			// Remove from waitlist
 			s.rdb.ZRem(s.ctx, waitlistKey, entryJSON)
+			s.noteWrite(waitlistKey)
 			availableSeats -= entry.RequestedSeats
 		}
 
@@ -568,8 +842,8 @@ func (s *ReservationService) ProcessWaitlist(eventID string, availableSeats int)
 // GetReservation retrieves a reservation by ID
 // Pattern 5: Fallback — tries Redis first, falls back to PostgreSQL
 func (s *ReservationService) GetReservation(reservationID string) (*models.Reservation, error) {
-	resKey := fmt.Sprintf(reservationKeyPattern, reservationID)
-	resJSON, err := s.rdb.Get(s.ctx, resKey).Result()
+	resKey := reservationKey(reservationID)
+	resJSON, err := s.reader(ReadReservation, resKey).Get(s.ctx, resKey).Result()
 	if err == nil {
 		var reservation models.Reservation
 		if err := json.Unmarshal([]byte(resJSON), &reservation); err != nil {
@@ -593,7 +867,7 @@ func (s *ReservationService) GetReservation(reservationID string) (*models.Reser
 // GetUserReservations retrieves all reservations for a user
 func (s *ReservationService) GetUserReservations(userID string) ([]*models.Reservation, error) {
 	userResKey := fmt.Sprintf(userReservationsKey, userID)
-	resIDs, err := s.rdb.SMembers(s.ctx, userResKey).Result()
+	resIDs, err := s.reader(ReadReservation, userResKey).SMembers(s.ctx, userResKey).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user reservations: %w", err)
 	}
@@ -617,7 +891,7 @@ func (s *ReservationService) PrintSeatMap(eventID string) error {
 	}
 
 	seatsKey := fmt.Sprintf(seatsKeyPattern, eventID)
-	seatsMap, err := s.rdb.HGetAll(s.ctx, seatsKey).Result()
+	seatsMap, err := s.reader(ReadSeatMap, seatsKey).HGetAll(s.ctx, seatsKey).Result()
 	if err != nil {
 		return fmt.Errorf("failed to get seats: %w", err)
 	}
@@ -675,6 +949,7 @@ func (s *ReservationService) ReconcileReservations(eventID string, since time.Ti
 
 	// Check each seat in Redis and fix mismatches
 	seatsKey := fmt.Sprintf(seatsKeyPattern, eventID)
+	ownersKey := fmt.Sprintf(seatOwnersKeyPattern, eventID)
 	fixed := 0
 
 	for _, seat := range confirmedSeats {
@@ -690,11 +965,14 @@ func (s *ReservationService) ReconcileReservations(eventID string, since time.Ti
 			log.Printf("[Reconciliation] MISMATCH: Seat %s is '%s' in Redis but confirmed in PG (reservation %s). Fixing...",
 				seat.SeatID, redisStatus, seat.ReservationID)
 
-			err = s.rdb.HSet(s.ctx, seatsKey, seat.SeatID, string(models.SeatSold)).Err()
-			if err != nil {
+			pipe := s.rdb.Pipeline()
+			pipe.HSet(s.ctx, seatsKey, seat.SeatID, string(models.SeatSold))
+			pipe.HSet(s.ctx, ownersKey, seat.SeatID, seat.ReservationID+seatOwnerSep+seat.UserID)
+			if _, err = pipe.Exec(s.ctx); err != nil {
 				log.Printf("[Reconciliation] ERROR: Failed to fix seat %s in Redis: %v", seat.SeatID, err)
 				continue
 			}
+			s.noteWrite(seatsKey, ownersKey)
 			fixed++
 		}
 	}
@@ -703,15 +981,21 @@ func (s *ReservationService) ReconcileReservations(eventID string, since time.Ti
 	return fixed, nil
 }
 
-// CleanupExpiredReservations cleans up expired pending reservations
+// CleanupExpiredReservations releases the event's expired holds now and
+// drops reservation IDs whose records are gone from the event's set
 func (s *ReservationService) CleanupExpiredReservations(eventID string) (int, error) {
+	expired, err := s.ReleaseExpiredHolds(eventID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
 	reservationsKey := fmt.Sprintf(reservationsKeyPattern, eventID)
 	resIDs, err := s.rdb.SMembers(s.ctx, reservationsKey).Result()
 	if err != nil {
-		return 0, err
+		return len(expired), err
 	}
 
-	cleaned := 0
+	cleaned := len(expired)
 	for _, resID := range resIDs {
 		res, err := s.GetReservation(resID)
 		if err != nil {
@@ -722,7 +1006,7 @@ func (s *ReservationService) CleanupExpiredReservations(eventID string) (int, er
 		}
 
 		if res.Status == models.ReservationPending && time.Now().After(res.ExpiresAt) {
-			s.releaseSeatsInternal(eventID, res.Seats)
+			s.releaseSeatsInternal(eventID, resID, res.Seats, nil)
 			s.rdb.SRem(s.ctx, reservationsKey, resID)
 			cleaned++
 		}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"ticket-reservation/cluster"
+	"ticket-reservation/clustertest"
+	"ticket-reservation/models"
+)
+
+func newTestService(t *testing.T) (*ReservationService, *clustertest.Cluster) {
+	t.Helper()
+	fc := clustertest.New(t, 3)
+	client, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return NewReservationService(client.Redis(), time.Minute), fc
+}
+
+func checkStats(t *testing.T, s *ReservationService, eventID string, available, pending, sold int, revenue float64) {
+	t.Helper()
+	stats, err := s.GetAvailability(eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.AvailableSeats != available || stats.PendingSeats != pending || stats.SoldSeats != sold || stats.Revenue != revenue {
+		t.Fatalf("stats = available %d, pending %d, sold %d, revenue %.2f; want %d, %d, %d, %.2f",
+			stats.AvailableSeats, stats.PendingSeats, stats.SoldSeats, stats.Revenue, available, pending, sold, revenue)
+	}
+}
+
+func TestReservationLifecycle(t *testing.T) {
+	s, _ := newTestService(t)
+
+	event, err := s.CreateEvent("Concert", "Arena", time.Now().Add(24*time.Hour), 2, 5, 25.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.GetEvent(event.ID)
+	if err != nil || got.Name != "Concert" || got.TotalSeats != 10 {
+		t.Fatalf("GetEvent = %+v, %v", got, err)
+	}
+	checkStats(t, s, event.ID, 10, 0, 0, 0)
+
+	res, err := s.ReserveSeats(event.ID, "user-1", []string{"A1", "A2"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != models.ReservationPending || res.TotalAmount != 51 {
+		t.Fatalf("reservation = %+v", res)
+	}
+	checkStats(t, s, event.ID, 8, 2, 0, 0)
+
+	// A seat can only be held once
+	if _, err := s.ReserveSeats(event.ID, "user-2", []string{"A3", "A2"}, "Bob", "bob@example.com"); err == nil || !strings.Contains(err.Error(), "A2") {
+		t.Fatalf("double reservation: got %v, want seat A2 not available", err)
+	}
+	checkStats(t, s, event.ID, 8, 2, 0, 0)
+
+	confirmed, err := s.ConfirmReservation(res.ID, "pay-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if confirmed.Status != models.ReservationConfirmed {
+		t.Fatalf("confirmed status = %s", confirmed.Status)
+	}
+	checkStats(t, s, event.ID, 8, 0, 2, 51)
+
+	seats, err := s.GetAvailableSeats(event.ID)
+	if err != nil || len(seats) != 8 {
+		t.Fatalf("available seats = %v, %v", seats, err)
+	}
+}
+
+func TestCancelReleasesSeats(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Play", "Theatre", time.Now().Add(time.Hour), 1, 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.ReserveSeats(event.ID, "user-1", []string{"A1", "A2", "A3"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CancelReservation(res.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, s, event.ID, 4, 0, 0, 0)
+
+	if err := s.CancelReservation(res.ID); err == nil {
+		t.Fatal("cancelling twice succeeded")
+	}
+	if _, err := s.ConfirmReservation(res.ID, "pay-1"); err == nil {
+		t.Fatal("confirming a cancelled reservation succeeded")
+	}
+	if _, err := s.ReserveSeats(event.ID, "user-2", []string{"A1"}, "Bob", "bob@example.com"); err != nil {
+		t.Fatalf("released seat not reservable: %v", err)
+	}
+}
+
+// The reservation record is written by the script that holds the seats,
+// under the event's hash tag, and expires with the hold
+func TestReservationRecordWithSeats(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Opera", "House", time.Now().Add(time.Hour), 1, 4, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := s.ReserveSeats(event.ID, "user-1", []string{"A1", "A2"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resKey := reservationKey(res.ID)
+	if want := fmt.Sprintf(reservationKeyPattern, event.ID, res.ID); resKey != want {
+		t.Fatalf("record key = %s, want %s", resKey, want)
+	}
+	if cluster.KeySlot(resKey) != cluster.KeySlot(fmt.Sprintf(seatsKeyPattern, event.ID)) {
+		t.Fatalf("record %s is not in the event's slot", resKey)
+	}
+	if ttl, err := s.rdb.PTTL(s.ctx, resKey).Result(); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("record TTL = %v, %v; want the hold time", ttl, err)
+	}
+	if ok, _ := s.rdb.SIsMember(s.ctx, fmt.Sprintf(reservationsKeyPattern, event.ID), res.ID).Result(); !ok {
+		t.Fatal("reservation missing from the event's set")
+	}
+	list, err := s.GetUserReservations("user-1")
+	if err != nil || len(list) != 1 || list[0].ID != res.ID {
+		t.Fatalf("user reservations = %v, %v", list, err)
+	}
+}
+
+// A reservation whose seats were released and taken by someone else can
+// neither sell nor free them
+func TestSeatOwnership(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Gala", "Ballroom", time.Now().Add(time.Hour), 1, 4, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	late, err := s.ReserveSeats(event.ID, "user-1", []string{"A1", "A2"}, "Ann", "ann@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The hold is released while the record still reads pending
+	if _, _, err := s.releaseSeatsInternal(event.ID, late.ID, late.Seats, nil); err != nil {
+		t.Fatal(err)
+	}
+	other, err := s.ReserveSeats(event.ID, "user-2", []string{"A1"}, "Bob", "bob@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.ConfirmReservation(late.ID, "pay-1")
+	var conflict *SeatConflictError
+	if !errors.As(err, &conflict) || conflict.SeatID != "A1" || conflict.Reason != SeatHeldByOther {
+		t.Fatalf("late confirm: got %v, want A1 held by another reservation", err)
+	}
+	if err := s.CancelReservation(late.ID); err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, s, event.ID, 3, 1, 0, 0)
+
+	if _, err := s.ConfirmReservation(other.ID, "pay-2"); err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, s, event.ID, 3, 0, 1, 20)
+
+	held, err := s.ReserveSeats(event.ID, "user-3", []string{"A3"}, "Cy", "cy@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seats, err := s.GetSeats(event.ID)
+	if err != nil || len(seats) != 4 {
+		t.Fatalf("seats = %v, %v", seats, err)
+	}
+	if a1 := seats[0]; a1.Status != models.SeatSold || a1.SoldTo != "user-2" || a1.ReservationID != other.ID {
+		t.Fatalf("A1 = %+v, want sold to user-2", a1)
+	}
+	if a2 := seats[1]; a2.Status != models.SeatAvailable || a2.HeldBy != "" || a2.ReservationID != "" {
+		t.Fatalf("A2 = %+v, want available", a2)
+	}
+	if a3 := seats[2]; a3.Status != models.SeatPending || a3.HeldBy != "user-3" || a3.ReservationID != held.ID {
+		t.Fatalf("A3 = %+v, want held by user-3", a3)
+	}
+}
+
+// Records written before they moved under the event's hash tag are still
+// read, confirmed and cancelled through their old key
+func TestLegacyReservationRecord(t *testing.T) {
+	s, _ := newTestService(t)
+	event, err := s.CreateEvent("Ballet", "Hall", time.Now().Add(time.Hour), 1, 4, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seatsKey := fmt.Sprintf(seatsKeyPattern, event.ID)
+	statsKey := fmt.Sprintf(statsKeyPattern, event.ID)
+	legacy := func(id string, seats ...string) {
+		t.Helper()
+		for _, seat := range seats {
+			s.rdb.HSet(s.ctx, seatsKey, seat, string(models.SeatPending))
+		}
+		s.rdb.HIncrBy(s.ctx, statsKey, "available_seats", -int64(len(seats)))
+		s.rdb.HIncrBy(s.ctx, statsKey, "pending_seats", int64(len(seats)))
+		resJSON, _ := json.Marshal(models.Reservation{
+			ID:          id,
+			EventID:     event.ID,
+			UserID:      "user-1",
+			Seats:       seats,
+			Status:      models.ReservationPending,
+			TotalAmount: 15 * float64(len(seats)),
+			ExpiresAt:   time.Now().Add(time.Minute),
+		})
+		if err := s.rdb.Set(s.ctx, fmt.Sprintf(legacyReservationKeyPattern, id), resJSON, time.Minute).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy("1a2b3c4d-5e6", "A1", "A2")
+	legacy("9f8e7d6c-5b4", "A3")
+	checkStats(t, s, event.ID, 1, 3, 0, 0)
+
+	got, err := s.GetReservation("1a2b3c4d-5e6")
+	if err != nil || got.EventID != event.ID || len(got.Seats) != 2 {
+		t.Fatalf("legacy reservation = %+v, %v", got, err)
+	}
+	if _, err := s.ConfirmReservation("1a2b3c4d-5e6", "pay-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CancelReservation("9f8e7d6c-5b4"); err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, s, event.ID, 2, 0, 2, 30)
+
+	if got, err := s.GetReservation("1a2b3c4d-5e6"); err != nil || got.Status != models.ReservationConfirmed {
+		t.Fatalf("confirmed legacy reservation = %+v, %v", got, err)
+	}
+}
+
+// The event's keys share a hash tag, so they move together and the service
+// keeps working after a reshard
+func TestReservationAfterSlotMove(t *testing.T) {
+	s, fc := newTestService(t)
+	event, err := s.CreateEvent("Match", "Stadium", time.Now().Add(time.Hour), 1, 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot := cluster.KeySlot(fmt.Sprintf(eventKeyPattern, event.ID))
+	from := fc.Owner(slot)
+	to := fc.Nodes()[(indexOf(fc, from)+1)%len(fc.Nodes())]
+	fc.MoveSlots(slot, slot, to)
+
+	if _, err := s.ReserveSeats(event.ID, "user-1", []string{"A1"}, "Ann", "ann@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, s, event.ID, 2, 1, 0, 0)
+}
+
+func indexOf(fc *clustertest.Cluster, n *clustertest.Node) int {
+	for i, node := range fc.Nodes() {
+		if node == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// Connection resets and lost packets may fail individual reservations, but
+// the Lua scripts keep seats and stats consistent
+func TestReservationUnderChaos(t *testing.T) {
+	fc := clustertest.New(t, 3)
+	chaos := cluster.NewChaos(&cluster.ChaosScenario{
+		Default: cluster.FaultSpec{
+			Latency:         cluster.Duration(time.Millisecond),
+			DropRate:        0.1,
+			RetransmitDelay: cluster.Duration(5 * time.Millisecond),
+			ResetRate:       0.05,
+		},
+	})
+	client, err := cluster.NewClient(fc.Config().WithChaos(chaos))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	s := NewReservationService(client.Redis(), time.Minute)
+
+	var event *models.Event
+	for i := 0; i < 10 && event == nil; i++ {
+		event, err = s.CreateEvent("Chaos", "Lab", time.Now().Add(time.Hour), 4, 5, 10)
+	}
+	if event == nil {
+		t.Fatal(err)
+	}
+	reserved := 0
+	for i := 1; i <= 20; i++ {
+		seat := fmt.Sprintf("%c%d", 'A'+(i-1)/5, (i-1)%5+1)
+		if _, err := s.ReserveSeats(event.ID, fmt.Sprintf("user-%d", i), []string{seat}, "U", "u@example.com"); err == nil {
+			reserved++
+		}
+	}
+
+	// Check the end state without faults
+	clean, err := cluster.NewClient(fc.Config())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clean.Close()
+	verify := NewReservationService(clean.Redis(), time.Minute)
+	seats, err := clean.Redis().HGetAll(clean.Context(), fmt.Sprintf(seatsKeyPattern, event.ID)).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := 0
+	for _, status := range seats {
+		if status == string(models.SeatPending) {
+			pending++
+		}
+	}
+	if pending < reserved {
+		t.Fatalf("%d reservations succeeded but only %d seats are pending", reserved, pending)
+	}
+	checkStats(t, verify, event.ID, 20-pending, pending, 0, 0)
+
+	var resets int64
+	for _, st := range chaos.Stats() {
+		resets += st.Resets
+	}
+	t.Logf("%d/20 reservations succeeded, %d seats pending, %d connection resets", reserved, pending, resets)
+}
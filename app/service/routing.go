@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ReadClass groups reads by how stale they are allowed to be
+type ReadClass string
+
+const (
+	ReadReservation ReadClass = "reservation" // holds, confirmations, user reservations
+	ReadEvent       ReadClass = "event"       // event metadata
+	ReadSeatMap     ReadClass = "seatmap"     // seat statuses
+	ReadAnalytics   ReadClass = "analytics"   // stats and counters
+)
+
+// Route is where a read class is sent
+type Route string
+
+const (
+	RouteMaster  Route = "master"  // always the slot's master
+	RouteReplica Route = "replica" // any node of the shard while replica lag is within MaxStaleness
+	RouteLatency Route = "latency" // the closest node of the shard, staleness not bounded
+)
+
+// RoutingPolicy maps read classes to routes and sets the consistency bounds
+type RoutingPolicy struct {
+	Routes map[ReadClass]Route
+
+	// MaxStaleness disables replica reads while any replica lags further
+	// behind. Redis reports lag in whole seconds, so values below 1s behave
+	// like 1s.
+	MaxStaleness time.Duration
+
+	// RecentWriteWindow sends reads of keys written by this process within
+	// the window to the master, so a read right after a write never hits a
+	// replica that has not caught up yet
+	RecentWriteWindow time.Duration
+
+	// SessionWindow is the read-your-writes window of a Session: its own
+	// writes are read from masters for this long
+	SessionWindow time.Duration
+}
+
+// DefaultRoutingPolicy keeps reservation state on masters, lets event data
+// and seat maps use replicas with at most 1s of lag, and routes analytics by
+// latency
+func DefaultRoutingPolicy() RoutingPolicy {
+	return RoutingPolicy{
+		Routes: map[ReadClass]Route{
+			ReadReservation: RouteMaster,
+			ReadEvent:       RouteReplica,
+			ReadSeatMap:     RouteReplica,
+			ReadAnalytics:   RouteLatency,
+		},
+		MaxStaleness:      time.Second,
+		RecentWriteWindow: 2 * time.Second,
+		SessionWindow:     10 * time.Second,
+	}
+}
+
+// writeTracker remembers when keys were last written
+type writeTracker struct {
+	mu      sync.Mutex
+	window  time.Duration
+	written map[string]time.Time
+}
+
+func newWriteTracker(window time.Duration) *writeTracker {
+	return &writeTracker{window: window, written: make(map[string]time.Time)}
+}
+
+// note records a write of keys
+func (t *writeTracker) note(keys ...string) {
+	if t == nil || t.window <= 0 {
+		return
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range keys {
+		t.written[k] = now
+	}
+	// Drop expired entries once the map grows so it stays bounded
+	if len(t.written) > 10000 {
+		for k, at := range t.written {
+			if now.Sub(at) > t.window {
+				delete(t.written, k)
+			}
+		}
+	}
+}
+
+// recent reports whether any of keys was written within the window
+func (t *writeTracker) recent(keys ...string) bool {
+	if t == nil || t.window <= 0 {
+		return false
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, k := range keys {
+		if at, ok := t.written[k]; ok && now.Sub(at) <= t.window {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadRouter picks the cluster client for a read based on its class, the
+// current replica lag and recent writes
+type ReadRouter struct {
+	master  *redis.ClusterClient
+	replica *redis.ClusterClient
+	latency *redis.ClusterClient
+	policy  RoutingPolicy
+	writes  *writeTracker
+
+	lagMu        sync.Mutex
+	maxLag       time.Duration
+	lagKnown     bool
+	lagCheckedAt time.Time
+	lagRefresh   bool
+}
+
+// NewReadRouter creates a router. replica and latency may be nil, in which
+// case those routes fall back to master.
+func NewReadRouter(master, replica, latency *redis.ClusterClient, policy RoutingPolicy) *ReadRouter {
+	if policy.Routes == nil {
+		policy.Routes = DefaultRoutingPolicy().Routes
+	}
+	return &ReadRouter{
+		master:  master,
+		replica: replica,
+		latency: latency,
+		policy:  policy,
+		writes:  newWriteTracker(policy.RecentWriteWindow),
+	}
+}
+
+// masterOnlyRouter is used when a service has no router configured
+func masterOnlyRouter(master *redis.ClusterClient) *ReadRouter {
+	return NewReadRouter(master, nil, nil, RoutingPolicy{})
+}
+
+// Policy returns the router's policy
+func (r *ReadRouter) Policy() RoutingPolicy {
+	return r.policy
+}
+
+// NoteWrite records that keys were just written (process-wide)
+func (r *ReadRouter) NoteWrite(keys ...string) {
+	r.writes.note(keys...)
+}
+
+// reader returns the client to use for a read of keys in class. session may
+// be nil; otherwise its own recent writes also force the master.
+func (r *ReadRouter) reader(class ReadClass, session *writeTracker, keys ...string) redis.Cmdable {
+	if r.writes.recent(keys...) || session.recent(keys...) {
+		return r.master
+	}
+
+	switch r.policy.Routes[class] {
+	case RouteReplica:
+		if r.replica != nil && r.replicasFresh() {
+			return r.replica
+		}
+	case RouteLatency:
+		if r.latency != nil {
+			return r.latency
+		}
+	}
+	return r.master
+}
+
+// replicasFresh reports whether the last known replica lag is within
+// MaxStaleness. The lag is refreshed in the background at most once per
+// second; until the first measurement arrives reads go to the master.
+func (r *ReadRouter) replicasFresh() bool {
+	r.lagMu.Lock()
+	defer r.lagMu.Unlock()
+
+	if !r.lagRefresh && time.Since(r.lagCheckedAt) > time.Second {
+		r.lagRefresh = true
+		go r.refreshLag()
+	}
+	if !r.lagKnown {
+		return false
+	}
+	staleness := r.policy.MaxStaleness
+	if staleness < time.Second {
+		staleness = time.Second
+	}
+	return r.maxLag < staleness
+}
+
+// refreshLag reads INFO replication from every master and records the
+// largest replica lag
+func (r *ReadRouter) refreshLag() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	var maxLag time.Duration
+	err := r.master.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		info, err := node.Info(ctx, "replication").Result()
+		if err != nil {
+			return err
+		}
+		lag := MaxReplicaLag(info)
+		mu.Lock()
+		if lag > maxLag {
+			maxLag = lag
+		}
+		mu.Unlock()
+		return nil
+	})
+
+	r.lagMu.Lock()
+	defer r.lagMu.Unlock()
+	r.lagRefresh = false
+	r.lagCheckedAt = time.Now()
+	// An unreachable master means lag is unknown: stay on masters
+	r.lagKnown = err == nil
+	r.maxLag = maxLag
+}
+
+// MaxReplicaLag parses the slaveN lines of INFO replication and returns the
+// largest lag (Redis reports it in whole seconds). A replica that is not
+// online counts as infinitely behind.
+func MaxReplicaLag(info string) time.Duration {
+	var max time.Duration
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "slave") || !strings.Contains(line, ":ip=") {
+			continue
+		}
+		fields := make(map[string]string)
+		for _, kv := range strings.Split(line[strings.Index(line, ":")+1:], ",") {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				fields[k] = v
+			}
+		}
+		if fields["state"] != "online" {
+			return time.Duration(1<<63 - 1)
+		}
+		secs, _ := strconv.Atoi(fields["lag"])
+		if lag := time.Duration(secs) * time.Second; lag > max {
+			max = lag
+		}
+	}
+	return max
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"ticket-reservation/models"
+)
+
+// SeatConflictReason says why a reservation no longer holds a seat
+type SeatConflictReason string
+
+const (
+	SeatReleased    SeatConflictReason = "released"      // the hold ran out or was cancelled
+	SeatHeldByOther SeatConflictReason = "held_by_other" // re-reserved by another reservation
+	SeatSoldToOther SeatConflictReason = "sold_to_other" // sold under another reservation
+	SeatAlreadySold SeatConflictReason = "already_sold"  // sold under this reservation
+)
+
+// seatOwnerSep separates the reservation ID from the user in a seat owner
+const seatOwnerSep = "|"
+
+// SeatConflict is a seat a script refused to touch because the reservation
+// does not hold it
+type SeatConflict struct {
+	SeatID string             `json:"seat_id"`
+	Reason SeatConflictReason `json:"reason"`
+}
+
+// SeatConflictError is returned when a reservation is confirmed for a seat
+// it no longer holds
+type SeatConflictError struct {
+	ReservationID string
+	SeatConflict
+}
+
+func (e *SeatConflictError) Error() string {
+	return fmt.Sprintf("reservation %s does not hold seat %s: %s", e.ReservationID, e.SeatID, e.Reason)
+}
+
+// seatConflicts reads the flat {seat, reason, ...} pairs the scripts return
+func seatConflicts(values []interface{}) []SeatConflict {
+	var conflicts []SeatConflict
+	for i := 0; i+1 < len(values); i += 2 {
+		seatID, _ := values[i].(string)
+		reason, _ := values[i+1].(string)
+		conflicts = append(conflicts, SeatConflict{SeatID: seatID, Reason: SeatConflictReason(reason)})
+	}
+	return conflicts
+}
+
+// parseSeatOwner splits a seat_owners value into the reservation ID and
+// the user holding the seat
+func parseSeatOwner(owner string) (reservationID, userID string) {
+	reservationID, userID, _ = strings.Cut(owner, seatOwnerSep)
+	return reservationID, userID
+}
+
+// GetSeats returns every seat of the event with its status and, for held
+// and sold seats, the reservation and user that own it
+func (s *ReservationService) GetSeats(eventID string) ([]models.Seat, error) {
+	event, err := s.GetEvent(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	seatsKey := fmt.Sprintf(seatsKeyPattern, eventID)
+	ownersKey := fmt.Sprintf(seatOwnersKeyPattern, eventID)
+	pipe := s.reader(ReadSeatMap, seatsKey, ownersKey).Pipeline()
+	statusCmd := pipe.HGetAll(s.ctx, seatsKey)
+	ownersCmd := pipe.HGetAll(s.ctx, ownersKey)
+	if _, err := pipe.Exec(s.ctx); err != nil {
+		return nil, fmt.Errorf("failed to get seats: %w", err)
+	}
+	statuses, owners := statusCmd.Val(), ownersCmd.Val()
+
+	seats := make([]models.Seat, 0, event.TotalSeats)
+	for row := 0; row < event.Rows; row++ {
+		rowLetter := string(rune('A' + row))
+		for seatNum := 1; seatNum <= event.SeatsPerRow; seatNum++ {
+			seat := models.Seat{
+				ID:      fmt.Sprintf("%s%d", rowLetter, seatNum),
+				EventID: eventID,
+				Row:     rowLetter,
+				Number:  seatNum,
+				Price:   event.PricePerSeat,
+			}
+			seat.Status = models.SeatStatus(statuses[seat.ID])
+			if owner, ok := owners[seat.ID]; ok {
+				reservationID, userID := parseSeatOwner(owner)
+				seat.ReservationID = reservationID
+				switch seat.Status {
+				case models.SeatPending:
+					seat.HeldBy = userID
+				case models.SeatSold:
+					seat.SoldTo = userID
+				}
+			}
+			seats = append(seats, seat)
+		}
+	}
+	return seats, nil
+}
@@ -1,7 +1,7 @@
 import http from 'k6/http';
 import { check, sleep, group } from 'k6';
 import { Rate, Trend, Counter } from 'k6/metrics';
-import { CONFIG, getRandomSeats, generateUserId } from './config.js';
+import { CONFIG, generateUserId } from './config.js';
 
 // Custom metrics
 const reservationSuccessRate = new Rate('reservation_success_rate');
@@ -86,38 +86,15 @@ export default function (data) {
             });
         });
 
-        // Step 2: Get available seats
-        let availableSeats = [];
-        group('Get Available Seats', function () {
-            const seatsRes = http.get(`${CONFIG.BASE_URL}/events/${data.eventId}/seats`);
-            check(seatsRes, {
-                'seats status is 200': (r) => r.status === 200,
-            });
-
-            if (seatsRes.status === 200) {
-                const body = JSON.parse(seatsRes.body);
-                availableSeats = body.available_seats || [];
-            }
-        });
-
-        // Step 3: Reserve seats
+        // Step 2: Reserve the best available seats (the server picks
+        // adjacent seats atomically, so concurrent users don't collide)
         let reservationId = null;
         group('Reserve Seats', function () {
-            // Pick random seats from available
-            let seatsToBook;
-            if (availableSeats.length >= seatsToReserve) {
-                // Shuffle and pick
-                const shuffled = availableSeats.sort(() => 0.5 - Math.random());
-                seatsToBook = shuffled.slice(0, seatsToReserve);
-            } else {
-                // Fall back to random generation
-                seatsToBook = getRandomSeats(data.rows, data.seatsPerRow, seatsToReserve);
-            }
-
             const reservePayload = JSON.stringify({
                 event_id: data.eventId,
                 user_id: userId,
-                seats: seatsToBook,
+                count: seatsToReserve,
+                preferences: { centered: true },
                 customer_name: `Test User ${userId}`,
                 customer_email: `${userId}@test.com`,
             });
@@ -145,11 +122,11 @@ export default function (data) {
             if (success) {
                 const body = JSON.parse(reserveRes.body);
                 reservationId = body.id;
-                seatsReserved.add(seatsToBook.length);
+                seatsReserved.add(body.seats.length);
             }
         });
 
-        // Step 4: Confirm reservation (if created)
+        // Step 3: Confirm reservation (if created)
         if (reservationId) {
             sleep(0.5); // Small delay to simulate payment processing
 